@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/karimra/gnoic/config"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/credentials"
+)
+
+// perRPCCredentials attaches the target credentials to each RPC:
+// username/password, a static or OAuth2 bearer token and extra metadata.
+type perRPCCredentials struct {
+	username    string
+	password    string
+	token       string
+	tokenSource oauth2.TokenSource
+	metadata    map[string]string
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c *perRPCCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	md := make(map[string]string, len(c.metadata)+3)
+	for k, v := range c.metadata {
+		md[k] = v
+	}
+	if c.username != "" || c.password != "" {
+		md["username"] = c.username
+		md["password"] = c.password
+	}
+	switch {
+	case c.tokenSource != nil:
+		tk, err := c.tokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get oauth2 token: %v", err)
+		}
+		md["authorization"] = tk.Type() + " " + tk.AccessToken
+	case c.token != "":
+		md["authorization"] = "Bearer " + c.token
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+// Credentials are sent on insecure connections as well, as the
+// username and password always were.
+func (c *perRPCCredentials) RequireTransportSecurity() bool { return false }
+
+// perRPCCredentials builds the target per RPC credentials,
+// it returns nil if the target has no credentials.
+func (t *Target) perRPCCredentials() credentials.PerRPCCredentials {
+	c := &perRPCCredentials{metadata: t.Config.Metadata}
+	if t.Config.Username != nil {
+		c.username = *t.Config.Username
+	}
+	if t.Config.Password != nil {
+		c.password = *t.Config.Password
+	}
+	if t.Config.Token != nil {
+		c.token = *t.Config.Token
+	}
+	if t.Config.OAuth2 != nil {
+		c.tokenSource = oauth2TokenSource(t.Config.OAuth2)
+	}
+	if c.username == "" && c.password == "" && c.token == "" &&
+		c.tokenSource == nil && len(c.metadata) == 0 {
+		return nil
+	}
+	return c
+}
+
+var (
+	tokenSourcesMu sync.Mutex
+	// token sources shared by the targets using the same client credentials
+	tokenSources = map[string]oauth2.TokenSource{}
+)
+
+// oauth2TokenSource returns a token source fetching tokens using the
+// client credentials flow. Tokens are cached and refreshed before they expire.
+func oauth2TokenSource(oc *config.OAuth2Config) oauth2.TokenSource {
+	scopes := append([]string(nil), oc.Scopes...)
+	sort.Strings(scopes)
+	key := strings.Join([]string{oc.TokenURL, oc.ClientID, oc.ClientSecret, strings.Join(scopes, " ")}, "\x00")
+
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+	if ts, ok := tokenSources[key]; ok {
+		return ts
+	}
+	cc := &clientcredentials.Config{
+		ClientID:     oc.ClientID,
+		ClientSecret: oc.ClientSecret,
+		TokenURL:     oc.TokenURL,
+		Scopes:       oc.Scopes,
+	}
+	ts := cc.TokenSource(context.Background())
+	tokenSources[key] = ts
+	return ts
+}
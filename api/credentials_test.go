@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/karimra/gnoic/config"
+)
+
+func TestPerRPCCredentials(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "gnoic" || secret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"tk1","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		tc      *config.TargetConfig
+		want    map[string]string
+		wantNil bool
+		wantErr bool
+	}{
+		{
+			name:    "no_credentials",
+			tc:      &config.TargetConfig{Username: pointer.ToString(""), Password: pointer.ToString("")},
+			wantNil: true,
+		},
+		{
+			name: "username_password",
+			tc:   &config.TargetConfig{Username: pointer.ToString("admin"), Password: pointer.ToString("admin")},
+			want: map[string]string{"username": "admin", "password": "admin"},
+		},
+		{
+			name: "token_and_metadata",
+			tc: &config.TargetConfig{
+				Token:    pointer.ToString("abc"),
+				Metadata: map[string]string{"x-tenant": "blue"},
+			},
+			want: map[string]string{"authorization": "Bearer abc", "x-tenant": "blue"},
+		},
+		{
+			name: "oauth2",
+			tc: &config.TargetConfig{
+				OAuth2: &config.OAuth2Config{TokenURL: ts.URL, ClientID: "gnoic", ClientSecret: "s3cr3t"},
+			},
+			want: map[string]string{"authorization": "Bearer tk1"},
+		},
+		{
+			name: "oauth2_bad_secret",
+			tc: &config.TargetConfig{
+				OAuth2: &config.OAuth2Config{TokenURL: ts.URL, ClientID: "gnoic", ClientSecret: "wrong"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds := NewTargetFromConfig(tt.tc).perRPCCredentials()
+			if tt.wantNil {
+				if creds != nil {
+					t.Fatalf("expected no credentials, got %+v", creds)
+				}
+				return
+			}
+			md, err := creds.GetRequestMetadata(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRequestMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(md) != len(tt.want) {
+				t.Fatalf("GetRequestMetadata() = %v, want %v", md, tt.want)
+			}
+			for k, v := range tt.want {
+				if md[k] != v {
+					t.Errorf("GetRequestMetadata()[%q] = %q, want %q", k, md[k], v)
+				}
+			}
+		})
+	}
+	// the oauth2 token is cached and shared by the targets using the same credentials
+	before := atomic.LoadInt32(&calls)
+	for i := 0; i < 3; i++ {
+		creds := NewTargetFromConfig(&config.TargetConfig{
+			OAuth2: &config.OAuth2Config{TokenURL: ts.URL, ClientID: "gnoic", ClientSecret: "s3cr3t"},
+		}).perRPCCredentials()
+		if _, err := creds.GetRequestMetadata(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != before {
+		t.Errorf("expected the cached token to be reused, got %d token requests", n-before)
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	defaultPoolIdleTimeout         = 5 * time.Minute
+	defaultPoolHealthCheckInterval = 30 * time.Second
+)
+
+// Pool shares gRPC connections between the Targets using the same configuration,
+// so that successive RPCs, commands or workflow steps towards a target reuse
+// a single connection.
+// A Target uses the pool once SetPool is called, its Close method then
+// releases the connection to the pool instead of closing it.
+type Pool struct {
+	m     sync.Mutex
+	conns map[string]*poolConn
+	opts  []grpc.DialOption
+
+	idleTimeout         time.Duration
+	healthCheckInterval time.Duration
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type poolConn struct {
+	conn     *grpc.ClientConn
+	refs     int
+	lastUsed time.Time
+	// the target whose dialer is used by the connection
+	owner *Target
+}
+
+type PoolOption func(*Pool)
+
+// NewPool creates a connection pool, it runs a health check loop
+// until Close is called.
+func NewPool(opts ...PoolOption) *Pool {
+	p := &Pool{
+		conns:               make(map[string]*poolConn),
+		opts:                make([]grpc.DialOption, 0),
+		idleTimeout:         defaultPoolIdleTimeout,
+		healthCheckInterval: defaultPoolHealthCheckInterval,
+		done:                make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	go p.healthCheck()
+	return p
+}
+
+// WithKeepalive sets the gRPC keepalive parameters of the pool connections.
+// A zero interval disables keepalive pings.
+func WithKeepalive(interval, timeout time.Duration) PoolOption {
+	return func(p *Pool) {
+		if interval <= 0 {
+			return
+		}
+		p.opts = append(p.opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                interval,
+			Timeout:             timeout,
+			PermitWithoutStream: true,
+		}))
+	}
+}
+
+// WithMaxMsgSize sets the maximum size of the messages sent and received on the pool connections.
+func WithMaxMsgSize(size int) PoolOption {
+	return func(p *Pool) {
+		if size <= 0 {
+			return
+		}
+		p.opts = append(p.opts, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(size),
+			grpc.MaxCallSendMsgSize(size),
+		))
+	}
+}
+
+// WithIdleTimeout sets the time after which an unused connection is closed.
+func WithIdleTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		if d > 0 {
+			p.idleTimeout = d
+		}
+	}
+}
+
+// WithHealthCheckInterval sets the interval between two health checks of the pool connections.
+func WithHealthCheckInterval(d time.Duration) PoolOption {
+	return func(p *Pool) {
+		if d > 0 {
+			p.healthCheckInterval = d
+		}
+	}
+}
+
+// SetPool makes the target get its gRPC connection from the pool p.
+func (t *Target) SetPool(p *Pool) {
+	t.pool = p
+}
+
+// poolKey identifies the targets that can share a connection.
+func (t *Target) poolKey() string {
+	h := sha256.Sum256([]byte(t.Config.String()))
+	return t.Config.Name + "/" + hex.EncodeToString(h[:8])
+}
+
+// acquire returns the pooled connection of t, dialing a new one
+// if there is none or if the existing one is not healthy.
+func (p *Pool) acquire(t *Target, dial func(opts ...grpc.DialOption) (*grpc.ClientConn, error)) (*grpc.ClientConn, error) {
+	key := t.poolKey()
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pc, ok := p.conns[key]; ok {
+		if pc.refs > 0 || healthy(pc.conn) {
+			pc.refs++
+			pc.lastUsed = time.Now()
+			pc.conn.Connect()
+			return pc.conn, nil
+		}
+		pc.close()
+		delete(p.conns, key)
+	}
+	conn, err := dial(p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[key] = &poolConn{conn: conn, refs: 1, lastUsed: time.Now(), owner: t}
+	return conn, nil
+}
+
+func (p *Pool) release(t *Target) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pc, ok := p.conns[t.poolKey()]; ok && pc.refs > 0 {
+		pc.refs--
+		pc.lastUsed = time.Now()
+	}
+}
+
+// Len returns the number of pooled connections.
+func (p *Pool) Len() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return len(p.conns)
+}
+
+// Close stops the health check loop and closes all the pooled connections.
+func (p *Pool) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	p.m.Lock()
+	defer p.m.Unlock()
+	for k, pc := range p.conns {
+		pc.close()
+		delete(p.conns, k)
+	}
+	return nil
+}
+
+// healthCheck periodically closes the unused connections
+// that are idle for too long or that are not healthy.
+func (p *Pool) healthCheck() {
+	ticker := time.NewTicker(p.healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.m.Lock()
+			for k, pc := range p.conns {
+				if pc.refs > 0 {
+					continue
+				}
+				if time.Since(pc.lastUsed) > p.idleTimeout || !healthy(pc.conn) {
+					pc.close()
+					delete(p.conns, k)
+				}
+			}
+			p.m.Unlock()
+		}
+	}
+}
+
+func healthy(conn *grpc.ClientConn) bool {
+	switch conn.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	}
+	return true
+}
+
+func (pc *poolConn) close() {
+	pc.conn.Close()
+	pc.owner.tunnel.close()
+}
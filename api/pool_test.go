@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/karimra/gnoic/config"
+)
+
+func TestPoolReuse(t *testing.T) {
+	p := NewPool(WithKeepalive(10*time.Second, time.Second), WithMaxMsgSize(1024))
+	defer p.Close()
+	newTarget := func(username string) *Target {
+		tg := NewTargetFromConfig(&config.TargetConfig{
+			Name:     "router1",
+			Address:  "127.0.0.1:57400",
+			Timeout:  time.Second,
+			Insecure: pointer.ToBool(true),
+			Username: pointer.ToString(username),
+		})
+		tg.SetPool(p)
+		return tg
+	}
+	ctx := context.Background()
+	t1, t2 := newTarget("admin"), newTarget("admin")
+	if err := t1.CreateGrpcClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := t2.CreateGrpcClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if t1.client != t2.client {
+		t.Error("expected targets with the same config to share a connection")
+	}
+	if p.Len() != 1 {
+		t.Errorf("expected 1 pooled connection, got %d", p.Len())
+	}
+	// a different configuration gets its own connection
+	t3 := newTarget("operator")
+	if err := t3.CreateGrpcClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if p.Len() != 2 {
+		t.Errorf("expected 2 pooled connections, got %d", p.Len())
+	}
+	conn := t1.client
+	for _, tg := range []*Target{t1, t2, t3} {
+		tg.Close()
+	}
+	// released connections stay in the pool for the next command
+	t4 := newTarget("admin")
+	if err := t4.CreateGrpcClient(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer t4.Close()
+	if t4.client != conn {
+		t.Error("expected the released connection to be reused")
+	}
+	p.Close()
+	if p.Len() != 0 {
+		t.Errorf("expected an empty pool after Close, got %d connections", p.Len())
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/karimra/gnoic/config"
+)
+
+func TestHTTPConnectProxy(t *testing.T) {
+	// echo server standing in for the target
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			c, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+	// HTTP CONNECT proxy requiring basic authentication
+	proxyL, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxyL.Close()
+	go func() {
+		for {
+			c, err := proxyL.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				req, err := http.ReadRequest(bufio.NewReader(c))
+				if err != nil {
+					return
+				}
+				if req.Method != http.MethodConnect || req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+					io.WriteString(c, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				dst, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer dst.Close()
+				io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(dst, c)
+				io.Copy(c, dst)
+			}()
+		}
+	}()
+
+	for _, tt := range []struct {
+		name    string
+		proxy   string
+		wantErr bool
+	}{
+		{name: "authenticated", proxy: "http://user:pass@" + proxyL.Addr().String()},
+		{name: "wrong_credentials", proxy: "http://user:wrong@" + proxyL.Addr().String(), wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := NewTargetFromConfig(&config.TargetConfig{
+				Address: target.Addr().String(),
+				Timeout: 5 * time.Second,
+				Proxy:   pointer.ToString(tt.proxy),
+			})
+			conn, err := tg.createCustomDialer(tg.Config.Address)(context.Background(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dial error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte("ping"))
+			b := make([]byte, 4)
+			if _, err = io.ReadFull(conn, b); err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "ping" {
+				t.Errorf("got %q through the proxy, want %q", b, "ping")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/openconfig/gnoi/cert"
+	"github.com/openconfig/gnoi/factory_reset"
+	"github.com/openconfig/gnoi/file"
+	"github.com/openconfig/gnoi/healthz"
+	gnoios "github.com/openconfig/gnoi/os"
+	"github.com/openconfig/gnoi/system"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// nonIdempotentMethods are the RPCs that change the target state,
+// they are retried only if the retry policy allows it.
+var nonIdempotentMethods = map[string]struct{}{
+	cert.CertificateManagement_Rotate_FullMethodName:                         {},
+	cert.CertificateManagement_Install_FullMethodName:                        {},
+	cert.CertificateManagement_LoadCertificate_FullMethodName:                {},
+	cert.CertificateManagement_LoadCertificateAuthorityBundle_FullMethodName: {},
+	cert.CertificateManagement_RevokeCertificates_FullMethodName:             {},
+	factory_reset.FactoryReset_Start_FullMethodName:                          {},
+	file.File_Put_FullMethodName:                                             {},
+	file.File_Remove_FullMethodName:                                          {},
+	file.File_TransferToRemote_FullMethodName:                                {},
+	healthz.Healthz_Acknowledge_FullMethodName:                               {},
+	gnoios.OS_Install_FullMethodName:                                         {},
+	gnoios.OS_Activate_FullMethodName:                                        {},
+	system.System_Reboot_FullMethodName:                                      {},
+	system.System_CancelReboot_FullMethodName:                                {},
+	system.System_SetPackage_FullMethodName:                                  {},
+	system.System_KillProcess_FullMethodName:                                 {},
+	system.System_SwitchControlProcessor_FullMethodName:                      {},
+}
+
+type retryCtxKey struct{}
+
+// Retry runs fn until it succeeds, the target retry policy attempts are exhausted
+// or fn returns an error that is not retryable for the RPC method.
+// Each attempt runs with its own context so that a failed stream is torn down
+// before fn starts it again from scratch.
+// RPCs issued by fn are not retried individually.
+func (t *Target) Retry(ctx context.Context, method string, fn func(context.Context) error) error {
+	ctx = context.WithValue(ctx, retryCtxKey{}, true)
+	return t.retry(ctx, method, func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+func (t *Target) retry(ctx context.Context, method string, fn func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !t.retryable(method, err, attempt) {
+			return err
+		}
+		timer := time.NewTimer(t.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *Target) retryable(method string, err error, attempt int) bool {
+	rp := t.Config.Retry
+	if rp == nil || attempt >= rp.MaxAttempts {
+		return false
+	}
+	if _, ok := nonIdempotentMethods[method]; ok && (rp.NonIdempotent == nil || !*rp.NonIdempotent) {
+		return false
+	}
+	rc, err2 := rp.RetryableCodes()
+	if err2 != nil {
+		return false
+	}
+	_, ok := rc[status.Code(err)]
+	return ok
+}
+
+func (t *Target) backoff(attempt int) time.Duration {
+	d := t.Config.Retry.BackoffFor(attempt)
+	j := pointer.GetFloat64(t.Config.Retry.Jitter)
+	if j <= 0 {
+		return d
+	}
+	if j > 1 {
+		j = 1
+	}
+	// random value in [d*(1-j), d*(1+j)]
+	return time.Duration(float64(d) * (1 - j + 2*j*rand.Float64()))
+}
+
+// retryUnaryInterceptor retries unary RPCs according to the target retry policy.
+func (t *Target) retryUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if ctx.Value(retryCtxKey{}) != nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		return t.retry(ctx, method, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// retryStreamInterceptor retries the creation of streaming RPCs according to the target retry policy.
+// Restarting a stream that failed after it was established is done by wrapping
+// the whole exchange with Target.Retry.
+func (t *Target) retryStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if ctx.Value(retryCtxKey{}) != nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		var cs grpc.ClientStream
+		err := t.retry(ctx, method, func(ctx context.Context) error {
+			var err error
+			cs, err = streamer(ctx, desc, cc, method, opts...)
+			return err
+		})
+		return cs, err
+	}
+}
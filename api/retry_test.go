@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/karimra/gnoic/config"
+	"github.com/openconfig/gnoi/system"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newRetryTarget(rp *config.RetryPolicy) *Target {
+	return NewTargetFromConfig(&config.TargetConfig{Name: "router1", Retry: rp})
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{name: "no_jitter", jitter: 0, min: 400 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "quarter_jitter", jitter: 0.25, min: 300 * time.Millisecond, max: 500 * time.Millisecond},
+		{name: "jitter_capped_to_1", jitter: 3, min: 0, max: 800 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newRetryTarget(&config.RetryPolicy{
+				Backoff:    pointer.ToDuration(100 * time.Millisecond),
+				MaxBackoff: time.Second,
+				Jitter:     pointer.ToFloat64(tt.jitter),
+			})
+			for i := 0; i < 1000; i++ {
+				d := tg.backoff(3)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("backoff(3) = %v, want in [%v, %v]", d, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryUnaryInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	tests := []struct {
+		name         string
+		method       string
+		policy       *config.RetryPolicy
+		errs         []error
+		wantAttempts int
+		wantCode     codes.Code
+	}{
+		{
+			name:         "succeeds_after_retries",
+			method:       system.System_Time_FullMethodName,
+			policy:       &config.RetryPolicy{MaxAttempts: 3, Codes: []string{"Unavailable"}},
+			errs:         []error{unavailable, unavailable, nil},
+			wantAttempts: 3,
+			wantCode:     codes.OK,
+		},
+		{
+			name:         "max_attempts_cap",
+			method:       system.System_Time_FullMethodName,
+			policy:       &config.RetryPolicy{MaxAttempts: 2, Codes: []string{"Unavailable"}},
+			errs:         []error{unavailable, unavailable, nil},
+			wantAttempts: 2,
+			wantCode:     codes.Unavailable,
+		},
+		{
+			name:         "code_not_retryable",
+			method:       system.System_Time_FullMethodName,
+			policy:       &config.RetryPolicy{MaxAttempts: 3, Codes: []string{"Unavailable"}},
+			errs:         []error{status.Error(codes.PermissionDenied, "denied"), nil},
+			wantAttempts: 1,
+			wantCode:     codes.PermissionDenied,
+		},
+		{
+			name:         "non_idempotent_not_retried",
+			method:       system.System_Reboot_FullMethodName,
+			policy:       &config.RetryPolicy{MaxAttempts: 3, Codes: []string{"Unavailable"}},
+			errs:         []error{unavailable, nil},
+			wantAttempts: 1,
+			wantCode:     codes.Unavailable,
+		},
+		{
+			name:         "non_idempotent_opted_in",
+			method:       system.System_Reboot_FullMethodName,
+			policy:       &config.RetryPolicy{MaxAttempts: 3, Codes: []string{"Unavailable"}, NonIdempotent: pointer.ToBool(true)},
+			errs:         []error{unavailable, nil},
+			wantAttempts: 2,
+			wantCode:     codes.OK,
+		},
+		{
+			name:         "no_policy",
+			method:       system.System_Time_FullMethodName,
+			errs:         []error{unavailable, nil},
+			wantAttempts: 1,
+			wantCode:     codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.policy != nil {
+				tt.policy.Backoff = pointer.ToDuration(time.Millisecond)
+			}
+			tg := newRetryTarget(tt.policy)
+			attempts := 0
+			invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			}
+			err := tg.retryUnaryInterceptor()(context.Background(), tt.method, nil, nil, nil, invoker)
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("got err %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+// fakeClientStream is an established stream failing to receive.
+type fakeClientStream struct {
+	grpc.ClientStream
+	recvErr error
+}
+
+func (s *fakeClientStream) RecvMsg(any) error { return s.recvErr }
+
+func TestRetryStreamInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	tests := []struct {
+		name         string
+		streamErrs   []error
+		wantAttempts int
+		wantErr      bool
+		wantRecvCode codes.Code
+	}{
+		{
+			name:         "retried_until_established",
+			streamErrs:   []error{unavailable, unavailable, nil},
+			wantAttempts: 3,
+			wantRecvCode: codes.Unavailable,
+		},
+		{
+			name:         "not_retried_once_established",
+			streamErrs:   []error{nil, nil},
+			wantAttempts: 1,
+			wantRecvCode: codes.Unavailable,
+		},
+		{
+			name:         "gives_up_after_max_attempts",
+			streamErrs:   []error{unavailable, unavailable, unavailable, nil},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newRetryTarget(&config.RetryPolicy{
+				MaxAttempts: 3,
+				Backoff:     pointer.ToDuration(time.Millisecond),
+				Codes:       []string{"Unavailable"},
+			})
+			attempts := 0
+			streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+				err := tt.streamErrs[attempts]
+				attempts++
+				if err != nil {
+					return nil, err
+				}
+				return &fakeClientStream{recvErr: unavailable}, nil
+			}
+			cs, err := tg.retryStreamInterceptor()(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, system.System_Traceroute_FullMethodName, streamer)
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d stream attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// a failure after the stream is established is returned as is
+			if err := cs.RecvMsg(nil); status.Code(err) != tt.wantRecvCode {
+				t.Errorf("RecvMsg() err = %v, want code %s", err, tt.wantRecvCode)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d stream attempts after RecvMsg, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnContextDone(t *testing.T) {
+	tg := newRetryTarget(&config.RetryPolicy{MaxAttempts: 5, Backoff: pointer.ToDuration(time.Hour), Codes: []string{"Unavailable"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	attempts := 0
+	err := tg.Retry(ctx, system.System_Time_FullMethodName, func(context.Context) error {
+		attempts++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if attempts != 1 || status.Code(err) != codes.Unavailable {
+		t.Errorf("got %d attempts, err %v, want 1 attempt and the last error", attempts, err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the RPC error, not the context error")
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const defaultSSHPort = "22"
+
+// default private keys tried when no key file is configured
+var defaultSSHKeyFiles = []string{
+	"~/.ssh/id_ed25519",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_rsa",
+}
+
+// sshTunnel holds the SSH clients of a chain of jump hosts,
+// the last one is used to reach the target.
+type sshTunnel struct {
+	m       sync.Mutex
+	clients []*ssh.Client
+}
+
+func (st *sshTunnel) close() error {
+	st.m.Lock()
+	defer st.m.Unlock()
+	var err error
+	// close the innermost hop first
+	for i := len(st.clients) - 1; i >= 0; i-- {
+		if cerr := st.clients[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	st.clients = nil
+	return err
+}
+
+// sshDial dials addr through the target SSH jump hosts.
+// The SSH connections are established on first use and reused
+// by the following dials, until the target is closed or a hop fails.
+func (t *Target) sshDial(ctx context.Context, addr string) (net.Conn, error) {
+	t.tunnel.m.Lock()
+	if len(t.tunnel.clients) == 0 {
+		clients, err := t.sshConnect(ctx)
+		if err != nil {
+			t.tunnel.m.Unlock()
+			return nil, err
+		}
+		t.tunnel.clients = clients
+	}
+	last := t.tunnel.clients[len(t.tunnel.clients)-1]
+	t.tunnel.m.Unlock()
+
+	conn, err := last.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		// the tunnel is torn down so that the next dial rebuilds it
+		t.tunnel.close()
+		return nil, fmt.Errorf("failed to dial %q through ssh proxy: %v", addr, err)
+	}
+	return conn, nil
+}
+
+// sshConnect connects to each jump host in order, through the previous one.
+func (t *Target) sshConnect(ctx context.Context) ([]*ssh.Client, error) {
+	auth, agentConn, err := t.sshAuthMethods()
+	if err != nil {
+		return nil, err
+	}
+	if agentConn != nil {
+		// the agent is only needed during the hops handshakes
+		defer agentConn.Close()
+	}
+	hostKeyCallback, err := t.sshHostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	clients := make([]*ssh.Client, 0, len(t.Config.SSHProxy))
+	closeAll := func() {
+		for i := len(clients) - 1; i >= 0; i-- {
+			clients[i].Close()
+		}
+	}
+	for _, hop := range t.Config.SSHProxy {
+		username, hostport, err := parseSSHHop(hop)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		var conn net.Conn
+		if len(clients) == 0 {
+			// the first jump host can be behind the target proxy
+			conn, err = t.dialTCP(ctx, hostport)
+		} else {
+			conn, err = clients[len(clients)-1].DialContext(ctx, "tcp", hostport)
+		}
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to connect to ssh proxy %q: %v", hostport, err)
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		cc, chans, reqs, err := ssh.NewClientConn(conn, hostport, &ssh.ClientConfig{
+			User:            username,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         t.Config.Timeout,
+		})
+		if err != nil {
+			conn.Close()
+			closeAll()
+			return nil, fmt.Errorf("ssh proxy %q: %v", hostport, err)
+		}
+		conn.SetDeadline(time.Time{})
+		clients = append(clients, ssh.NewClient(cc, chans, reqs))
+	}
+	return clients, nil
+}
+
+// parseSSHHop parses a jump host in the [user@]host[:port] format.
+// The user defaults to the current user and the port to 22.
+func parseSSHHop(hop string) (string, string, error) {
+	hop = strings.TrimSpace(hop)
+	if strings.HasPrefix(hop, "ssh://") {
+		hop = strings.TrimPrefix(hop, "ssh://")
+	}
+	username, hostport, ok := strings.Cut(hop, "@")
+	if !ok {
+		hostport = username
+		u, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("ssh proxy %q: missing user: %v", hop, err)
+		}
+		username = u.Username
+	}
+	if hostport == "" {
+		return "", "", fmt.Errorf("ssh proxy %q: missing host", hop)
+	}
+	if _, _, err := net.SplitHostPort(hostport); err != nil {
+		hostport = net.JoinHostPort(strings.Trim(hostport, "[]"), defaultSSHPort)
+	}
+	return username, hostport, nil
+}
+
+// sshAuthMethods returns the SSH agent, if available, and the private key authentication methods.
+// The returned agent connection, if not nil, must be closed by the caller once the handshakes are done.
+func (t *Target) sshAuthMethods() ([]ssh.AuthMethod, net.Conn, error) {
+	keyFiles := defaultSSHKeyFiles
+	explicit := t.Config.SSHKeyFile != nil && *t.Config.SSHKeyFile != ""
+	if explicit {
+		keyFiles = []string{*t.Config.SSHKeyFile}
+	}
+	signers := make([]ssh.Signer, 0, len(keyFiles))
+	for _, kf := range keyFiles {
+		path, err := homedir.Expand(kf)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			if !explicit && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to read ssh key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			if !explicit {
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to parse ssh key %q: %v", path, err)
+		}
+		signers = append(signers, signer)
+	}
+	methods := make([]ssh.AuthMethod, 0, 2)
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err == nil {
+			agentConn = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if len(methods) == 0 {
+		return nil, nil, errors.New("no ssh authentication method available, set an ssh key file or run an ssh agent")
+	}
+	return methods, agentConn, nil
+}
+
+// sshHostKeyCallback verifies the jump hosts keys against the known_hosts file.
+func (t *Target) sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	path := filepath.Join("~", ".ssh", "known_hosts")
+	if t.Config.SSHKnownHosts != nil && *t.Config.SSHKnownHosts != "" {
+		path = *t.Config.SSHKnownHosts
+	}
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	cb, err := knownhosts.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh known hosts: %v", err)
+	}
+	return cb, nil
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnoic/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSSHJumpHost starts an SSH server accepting the client key
+// and forwarding direct-tcpip channels.
+func startSSHJumpHost(t *testing.T, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, nil
+		},
+	}
+	cfg.AddHostKey(hostKey)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(c, cfg)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for nc := range chans {
+					if nc.ChannelType() != "direct-tcpip" {
+						nc.Reject(ssh.UnknownChannelType, "unsupported")
+						continue
+					}
+					// host string, port uint32, origin string, origin port uint32
+					data := nc.ExtraData()
+					hl := binary.BigEndian.Uint32(data)
+					host := string(data[4 : 4+hl])
+					port := binary.BigEndian.Uint32(data[4+hl:])
+					dst, err := net.Dial("tcp", net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10)))
+					if err != nil {
+						nc.Reject(ssh.ConnectionFailed, err.Error())
+						continue
+					}
+					ch, creqs, err := nc.Accept()
+					if err != nil {
+						dst.Close()
+						continue
+					}
+					go ssh.DiscardRequests(creqs)
+					go func() {
+						defer ch.Close()
+						defer dst.Close()
+						go io.Copy(dst, ch)
+						io.Copy(ch, dst)
+					}()
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), hostKey.PublicKey()
+}
+
+func TestSSHProxyChain(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err = os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	jump1, hk1 := startSSHJumpHost(t, sshPub)
+	jump2, hk2 := startSSHJumpHost(t, sshPub)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	kh := knownhosts.Line([]string{jump1}, hk1) + "\n" + knownhosts.Line([]string{jump2}, hk2) + "\n"
+	if err = os.WriteFile(knownHosts, []byte(kh), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// echo server standing in for the target
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+
+	tg := NewTargetFromConfig(&config.TargetConfig{
+		Address:       l.Addr().String(),
+		Timeout:       5 * time.Second,
+		SSHProxy:      []string{"admin@" + jump1, "admin@" + jump2},
+		SSHKeyFile:    &keyFile,
+		SSHKnownHosts: &knownHosts,
+	})
+	defer tg.Close()
+	t.Setenv("SSH_AUTH_SOCK", "")
+	conn, err := tg.createCustomDialer(tg.Config.Address)(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4)
+	if _, err = io.ReadFull(conn, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ping" {
+		t.Errorf("got %q through the ssh tunnel, want %q", b, "ping")
+	}
+	if len(tg.tunnel.clients) != 2 {
+		t.Errorf("expected 2 ssh hops, got %d", len(tg.tunnel.clients))
+	}
+
+	// unknown host key
+	unknown := filepath.Join(dir, "empty_known_hosts")
+	if err = os.WriteFile(unknown, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tg2 := NewTargetFromConfig(&config.TargetConfig{
+		Address:       l.Addr().String(),
+		Timeout:       5 * time.Second,
+		SSHProxy:      []string{"admin@" + jump1},
+		SSHKeyFile:    &keyFile,
+		SSHKnownHosts: &unknown,
+	})
+	defer tg2.Close()
+	if _, err = tg2.createCustomDialer(tg2.Config.Address)(context.Background(), ""); err == nil {
+		t.Error("expected an error with an unknown jump host key")
+	}
+}
+
+func TestSSHProxyAgent(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err = keyring.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	al, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer al.Close()
+	// signaled when the client closes its agent connection
+	agentClosed := make(chan struct{}, 1)
+	go func() {
+		for {
+			c, err := al.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, c)
+				c.Close()
+				agentClosed <- struct{}{}
+			}()
+		}
+	}()
+	jump, hk := startSSHJumpHost(t, sshPub)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err = os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{jump}, hk)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	tg := NewTargetFromConfig(&config.TargetConfig{
+		Address:       l.Addr().String(),
+		Timeout:       5 * time.Second,
+		SSHProxy:      []string{"admin@" + jump},
+		SSHKnownHosts: &knownHosts,
+	})
+	defer tg.Close()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+	conn, err := tg.createCustomDialer(tg.Config.Address)(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	select {
+	case <-agentClosed:
+	case <-time.After(5 * time.Second):
+		t.Error("the ssh agent connection was not closed after the handshake")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"strings"
 	"time"
@@ -24,13 +25,28 @@ type TargetOption func(*Target) error
 type Target struct {
 	Config *config.TargetConfig
 	client *grpc.ClientConn
+	// SSH jump hosts connections
+	tunnel sshTunnel
+	// connection pool, if set
+	pool   *Pool
+	pooled bool
 }
 
+// Close closes the target gRPC connection,
+// or releases it if it was acquired from a Pool.
 func (t *Target) Close() error {
-	if t.client == nil {
+	if t.pooled {
+		t.pool.release(t)
+		t.pooled = false
+		t.client = nil
 		return nil
 	}
-	return t.client.Close()
+	if t.client == nil {
+		return t.tunnel.close()
+	}
+	err := t.client.Close()
+	t.tunnel.close()
+	return err
 }
 
 func NewTarget(opts ...TargetOption) (*Target, error) {
@@ -71,6 +87,10 @@ func NewTargetFromConfig(tc *config.TargetConfig) *Target {
 }
 
 func (t *Target) CreateGrpcClient(ctx context.Context, opts ...grpc.DialOption) error {
+	if t.pooled {
+		// already holding a pooled connection
+		return nil
+	}
 	tOpts := make([]grpc.DialOption, 0, len(opts)+1)
 	tOpts = append(tOpts, opts...)
 
@@ -79,8 +99,26 @@ func (t *Target) CreateGrpcClient(ctx context.Context, opts ...grpc.DialOption)
 		return err
 	}
 	tOpts = append(tOpts, nOpts...)
-	tOpts = append(tOpts, grpc.WithContextDialer(t.createDialer(t.Config.Address)))
-	t.client, err = grpc.NewClient(t.Config.Address, tOpts...)
+	tOpts = append(tOpts,
+		grpc.WithContextDialer(t.createDialer(t.Config.Address)),
+		grpc.WithChainUnaryInterceptor(t.retryUnaryInterceptor()),
+		grpc.WithChainStreamInterceptor(t.retryStreamInterceptor()),
+	)
+	if t.Config.Proxy != nil && *t.Config.Proxy != "" {
+		// the target proxy replaces the environment proxy
+		tOpts = append(tOpts, grpc.WithNoProxy())
+	}
+	if creds := t.perRPCCredentials(); creds != nil {
+		tOpts = append(tOpts, grpc.WithPerRPCCredentials(creds))
+	}
+	if t.pool == nil {
+		t.client, err = grpc.NewClient(t.Config.Address, tOpts...)
+		return err
+	}
+	t.client, err = t.pool.acquire(t, func(pOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		return grpc.NewClient(t.Config.Address, append(tOpts, pOpts...)...)
+	})
+	t.pooled = err == nil
 	return err
 }
 
@@ -90,24 +128,52 @@ func (t *Target) createDialer(addr string) func(context.Context, string) (net.Co
 
 func (t *Target) createCustomDialer(addr string) func(context.Context, string) (net.Conn, error) {
 	return func(ctx context.Context, _ string) (net.Conn, error) {
-		dialer := net.Dialer{
-			Timeout:   t.Config.Timeout,
-			KeepAlive: t.Config.TCPKeepalive,
-		}
 		ctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
 		defer cancel()
 
-		var networkType = "tcp"
-		if indx := strings.Index(addr, "://"); indx > 0 {
-			if addr[:indx] == "unix" {
-				networkType = "unix"
-				addr = addr[indx+3:]
+		if indx := strings.Index(addr, "://"); indx > 0 && addr[:indx] == "unix" {
+			if len(t.Config.SSHProxy) > 0 {
+				return nil, fmt.Errorf("cannot reach unix address %q through an ssh proxy", addr)
 			}
+			dialer := net.Dialer{Timeout: t.Config.Timeout}
+			return dialer.DialContext(ctx, "unix", addr[indx+3:])
+		}
+		if len(t.Config.SSHProxy) > 0 {
+			return t.sshDial(ctx, addr)
 		}
-		return dialer.DialContext(ctx, networkType, addr)
+		return t.dialTCP(ctx, addr)
 	}
 }
 
+// TLSHandshake opens a new connection to the target, through the same proxies as the gRPC client,
+// and returns the state of a TLS handshake performed with tlsConfig.
+// Like the gRPC client, the handshake sends the target host as SNI, unless tlsConfig sets a server name,
+// and offers the h2 protocol.
+func (t *Target) TLSHandshake(ctx context.Context, tlsConfig *tls.Config) (*tls.ConnectionState, error) {
+	conn, err := t.createDialer(t.Config.Address)(ctx, t.Config.Address)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig = tlsConfig.Clone()
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = t.Config.Address
+		if host, _, err := net.SplitHostPort(t.Config.Address); err == nil {
+			tlsConfig.ServerName = host
+		}
+	}
+	if len(tlsConfig.NextProtos) == 0 {
+		tlsConfig.NextProtos = []string{"h2"}
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	defer tlsConn.Close()
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := tlsConn.ConnectionState()
+	return &state, nil
+}
+
 func (t *Target) Conn() grpc.ClientConnInterface { return t.client }
 
 func (t *Target) CertClient() cert.CertificateManagementClient {
@@ -163,6 +229,40 @@ func Password(password string) TargetOption {
 	}
 }
 
+// Token sets the bearer token sent in the authorization metadata.
+func Token(token string) TargetOption {
+	return func(t *Target) error {
+		t.Config.Token = pointer.ToString(token)
+		return nil
+	}
+}
+
+// OAuth2ClientCredentials sets the OAuth2 client credentials used
+// to fetch the bearer token sent in the authorization metadata.
+func OAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) TargetOption {
+	return func(t *Target) error {
+		t.Config.OAuth2 = &config.OAuth2Config{
+			TokenURL:     tokenURL,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       scopes,
+		}
+		return nil
+	}
+}
+
+// Metadata adds a gRPC metadata key/value pair sent with each RPC.
+// This Option can be set multiple times.
+func Metadata(key, value string) TargetOption {
+	return func(t *Target) error {
+		if t.Config.Metadata == nil {
+			t.Config.Metadata = make(map[string]string)
+		}
+		t.Config.Metadata[strings.ToLower(key)] = value
+		return nil
+	}
+}
+
 // Timeout sets the gNMI client creation timeout.
 func Timeout(timeout time.Duration) TargetOption {
 	return func(t *Target) error {
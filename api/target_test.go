@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnoic/config"
+)
+
+func TestTLSHandshake(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "r1.example.net"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hellos := make(chan *tls.ClientHelloInfo, 1)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		NextProtos:   []string{"h2"},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			hellos <- hello
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	tests := []struct {
+		name       string
+		serverName string
+		want       string
+	}{
+		{name: "target_host", want: "localhost"},
+		{name: "server_name", serverName: "r1.example.net", want: "r1.example.net"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := NewTargetFromConfig(&config.TargetConfig{
+				Name:    "r1",
+				Address: net.JoinHostPort("localhost", port),
+				Timeout: time.Second,
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			state, err := tg.TLSHandshake(ctx, &tls.Config{InsecureSkipVerify: true, ServerName: tt.serverName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hello := <-hellos
+			if hello.ServerName != tt.want {
+				t.Errorf("SNI = %q, want %q", hello.ServerName, tt.want)
+			}
+			if state.NegotiatedProtocol != "h2" {
+				t.Errorf("negotiated protocol = %q, want h2", state.NegotiatedProtocol)
+			}
+		})
+	}
+}
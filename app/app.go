@@ -2,9 +2,11 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/karimra/gnoic/api"
@@ -24,13 +26,36 @@ type App struct {
 	Cfn     context.CancelFunc
 	RootCmd *cobra.Command
 
-	wg      *sync.WaitGroup
 	Config  *config.Config
 	m       *sync.Mutex
 	Targets map[string]*api.Target
 	Logger  *log.Entry
 	// print mutex
 	pm *sync.Mutex
+	// targets not started by the scheduler
+	skipped []string
+	// gRPC connections shared by the commands run in this session
+	pool *api.Pool
+	// command start time, targets results and the time spent on each target
+	start     time.Time
+	results   []*result
+	durations map[string]time.Duration
+	// the commands are run from the interactive shell
+	inShell bool
+	// parsed --format template=<tmpl>
+	outputTemplate *template.Template
+	// journal entry of the running command
+	journal *journalEntry
+	// --log-file
+	logFile *os.File
+	// target names indexed by address
+	targetNames sync.Map
+	// spans of the running command, if tracing is enabled
+	tracing *tracing
+	// signs the targets certificates, set by the cert commands
+	signer signer
+	// targets checked by Guard, the destructive command runs against those
+	guardedTargets map[string]*config.TargetConfig
 }
 
 func New() *App {
@@ -40,7 +65,6 @@ func New() *App {
 		ctx:     ctx,
 		Cfn:     cancel,
 		RootCmd: new(cobra.Command),
-		wg:      new(sync.WaitGroup),
 		Config:  config.New(),
 		m:       new(sync.Mutex),
 		Targets: make(map[string]*api.Target),
@@ -56,7 +80,24 @@ func (a *App) InitGlobalFlags() {
 	a.RootCmd.PersistentFlags().StringVar(&a.Config.CfgFile, "config", "", "config file (default is $HOME/gnoic.yaml)")
 	a.RootCmd.PersistentFlags().StringSliceVarP(&a.Config.GlobalFlags.Address, "address", "a", []string{}, "comma separated gNOI targets addresses")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Username, "username", "u", "", "username")
-	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Password, "password", "p", "", "password")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Password, "password", "p", "", "password, or a secret reference: env:VAR, file:/path, exec:command or secret:name")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.AskPassword, "ask-password", "", false, "prompt for the password, without echo")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.KeepaliveTime, "keepalive-time", "", 0, "interval between gRPC keepalive pings, 0 disables them")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.KeepaliveTimeout, "keepalive-timeout", "", 20*time.Second, "time to wait for a gRPC keepalive ping answer before closing the connection")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.MaxMsgSize, "max-msg-size", "", msgSize, "maximum size in bytes of the gRPC messages sent and received")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.ConnIdleTimeout, "conn-idle-timeout", "", 5*time.Minute, "time after which an unused gRPC connection is closed")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Proxy, "proxy", "", "", "proxy URL used to reach the targets, socks5://[user:pass@]host:port or http(s)://[user:pass@]host:port")
+	a.RootCmd.PersistentFlags().StringSliceVarP(&a.Config.GlobalFlags.NoProxy, "no-proxy", "", []string{}, "comma separated prefixes, IP addresses or domain names reached without the proxy")
+	a.RootCmd.PersistentFlags().StringSliceVarP(&a.Config.GlobalFlags.SSHProxy, "ssh-proxy", "", []string{}, "comma separated chain of SSH jump hosts used to reach the targets, in [user@]host[:port] format")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.SSHKeyFile, "ssh-key-file", "", "", "private key file used to authenticate with the SSH jump hosts")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.SSHKnownHosts, "ssh-known-hosts", "", "", "known_hosts file used to verify the SSH jump hosts keys (default is ~/.ssh/known_hosts)")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Token, "token", "", "", "bearer token sent in the authorization metadata, or a secret reference")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.OAuth2TokenURL, "oauth2-token-url", "", "", "OAuth2 token endpoint used to fetch a bearer token with the client credentials flow")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.OAuth2ClientID, "oauth2-client-id", "", "", "OAuth2 client ID")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.OAuth2ClientSecret, "oauth2-client-secret", "", "", "OAuth2 client secret, or a secret reference")
+	a.RootCmd.PersistentFlags().StringSliceVarP(&a.Config.GlobalFlags.OAuth2Scopes, "oauth2-scopes", "", []string{}, "OAuth2 scopes")
+	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.Metadata, "metadata", "", []string{}, "gRPC metadata sent with each RPC, in key=value format")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.SecretsFile, "secrets-file", "", "", "encrypted secrets file path (default is $XDG_CONFIG_HOME/gnoic/secrets.enc)")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Port, "port", "", defaultGrpcPort, "gRPC port")
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.Insecure, "insecure", "", false, "insecure connection")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.TLSCa, "tls-ca", "", "", "tls certificate authority")
@@ -66,15 +107,40 @@ func (a *App) InitGlobalFlags() {
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.Debug, "debug", "d", false, "debug mode")
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.SkipVerify, "skip-verify", "", false, "skip verify tls connection")
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.ProxyFromEnv, "proxy-from-env", "", false, "use proxy from environment")
-	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Format, "format", "", "text", "output format, one of: text, json")
-	// a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.LogFile, "log-file", "", "", "log file path")
-	// a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.Log, "log", "", false, "write log messages to stderr")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.SummaryFile, "summary-file", "", "", "write a JSON summary of the succeeded, failed and skipped targets to this file")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.JournalFile, "journal-file", "", "", "journal file the executed commands are appended to (default is $XDG_STATE_HOME/gnoic/journal.jsonl)")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.NoJournal, "no-journal", "", false, "do not record the command in the journal file")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.Yes, "yes", "", false, "do not ask for confirmation before running a destructive command")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.MaxTargets, "max-targets", "", 0, "maximum number of targets a destructive command can run against, 0 means no limit")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.PolicyFile, "policy-file", "", "", "policy file restricting the targets and change windows of destructive commands")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.DryRun, "dry-run", "", false, "print the requests a mutating command would send to each target, without connecting to them")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.Watch, "watch", "", 0, "re-run a read-only command at this interval and print the changes of each target")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Until, "until", "", "", "stop watching once all the targets responses match this condition, e.g: 'status==HEALTHY'")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Format, "format", "", "text", "output format, one of: text, json, yaml, ndjson, csv or template=<go template>")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.LogFile, "log-file", "", "", "write the log messages to this file instead of stderr")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.LogFormat, "log-format", "", "text", fmt.Sprintf("log messages format, one of %q", logFormats))
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.LogLevel, "log-level", "", "info", "log level, one of: trace, debug, info, warn, error, fatal or panic. --debug sets it to debug")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.OTLPEndpoint, "otlp-endpoint", "", "", "OpenTelemetry collector address the traces are exported to over OTLP/gRPC, in host:port format")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.OTLPInsecure, "otlp-insecure", "", false, "export the traces to the OpenTelemetry collector without TLS")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.TraceFile, "trace-file", "", "", "append the traces to this file, one JSON span per line")
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.PrintProto, "print-proto", "", false, "print request(s)/responses(s) in prototext format")
-	// a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.Retry, "retry", "", defaultRetryTimer, "retry timer for RPCs")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.TLSMinVersion, "tls-min-version", "", "", fmt.Sprintf("minimum TLS supported version, one of %q", tlsVersions))
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.TLSMaxVersion, "tls-max-version", "", "", fmt.Sprintf("maximum TLS supported version, one of %q", tlsVersions))
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.TLSVersion, "tls-version", "", "", fmt.Sprintf("set TLS version. Overwrites --tls-min-version and --tls-max-version, one of %q", tlsVersions))
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.Gzip, "gzip", "", false, "enable gzip compression on gRPC connections")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Inventory, "inventory", "", "", "targets inventory source: a CSV/YAML/JSON file, an http(s):// URL, srv:<dns name> or exec:<command>")
+	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.InventoryHeaders, "inventory-header", "", []string{}, "HTTP header added to inventory requests, in 'Key: Value' format")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.Select, "select", "", "", "targets selection expression, e.g: 'role=spine,site in (par1,lon2),!maintenance'")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.MaxConcurrency, "max-concurrency", "", 0, "maximum number of targets handled concurrently, 0 means no limit")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.BatchSize, "batch-size", "", 0, "number of targets per batch, a batch starts once the previous one is done. 0 means a single batch")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.Canary, "canary", "", 0, "number of targets to run first, the remaining targets are not started if any of them fails")
+	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.MaxFailures, "max-failures", "", "", "number (e.g 5) or percentage (e.g 10%) of failed targets after which no new targets are started")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.RetryMaxAttempts, "retry-max-attempts", "", 1, "maximum number of attempts per RPC, including the first one")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.RetryBackoff, "retry-backoff", "", time.Second, "wait time before the first retry, doubled after each attempt")
+	a.RootCmd.PersistentFlags().DurationVarP(&a.Config.GlobalFlags.RetryMaxBackoff, "retry-max-backoff", "", 30*time.Second, "maximum wait time between retries")
+	a.RootCmd.PersistentFlags().Float64VarP(&a.Config.GlobalFlags.RetryJitter, "retry-jitter", "", 0.2, "fraction of the backoff randomly added or removed, between 0 and 1")
+	a.RootCmd.PersistentFlags().StringSliceVarP(&a.Config.GlobalFlags.RetryCodes, "retry-codes", "", []string{"Unavailable", "ResourceExhausted"}, "gRPC status codes that trigger a retry")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.RetryNonIdempotent, "retry-non-idempotent", "", false, "allow retrying RPCs that change the target state, e.g: File Put, System Reboot and SetPackage")
 
 	a.RootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(flag.Name, flag)
@@ -82,18 +148,94 @@ func (a *App) InitGlobalFlags() {
 }
 
 func (a *App) PreRun(cmd *cobra.Command, args []string) error {
-	// init logger
-	a.Config.SetLogger()
+	// init logger, set again once the flags from the config file are known
 	if a.Config.Debug {
 		a.Logger.Logger.SetLevel(log.DebugLevel)
-		grpclog.SetLogger(a.Logger) //lint:ignore SA1019 .
 	}
+	a.Config.SetLogger(a.Logger)
 	a.Config.SetPersistantFlagsFromFile(a.RootCmd)
+	a.Config.SetCommand(cmd)
+	err := a.setLogger()
+	if err != nil {
+		return UsageError(err)
+	}
+	a.Config.SetLogger(a.Logger)
+	if a.Config.Debug {
+		grpclog.SetLogger(a.Logger) //lint:ignore SA1019 .
+	}
+	err = a.validateGlobalFlags()
+	if err != nil {
+		return UsageError(err)
+	}
+	a.start = time.Now()
+	a.results = nil
+	a.durations = nil
+	a.skipped = nil
+	if a.pool == nil {
+		a.pool = api.NewPool(
+			api.WithKeepalive(a.Config.KeepaliveTime, a.Config.KeepaliveTimeout),
+			api.WithMaxMsgSize(a.Config.MaxMsgSize),
+			api.WithIdleTimeout(a.Config.ConnIdleTimeout),
+		)
+	}
+	return nil
+}
+
+func (a *App) validateGlobalFlags() error {
+	if a.Config.MaxConcurrency < 0 || a.Config.BatchSize < 0 || a.Config.Canary < 0 {
+		return errors.New("max-concurrency, batch-size and canary must be positive")
+	}
+	_, err := parseMaxFailures(a.Config.MaxFailures, 0)
+	if err != nil {
+		return err
+	}
+	err = a.parseFormat()
+	if err != nil {
+		return err
+	}
+	rp := &config.RetryPolicy{Codes: a.Config.RetryCodes}
+	_, err = rp.RetryableCodes()
+	if err != nil {
+		return err
+	}
+	if a.Config.MaxTargets < 0 {
+		return errors.New("max-targets must be positive")
+	}
+	if a.Config.Watch < 0 {
+		return errors.New("watch interval must be positive")
+	}
+	if a.Config.Until != "" && a.Config.Watch == 0 {
+		return errors.New("--until requires --watch")
+	}
+	_, err = parseWatchCondition(a.Config.Until)
+	return err
+}
+
+// PostRun releases the resources held by the App once the command is done.
+// The connections are kept open while the interactive shell runs.
+func (a *App) PostRun(cmd *cobra.Command, args []string) error {
+	if a.pool != nil && !a.inShell {
+		a.pool.Close()
+		a.pool = nil
+	}
 	return nil
 }
 
+// commandContext returns the context cmd runs with, set per command by the shell,
+// or the App context if cmd was not executed with one.
+func (a *App) commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return a.ctx
+}
+
 func (a *App) createBaseDialOpts() []grpc.DialOption {
-	opts := []grpc.DialOption{grpc.WithBlock()}
+	opts := []grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithChainUnaryInterceptor(a.journalUnaryInterceptor(), a.logUnaryInterceptor(), a.traceUnaryInterceptor()),
+		grpc.WithChainStreamInterceptor(a.journalStreamInterceptor(), a.logStreamInterceptor(), a.traceStreamInterceptor()),
+	}
 	if !a.Config.ProxyFromEnv {
 		opts = append(opts, grpc.WithNoProxy())
 	}
@@ -109,7 +251,7 @@ func (a *App) printMsg(targetName string, m proto.Message) {
 	}
 	a.pm.Lock()
 	defer a.pm.Unlock()
-	fmt.Fprintf(os.Stdout, "%q:\n%s\n%s\n",
+	fmt.Fprintf(os.Stderr, "%q:\n%s\n%s\n",
 		targetName,
 		m.ProtoReflect().Descriptor().FullName(),
 		prototext.Format(m))
@@ -3,6 +3,8 @@ package app
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -10,6 +12,7 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -18,19 +21,46 @@ import (
 	"github.com/spf13/pflag"
 )
 
-var caCert tls.Certificate
-
 func (a *App) InitCertFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
 	cmd.PersistentFlags().StringVar(&a.Config.CertCAKey, "ca-key", "", "CA key")
 	cmd.PersistentFlags().StringVar(&a.Config.CertCACert, "ca-cert", "", "CA Certificate")
+	cmd.PersistentFlags().StringVar(&a.Config.CertSigner, "signer", signerLocal, fmt.Sprintf("certificates signer, one of %q", signers))
+	cmd.PersistentFlags().StringVar(&a.Config.CertSignerTLSCA, "signer-tls-ca", "", "CA certificate used to verify the TLS certificate of the vault or acme signer, the system CAs if not set")
+	cmd.PersistentFlags().StringVar(&a.Config.CertVaultAddr, "vault-addr", "", "vault address, defaults to $VAULT_ADDR")
+	cmd.PersistentFlags().StringVar(&a.Config.CertVaultToken, "vault-token", "", "vault token or a secret reference, defaults to $VAULT_TOKEN")
+	cmd.PersistentFlags().StringVar(&a.Config.CertVaultNamespace, "vault-namespace", "", "vault namespace, defaults to $VAULT_NAMESPACE")
+	cmd.PersistentFlags().StringVar(&a.Config.CertVaultMount, "vault-mount", "pki", "vault PKI secrets engine mount path")
+	cmd.PersistentFlags().StringVar(&a.Config.CertVaultRole, "vault-role", "", "vault PKI role used to sign the certificates")
+	cmd.PersistentFlags().StringVar(&a.Config.CertACMEDirectory, "acme-directory", "", "ACME server directory URL")
+	cmd.PersistentFlags().StringVar(&a.Config.CertACMEAccountKey, "acme-account-key", "", "ACME account private key file, a new account key is generated if not set")
+	cmd.PersistentFlags().StringVar(&a.Config.CertACMEEABKID, "acme-eab-kid", "", "ACME external account binding key ID")
+	cmd.PersistentFlags().StringVar(&a.Config.CertACMEEABKey, "acme-eab-key", "", "ACME external account binding base64url encoded HMAC key, or a secret reference")
+	markSecretFlags(cmd.PersistentFlags(), "vault-token", "acme-eab-key")
 	//
 	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
 	})
 }
 
+// loadCACert loads the CA certificate and key used to sign the targets certificates.
+func loadCACert(certFile, keyFile string) (*tls.Certificate, error) {
+	caCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(caCert.Certificate) != 1 {
+		return nil, errors.New("CA cert and key contains 0 or more than 1 certificate")
+	}
+	c, err := x509.ParseCertificate(caCert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	caCert.Leaf = c
+	return &caCert, nil
+}
+
 func genSerialNumber() (*big.Int, error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	return rand.Int(rand.Reader, serialNumberLimit)
@@ -49,7 +79,6 @@ func certificateFromCSR(csr *x509.CertificateRequest, certExpiration time.Durati
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
 		NotAfter:              time.Now().Add(certExpiration),
 		NotBefore:             time.Now().Add(-1 * time.Hour),
-		SignatureAlgorithm:    csr.SignatureAlgorithm,
 		Subject:               csr.Subject,
 		Signature:             csr.Signature,
 		Extensions:            csr.Extensions,
@@ -66,11 +95,20 @@ func certificateFromCSR(csr *x509.CertificateRequest, certExpiration time.Durati
 }
 
 func keyID(pub crypto.PublicKey) ([]byte, error) {
-	pk, ok := pub.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("failed to parse public key, not a rsa.PublicKey type")
+	var pkBytes []byte
+	var err error
+	switch pk := pub.(type) {
+	case *rsa.PublicKey:
+		pkBytes, err = asn1.Marshal(*pk)
+	case *ecdsa.PublicKey:
+		var epk *ecdh.PublicKey
+		epk, err = pk.ECDH()
+		if err == nil {
+			pkBytes = epk.Bytes()
+		}
+	default:
+		return nil, fmt.Errorf("failed to parse public key, unsupported type %T", pub)
 	}
-	pkBytes, err := asn1.Marshal(*pk)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal public key: %v", err)
 	}
@@ -78,14 +116,6 @@ func keyID(pub crypto.PublicKey) ([]byte, error) {
 	return subjectKeyID[:], nil
 }
 
-func (a *App) sign(c *x509.Certificate, ca *tls.Certificate) (*x509.Certificate, error) {
-	derCert, err := x509.CreateCertificate(rand.Reader, c, ca.Leaf, c.PublicKey, ca.PrivateKey)
-	if err != nil {
-		return nil, err
-	}
-	return x509.ParseCertificate(derCert)
-}
-
 func toPEM(c *x509.Certificate) ([]byte, error) {
 	b := new(bytes.Buffer)
 	err := pem.Encode(b, &pem.Block{
@@ -0,0 +1,379 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/karimra/gnoic/api"
+)
+
+// certificate audit status, by increasing severity
+const (
+	auditOK   = "ok"
+	auditWarn = "warn"
+	auditCrit = "crit"
+)
+
+var auditSeverity = map[string]int{auditOK: 0, auditWarn: 1, auditCrit: 2}
+
+var weakSignatureAlgorithms = []x509.SignatureAlgorithm{
+	x509.MD2WithRSA,
+	x509.MD5WithRSA,
+	x509.SHA1WithRSA,
+	x509.DSAWithSHA1,
+	x509.ECDSAWithSHA1,
+}
+
+// certAudit is the audit result of a certificate.
+type certAudit struct {
+	Target             string    `json:"target,omitempty"`
+	ID                 string    `json:"id,omitempty"`
+	Subject            string    `json:"subject,omitempty"`
+	Issuer             string    `json:"issuer,omitempty"`
+	Serial             string    `json:"serial,omitempty"`
+	NotAfter           time.Time `json:"not-after,omitempty"`
+	DaysLeft           int       `json:"days-left"`
+	KeyAlgorithm       string    `json:"key-algorithm,omitempty"`
+	KeySize            int       `json:"key-size,omitempty"`
+	SignatureAlgorithm string    `json:"signature-algorithm,omitempty"`
+	Status             string    `json:"status,omitempty"`
+	Findings           []string  `json:"findings,omitempty"`
+
+	cert *x509.Certificate
+}
+
+// addFinding records a finding and raises the certificate status to at least status.
+func (c *certAudit) addFinding(status, finding string) {
+	c.Findings = append(c.Findings, finding)
+	if auditSeverity[status] > auditSeverity[c.Status] {
+		c.Status = status
+	}
+}
+
+type certAuditResponse struct {
+	TargetError
+	certs []*certAudit
+}
+
+func (r *certAuditResponse) response() interface{} { return r.certs }
+
+func (a *App) InitCertAuditFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	//
+	cmd.Flags().StringVar(&a.Config.CertAuditWarn, "warn", "30d", "warn about the certificates expiring within this duration, e.g: 30d, 720h")
+	cmd.Flags().StringVar(&a.Config.CertAuditCrit, "crit", "7d", "report as critical the certificates expiring within this duration, e.g: 7d, 168h")
+	cmd.Flags().StringSliceVar(&a.Config.CertAuditID, "id", []string{}, "certificate ID to audit, all certificates if not set")
+	cmd.Flags().IntVar(&a.Config.CertAuditMinKeySize, "min-key-size", 2048, "warn about the RSA keys smaller than this size in bits")
+	//
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) PreRunECertAudit(cmd *cobra.Command, args []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	warn, err := parseDays(a.Config.CertAuditWarn)
+	if err != nil {
+		return fmt.Errorf("invalid --warn: %v", err)
+	}
+	crit, err := parseDays(a.Config.CertAuditCrit)
+	if err != nil {
+		return fmt.Errorf("invalid --crit: %v", err)
+	}
+	if crit > warn {
+		return errors.New("--crit must not be greater than --warn")
+	}
+	return nil
+}
+
+func (a *App) RunECertAudit(cmd *cobra.Command, args []string) error {
+	var ca *x509.Certificate
+	if a.Config.CertCACert != "" {
+		var err error
+		ca, err = readCertificate(a.Config.CertCACert)
+		if err != nil {
+			return err
+		}
+	}
+	warn, _ := parseDays(a.Config.CertAuditWarn)
+	crit, _ := parseDays(a.Config.CertAuditCrit)
+
+	targets, err := a.GetTargets()
+	if err != nil {
+		return err
+	}
+
+	numTargets := len(targets)
+	responseChan := make(chan *certAuditResponse, numTargets)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certAuditResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Name,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		certs, err := a.CertAudit(ctx, t, a.Config.CertAuditID)
+		return sendResponse(responseChan, &certAuditResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			certs: certs,
+		})
+	})
+	close(responseChan)
+
+	errs := make([]error, 0, numTargets)
+	rsps := make([]*certAuditResponse, 0, numTargets)
+	all := make([]*certAudit, 0)
+	for rsp := range responseChan {
+		rsps = append(rsps, rsp)
+		if rsp.Err != nil {
+			wErr := fmt.Errorf("%q Cert Audit failed: %v", rsp.TargetName, rsp.Err)
+			a.targetLogger(rsp.TargetName).Error(wErr)
+			errs = append(errs, wErr)
+			continue
+		}
+		all = append(all, rsp.certs...)
+	}
+	auditCertificates(all, ca, a.Config.CertAuditMinKeySize, warn, crit, time.Now())
+	for _, rsp := range rsps {
+		a.addResult(rsp)
+	}
+	if a.textOutput() {
+		fmt.Print(certAuditTable(all))
+	}
+	return certAuditError(a.handleErrs(errs), all)
+}
+
+// certAuditError combines the targets error with the warning and critical certificates,
+// the exit code is the most severe of both.
+func certAuditError(targetsErr error, certs []*certAudit) error {
+	var numWarn, numCrit int
+	for _, c := range certs {
+		switch c.Status {
+		case auditWarn:
+			numWarn++
+		case auditCrit:
+			numCrit++
+		}
+	}
+	var auditErr error
+	switch {
+	case numCrit > 0:
+		auditErr = &ExitError{Code: ExitFailure, Err: fmt.Errorf("%d critical and %d warning certificate(s)", numCrit, numWarn)}
+	case numWarn > 0:
+		auditErr = &ExitError{Code: ExitPartialFailure, Err: fmt.Errorf("%d warning certificate(s)", numWarn)}
+	}
+	switch {
+	case targetsErr == nil:
+		return auditErr
+	case auditErr == nil:
+		return targetsErr
+	}
+	return &ExitError{
+		Code: mostSevereExitCode(ExitCode(targetsErr), ExitCode(auditErr)),
+		Err:  fmt.Errorf("%v, %v", targetsErr, auditErr),
+	}
+}
+
+// CertAudit fetches and parses the certificates of target t,
+// all of them if ids is empty.
+func (a *App) CertAudit(ctx context.Context, t *api.Target, ids []string) ([]*certAudit, error) {
+	rsp, err := a.CertGetCertificates(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	certs := make([]*certAudit, 0, len(rsp.GetCertificateInfo()))
+	for _, certInfo := range rsp.GetCertificateInfo() {
+		if !sInListNotEmpty(certInfo.GetCertificateId(), ids) {
+			continue
+		}
+		c := &certAudit{
+			Target: t.Config.Name,
+			ID:     certInfo.GetCertificateId(),
+			Status: auditOK,
+		}
+		certs = append(certs, c)
+		block, _ := pem.Decode(certInfo.GetCertificate().GetCertificate())
+		if block == nil {
+			c.addFinding(auditCrit, "invalid PEM certificate")
+			continue
+		}
+		c.cert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			c.addFinding(auditCrit, fmt.Sprintf("invalid certificate: %v", err))
+			continue
+		}
+		c.Subject = c.cert.Subject.ToRDNSequence().String()
+		c.Issuer = c.cert.Issuer.ToRDNSequence().String()
+		c.Serial = c.cert.SerialNumber.Text(16)
+		c.NotAfter = c.cert.NotAfter
+		c.KeyAlgorithm = c.cert.PublicKeyAlgorithm.String()
+		c.KeySize = publicKeySize(c.cert.PublicKey)
+		c.SignatureAlgorithm = c.cert.SignatureAlgorithm.String()
+	}
+	return certs, nil
+}
+
+// auditCertificates checks the certificates expiry, key size, signature
+// algorithm and issuer, then looks for serial numbers and keys shared by
+// several targets.
+func auditCertificates(certs []*certAudit, ca *x509.Certificate, minKeySize int, warn, crit time.Duration, now time.Time) {
+	serials := make(map[string]map[string]struct{})
+	keys := make(map[string]map[string]struct{})
+	add := func(m map[string]map[string]struct{}, k, target string) {
+		if m[k] == nil {
+			m[k] = make(map[string]struct{})
+		}
+		m[k][target] = struct{}{}
+	}
+	serialKey := func(c *certAudit) string { return c.Issuer + "/" + c.Serial }
+	pubKey := func(c *certAudit) string {
+		h := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+		return hex.EncodeToString(h[:])
+	}
+
+	for _, c := range certs {
+		if c.cert == nil {
+			continue
+		}
+		left := c.cert.NotAfter.Sub(now)
+		c.DaysLeft = int(left.Hours() / 24)
+		switch {
+		case left <= 0:
+			c.addFinding(auditCrit, "expired")
+		case left <= crit:
+			c.addFinding(auditCrit, fmt.Sprintf("expires in %d day(s)", c.DaysLeft))
+		case left <= warn:
+			c.addFinding(auditWarn, fmt.Sprintf("expires in %d day(s)", c.DaysLeft))
+		}
+		if now.Before(c.cert.NotBefore) {
+			c.addFinding(auditCrit, "not yet valid")
+		}
+		if c.cert.PublicKeyAlgorithm == x509.RSA && c.KeySize < minKeySize {
+			c.addFinding(auditWarn, fmt.Sprintf("RSA key size %d below %d", c.KeySize, minKeySize))
+		}
+		for _, alg := range weakSignatureAlgorithms {
+			if c.cert.SignatureAlgorithm == alg {
+				c.addFinding(auditWarn, fmt.Sprintf("weak signature algorithm %s", alg))
+			}
+		}
+		if ca != nil {
+			if err := c.cert.CheckSignatureFrom(ca); err != nil {
+				c.addFinding(auditCrit, fmt.Sprintf("issuer mismatch: not signed by %q", ca.Subject.ToRDNSequence().String()))
+			}
+		}
+		add(serials, serialKey(c), c.Target)
+		add(keys, pubKey(c), c.Target)
+	}
+	shared := func(targets map[string]struct{}, self string) string {
+		others := make([]string, 0, len(targets))
+		for t := range targets {
+			if t != self {
+				others = append(others, t)
+			}
+		}
+		sort.Strings(others)
+		return strings.Join(others, ", ")
+	}
+	for _, c := range certs {
+		if c.cert == nil {
+			continue
+		}
+		if ts := serials[serialKey(c)]; len(ts) > 1 {
+			c.addFinding(auditCrit, fmt.Sprintf("serial number shared with %s", shared(ts, c.Target)))
+		}
+		if ts := keys[pubKey(c)]; len(ts) > 1 {
+			c.addFinding(auditCrit, fmt.Sprintf("public key shared with %s", shared(ts, c.Target)))
+		}
+	}
+}
+
+// parseDays parses a duration which can be expressed in days, e.g: 30d.
+func parseDays(s string) (time.Duration, error) {
+	if d, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(d, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func readCertificate(filename string) (*x509.Certificate, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parsePEMCertificate(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return c, nil
+}
+
+func certAuditTable(certs []*certAudit) string {
+	sort.Slice(certs, func(i, j int) bool {
+		if certs[i].Target == certs[j].Target {
+			return certs[i].ID < certs[j].ID
+		}
+		return certs[i].Target < certs[j].Target
+	})
+	tabData := make([][]string, 0, len(certs))
+	for _, c := range certs {
+		key := c.KeyAlgorithm
+		if c.KeySize > 0 {
+			key = fmt.Sprintf("%s %d", key, c.KeySize)
+		}
+		notAfter := ""
+		if !c.NotAfter.IsZero() {
+			notAfter = c.NotAfter.Format(time.RFC3339)
+		}
+		tabData = append(tabData, []string{
+			c.Target,
+			c.ID,
+			c.Subject,
+			notAfter,
+			strconv.Itoa(c.DaysLeft),
+			key,
+			c.SignatureAlgorithm,
+			strings.ToUpper(c.Status),
+			strings.Join(c.Findings, "\n"),
+		})
+	}
+	b := new(bytes.Buffer)
+	table := tablewriter.NewWriter(b)
+	table.SetHeader([]string{"Target Name", "ID", "Subject", "Valid Until", "Days Left", "Key", "Signature", "Status", "Findings"})
+	formatTable(table)
+	table.AppendBulk(tabData)
+	table.Render()
+	return b.String()
+}
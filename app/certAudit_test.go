@@ -0,0 +1,171 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, cn string, serial int64, notAfter time.Time, key *ecdsa.PrivateKey, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func Test_auditCertificates(t *testing.T) {
+	now := time.Now()
+	newKey := func() *ecdsa.PrivateKey {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	caKey, otherCAKey := newKey(), newKey()
+	ca := testCertificate(t, "ca", 1, now.Add(10*365*24*time.Hour), caKey, nil, nil)
+	otherCA := testCertificate(t, "other-ca", 1, now.Add(10*365*24*time.Hour), otherCAKey, nil, nil)
+	sharedKey := newKey()
+
+	audit := func(target string, c *x509.Certificate) *certAudit {
+		return &certAudit{
+			Target:  target,
+			ID:      "gnmi",
+			Issuer:  c.Issuer.ToRDNSequence().String(),
+			Serial:  c.SerialNumber.Text(16),
+			KeySize: publicKeySize(c.PublicKey),
+			Status:  auditOK,
+			cert:    c,
+		}
+	}
+	certs := []*certAudit{
+		audit("r1", testCertificate(t, "r1", 10, now.Add(90*24*time.Hour), newKey(), ca, caKey)),
+		audit("r2", testCertificate(t, "r2", 11, now.Add(20*24*time.Hour), newKey(), ca, caKey)),
+		audit("r3", testCertificate(t, "r3", 12, now.Add(3*24*time.Hour), newKey(), ca, caKey)),
+		audit("r4", testCertificate(t, "r4", 13, now.Add(-24*time.Hour), newKey(), ca, caKey)),
+		audit("r5", testCertificate(t, "r5", 14, now.Add(90*24*time.Hour), newKey(), otherCA, otherCAKey)),
+		audit("r6", testCertificate(t, "r6", 20, now.Add(90*24*time.Hour), newKey(), ca, caKey)),
+		audit("r7", testCertificate(t, "r7", 20, now.Add(90*24*time.Hour), sharedKey, ca, caKey)),
+		audit("r8", testCertificate(t, "r8", 21, now.Add(90*24*time.Hour), sharedKey, ca, caKey)),
+	}
+	auditCertificates(certs, ca, 2048, 30*24*time.Hour, 7*24*time.Hour, now)
+
+	want := []struct {
+		status   string
+		findings []string
+	}{
+		{auditOK, nil},
+		{auditWarn, []string{"expires in 19 day(s)"}},
+		{auditCrit, []string{"expires in 2 day(s)"}},
+		{auditCrit, []string{"expired"}},
+		{auditCrit, []string{`issuer mismatch: not signed by "CN=ca"`}},
+		{auditCrit, []string{"serial number shared with r7"}},
+		{auditCrit, []string{"serial number shared with r6", "public key shared with r8"}},
+		{auditCrit, []string{"public key shared with r7"}},
+	}
+	for i, c := range certs {
+		if c.Status != want[i].status || !reflect.DeepEqual(c.Findings, want[i].findings) {
+			t.Errorf("%s: got status=%s findings=%q, want status=%s findings=%q",
+				c.Target, c.Status, c.Findings, want[i].status, want[i].findings)
+		}
+	}
+}
+
+func Test_parseDays(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "72h", want: 72 * time.Hour},
+		{in: "d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "30days", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseDays(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDays(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDays(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func Test_certAuditError(t *testing.T) {
+	warn := []*certAudit{{Status: auditOK}, {Status: auditWarn}}
+	crit := []*certAudit{{Status: auditWarn}, {Status: auditCrit}}
+	tests := []struct {
+		name       string
+		targetsErr error
+		certs      []*certAudit
+		want       int
+	}{
+		{name: "ok", certs: []*certAudit{{Status: auditOK}}, want: ExitOK},
+		{name: "warn", certs: warn, want: ExitPartialFailure},
+		{name: "crit", certs: crit, want: ExitFailure},
+		{
+			name:       "unreachable_targets",
+			targetsErr: &ExitError{Code: ExitConnectionFailure, Err: errors.New("there was 1 error(s)")},
+			want:       ExitConnectionFailure,
+		},
+		{
+			name:       "unreachable_targets_and_warn",
+			targetsErr: &ExitError{Code: ExitConnectionFailure, Err: errors.New("there was 1 error(s)")},
+			certs:      warn,
+			want:       ExitConnectionFailure,
+		},
+		{
+			name:       "unreachable_targets_and_crit",
+			targetsErr: &ExitError{Code: ExitConnectionFailure, Err: errors.New("there was 1 error(s)")},
+			certs:      crit,
+			want:       ExitFailure,
+		},
+		{
+			name:       "failed_targets_and_warn",
+			targetsErr: &ExitError{Code: ExitPartialFailure, Err: errors.New("there was 1 error(s)")},
+			certs:      warn,
+			want:       ExitPartialFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := certAuditError(tt.targetsErr, tt.certs)
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("certAuditError() exit code = %d, want %d: %v", got, tt.want, err)
+			}
+			if tt.targetsErr != nil && !strings.Contains(err.Error(), tt.targetsErr.Error()) {
+				t.Errorf("certAuditError() = %v, lost the targets error", err)
+			}
+		})
+	}
+}
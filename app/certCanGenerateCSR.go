@@ -11,7 +11,6 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type certCGCSRResponse struct {
@@ -19,6 +18,8 @@ type certCGCSRResponse struct {
 	can bool
 }
 
+func (r *certCGCSRResponse) response() interface{} { return map[string]bool{"can_generate": r.can} }
+
 func (a *App) InitCertCanGenerateCSRFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -40,50 +41,46 @@ func (a *App) RunECertCanGenerateCSR(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *certCGCSRResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &certCGCSRResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			can, err := a.CertCanGenerateCSR(ctx, t)
-			responseChan <- &certCGCSRResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certCGCSRResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				can: can,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		can, err := a.CertCanGenerateCSR(ctx, t)
+		return sendResponse(responseChan, &certCGCSRResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			can: can,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*certCGCSRResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert CanGenerateCSR failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, rsp.Err)
 			continue
 		}
 		result = append(result, rsp)
 	}
-	fmt.Print(certCGCSRTable(result))
+	if a.textOutput() {
+		fmt.Print(certCGCSRTable(result))
+	}
 	return a.handleErrs(errs)
 }
 
@@ -105,7 +102,7 @@ func (a *App) CertCanGenerateCSR(ctx context.Context, t *api.Target) (bool, erro
 	}
 
 	a.printMsg(t.Config.Name, resp)
-	a.Logger.Infof("%q key-type=%s, cert-type=%s, key-size=%d: can_generate: %v",
+	a.targetLogger(t.Config.Name).Infof("%q key-type=%s, cert-type=%s, key-size=%d: can_generate: %v",
 		t.Config.Address,
 		a.Config.CertCanGenerateCSRKeyType,
 		a.Config.CertCanGenerateCSRCertificateType,
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/karimra/gnoic/api"
 	gcert "github.com/karimra/gnoic/api/cert"
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type certGenCSRResponse struct {
@@ -19,6 +19,8 @@ type certGenCSRResponse struct {
 	rsp *cert.GenerateCSRResponse
 }
 
+func (r *certGenCSRResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitCertGenerateCSRFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -33,7 +35,10 @@ func (a *App) InitCertGenerateCSRFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&a.Config.CertGenerateCSRCity, "city", "", "CSR city")
 	cmd.Flags().StringVar(&a.Config.CertGenerateCSROrg, "org", "", "CSR organization")
 	cmd.Flags().StringVar(&a.Config.CertGenerateCSROrgUnit, "org-unit", "", "CSR organization unit")
-	cmd.Flags().StringVar(&a.Config.CertGenerateCSRIPAddress, "ip-address", "", "CSR IP address")
+	cmd.Flags().StringSliceVar(&a.Config.CertGenerateCSRIPAddress, "ip-address", nil, "CSR IP address, only the first one is sent to the target")
+	cmd.Flags().StringSliceVar(&a.Config.CertGenerateCSRDNSName, "dns-name", nil, "CSR DNS name SANs, not supported by the target CSR parameters")
+	cmd.Flags().StringSliceVar(&a.Config.CertGenerateCSRURI, "uri", nil, "CSR URI SANs, not supported by the target CSR parameters")
+	cmd.Flags().StringSliceVar(&a.Config.CertGenerateCSRExtKeyUsage, "ext-key-usage", nil, "CSR extended key usages, not supported by the target CSR parameters")
 	cmd.Flags().StringVar(&a.Config.CertGenerateCSREmailID, "email-id", "", "CSR email ID")
 	//
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
@@ -49,44 +54,38 @@ func (a *App) RunEGenerateCSR(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *certGenCSRResponse, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &certGenCSRResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.CertGenerateCSR(ctx, t)
-			responseChan <- &certGenCSRResponse{
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certGenCSRResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.CertGenerateCSR(ctx, t)
+		return sendResponse(responseChan, &certGenCSRResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*certGenCSRResponse, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert CanGenerateCSR failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -102,21 +101,33 @@ func (a *App) RunEGenerateCSR(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) CertGenerateCSR(ctx context.Context, t *api.Target) (*cert.GenerateCSRResponse, error) {
+	subject, err := (&certSubject{
+		commonName:   a.Config.CertGenerateCSRCommonName,
+		country:      a.Config.CertGenerateCSRCountry,
+		state:        a.Config.CertGenerateCSRState,
+		city:         a.Config.CertGenerateCSRCity,
+		org:          a.Config.CertGenerateCSROrg,
+		orgUnit:      a.Config.CertGenerateCSROrgUnit,
+		emailID:      a.Config.CertGenerateCSREmailID,
+		dnsNames:     a.Config.CertGenerateCSRDNSName,
+		ipAddresses:  a.Config.CertGenerateCSRIPAddress,
+		uris:         a.Config.CertGenerateCSRURI,
+		extKeyUsages: a.Config.CertGenerateCSRExtKeyUsage,
+	}).execute(t)
+	if err != nil {
+		return nil, err
+	}
+	if dropped := csrParamsDropped(subject); len(dropped) > 0 {
+		a.targetLogger(t.Config.Name).Warnf("%q the CSR parameters cannot carry the %s, they are ignored",
+			t.Config.Address, strings.Join(dropped, ", "))
+	}
 	req, err := gcert.NewCertGenerateCSRRequest(
 		gcert.CertificateID(a.Config.CertGenerateCSRCertificateID),
-		gcert.CSRParams(
+		gcert.CSRParams(append([]gcert.CertOption{
 			gcert.CertificateType(a.Config.CertGenerateCSRCertificateType),
 			gcert.MinKeySize(a.Config.CertGenerateCSRMinKeySize),
 			gcert.KeyType(a.Config.CertGenerateCSRKeyType),
-			gcert.CommonName(a.Config.CertGenerateCSRCommonName),
-			gcert.Country(a.Config.CertGenerateCSRCountry),
-			gcert.State(a.Config.CertGenerateCSRState),
-			gcert.City(a.Config.CertGenerateCSRCity),
-			gcert.Org(a.Config.CertGenerateCSROrg),
-			gcert.OrgUnit(a.Config.CertGenerateCSROrgUnit),
-			gcert.IPAddress(a.Config.CertGenerateCSRIPAddress),
-			gcert.EmailID(a.Config.CertGenerateCSREmailID),
-		),
+		}, csrParamsOptions(subject)...)...),
 	)
 	if err != nil {
 		return nil, err
@@ -143,13 +154,13 @@ func (a *App) saveCSR(rsp *certGenCSRResponse) error {
 	}
 	f, err := os.Create(filepath.Join(rsp.TargetName, certId+".csr"))
 	if err != nil {
-		a.Logger.Warnf("%q cert=%q failed to create file: %v", rsp.TargetName, certId, err)
+		a.targetLogger(rsp.TargetName).Warnf("%q cert=%q failed to create file: %v", rsp.TargetName, certId, err)
 		return err
 	}
 	defer f.Close()
 	_, err = f.Write(rsp.rsp.GetCsr().GetCsr())
 	if err != nil {
-		a.Logger.Warnf("%q cert=%q failed to write certificate file: %v", rsp.TargetName, certId, err)
+		a.targetLogger(rsp.TargetName).Warnf("%q cert=%q failed to write certificate file: %v", rsp.TargetName, certId, err)
 		return err
 	}
 	return nil
@@ -18,7 +18,6 @@ import (
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type getCertificatesResponse struct {
@@ -26,6 +25,8 @@ type getCertificatesResponse struct {
 	rsp *cert.GetCertificatesResponse
 }
 
+func (r *getCertificatesResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitCertGetCertificatesFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -47,46 +48,40 @@ func (a *App) RunECertGetCertificates(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *getCertificatesResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &getCertificatesResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			a.Logger.Debugf("%q gRPC client created", t.Config.Address)
-			rsp, err := a.CertGetCertificates(ctx, t)
-			responseChan <- &getCertificatesResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &getCertificatesResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		a.targetLogger(t.Config.Name).Debugf("%q gRPC client created", t.Config.Address)
+		rsp, err := a.CertGetCertificates(ctx, t)
+		return sendResponse(responseChan, &getCertificatesResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*getCertificatesResponse, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert GetCertificates failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -96,6 +91,9 @@ func (a *App) RunECertGetCertificates(cmd *cobra.Command, args []string) error {
 		result = append(result, rsp)
 	}
 	//
+	if !a.textOutput() {
+		return a.handleErrs(errs)
+	}
 	if a.Config.CertGetCertificatesDetails {
 		if len(result) == 0 {
 			a.Logger.Warn("no certificates found")
@@ -103,7 +101,7 @@ func (a *App) RunECertGetCertificates(cmd *cobra.Command, args []string) error {
 		}
 		for _, rsp := range result {
 			if rsp.rsp == nil || len(rsp.rsp.GetCertificateInfo()) == 0 {
-				a.Logger.Warnf("%q no certificates found", rsp.TargetName)
+				a.targetLogger(rsp.TargetName).Warnf("%q no certificates found", rsp.TargetName)
 				continue
 			}
 			for _, certInfo := range rsp.rsp.GetCertificateInfo() {
@@ -198,7 +196,7 @@ func (a *App) saveCerts(rsp *getCertificatesResponse) {
 		os.Mkdir(rsp.TargetName, os.ModeDir)
 	}
 	if rsp.rsp == nil || len(rsp.rsp.GetCertificateInfo()) == 0 {
-		a.Logger.Warnf("%q no certificates found", rsp.TargetName)
+		a.targetLogger(rsp.TargetName).Warnf("%q no certificates found", rsp.TargetName)
 		return
 	}
 	for _, certInfo := range rsp.rsp.GetCertificateInfo() {
@@ -208,12 +206,12 @@ func (a *App) saveCerts(rsp *getCertificatesResponse) {
 		}
 		f, err := os.Create(filepath.Join(rsp.TargetName, certInfo.CertificateId+".pem"))
 		if err != nil {
-			a.Logger.Warnf("%q cert=%q failed to create file: %v", rsp.TargetName, certInfo.CertificateId, err)
+			a.targetLogger(rsp.TargetName).Warnf("%q cert=%q failed to create file: %v", rsp.TargetName, certInfo.CertificateId, err)
 			continue
 		}
 		_, err = f.Write(certInfo.GetCertificate().GetCertificate())
 		if err != nil {
-			a.Logger.Warnf("%q cert=%q failed to write certificate file: %v", rsp.TargetName, certInfo.CertificateId, err)
+			a.targetLogger(rsp.TargetName).Warnf("%q cert=%q failed to write certificate file: %v", rsp.TargetName, certInfo.CertificateId, err)
 		}
 		f.Close()
 	}
@@ -2,16 +2,11 @@ package app
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/tls"
 	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/asn1"
 	"encoding/pem"
-	"errors"
 	"fmt"
-	"net"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/karimra/gnoic/api"
@@ -19,7 +14,6 @@ import (
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -36,7 +30,10 @@ func (a *App) InitCertInstallFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&a.Config.CertInstallCity, "city", "", "CSR city")
 	cmd.Flags().StringVar(&a.Config.CertInstallOrg, "org", "", "CSR organization")
 	cmd.Flags().StringVar(&a.Config.CertInstallOrgUnit, "org-unit", "", "CSR organization unit")
-	cmd.Flags().StringVar(&a.Config.CertInstallIPAddress, "ip-address", "", "CSR IP address")
+	cmd.Flags().StringSliceVar(&a.Config.CertInstallIPAddress, "ip-address", nil, "CSR IP address SANs, IPv4 or IPv6")
+	cmd.Flags().StringSliceVar(&a.Config.CertInstallDNSName, "dns-name", nil, "CSR DNS name SANs")
+	cmd.Flags().StringSliceVar(&a.Config.CertInstallURI, "uri", nil, "CSR URI SANs, e.g: a SPIFFE ID")
+	cmd.Flags().StringSliceVar(&a.Config.CertInstallExtKeyUsage, "ext-key-usage", nil, fmt.Sprintf("certificate extended key usages, one of %q", extKeyUsageNames()))
 	cmd.Flags().StringVar(&a.Config.CertInstallEmailID, "email-id", "", "CSR email ID")
 	cmd.Flags().DurationVar(&a.Config.CertInstallValidity, "validity", 10*365*24*time.Hour, "certificate validity")
 	cmd.Flags().BoolVar(&a.Config.CertInstallPrintCSR, "print-csr", false, "print the generated Certificate Signing Request")
@@ -47,21 +44,28 @@ func (a *App) InitCertInstallFlags(cmd *cobra.Command) {
 	})
 }
 
+// certInstallSubject returns the certificate subject set with the cert install flags.
+func (a *App) certInstallSubject() *certSubject {
+	return certSubject{
+		commonName:   a.Config.CertInstallCommonName,
+		country:      a.Config.CertInstallCountry,
+		state:        a.Config.CertInstallState,
+		city:         a.Config.CertInstallCity,
+		org:          a.Config.CertInstallOrg,
+		orgUnit:      a.Config.CertInstallOrgUnit,
+		emailID:      a.Config.CertInstallEmailID,
+		dnsNames:     a.Config.CertInstallDNSName,
+		ipAddresses:  a.Config.CertInstallIPAddress,
+		uris:         a.Config.CertInstallURI,
+		extKeyUsages: a.Config.CertInstallExtKeyUsage,
+	}.withTargetDefaults()
+}
+
 func (a *App) RunECertInstall(cmd *cobra.Command, args []string) error {
 	var err error
-	if a.Config.CertCACert != "" && a.Config.CertCAKey != "" {
-		caCert, err = tls.LoadX509KeyPair(a.Config.CertCACert, a.Config.CertCAKey)
-		if err != nil {
-			return err
-		}
-		if len(caCert.Certificate) != 1 {
-			return errors.New("CA cert and key contains 0 or more than 1 certificate")
-		}
-		c, err := x509.ParseCertificate(caCert.Certificate[0])
-		if c != nil && err == nil {
-			caCert.Leaf = c
-		}
-		a.Logger.Infof("read local CA certs")
+	a.signer, err = a.newSigner(a.commandContext(cmd))
+	if err != nil {
+		return err
 	}
 	targets, err := a.GetTargets()
 	if err != nil {
@@ -71,38 +75,32 @@ func (a *App) RunECertInstall(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *TargetError, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
-					TargetName: t.Config.Address,
-					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err = a.CertInstall(ctx, t)
-			responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		err = a.CertInstall(ctx, t)
+		return sendResponse(responseChan, &TargetError{
+			TargetName: t.Config.Address,
+			Err:        err,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, len(targets))
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert Install failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -111,6 +109,10 @@ func (a *App) RunECertInstall(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) CertInstall(ctx context.Context, t *api.Target) error {
+	subject, err := a.certInstallSubject().execute(t)
+	if err != nil {
+		return fmt.Errorf("%q %v", t.Config.Address, err)
+	}
 	// create cert mgmt install stream RPC
 	stream, err := t.CertClient().Install(ctx)
 	if err != nil {
@@ -141,9 +143,9 @@ func (a *App) CertInstall(ctx context.Context, t *api.Target) error {
 	var creq *x509.CertificateRequest
 
 	if a.Config.CertInstallGenCSR {
-		keyPair, creq, err = a.createLocalCSRInstall(t)
+		keyPair, creq, err = createLocalCSR(subject, a.Config.CertInstallKeyType, int(a.Config.CertInstallMinKeySize))
 	} else {
-		creq, err = a.createRemoteCSRInstall(stream, t)
+		creq, err = a.createRemoteCSRInstall(stream, t, subject)
 	}
 	if err != nil {
 		return err
@@ -154,33 +156,53 @@ func (a *App) CertInstall(ctx context.Context, t *api.Target) error {
 		return err
 	}
 	if a.Config.CertInstallPrintCSR {
-		fmt.Printf("%q generated CSR:\n%s\n", t.Config.Address, s)
+		fmt.Fprintf(os.Stderr, "%q generated CSR:\n%s\n", t.Config.Address, s)
 	}
-	a.Logger.Debugf("%q generated CSR:\n%s\n", t.Config.Address, s)
+	a.targetLogger(t.Config.Name).Debugf("%q generated CSR:\n%s\n", t.Config.Address, s)
 
+	loadCertReq, err := a.certInstallLoadCertificateRequest(ctx, t, subject, keyPair, creq)
+	if err != nil {
+		return err
+	}
+	err = stream.Send(loadCertReq)
+	if err != nil {
+		return fmt.Errorf("%q failed sending InstallRequest: %v", t.Config.Address, err)
+	}
+	_, err = stream.Recv()
+	if err != nil {
+		return fmt.Errorf("%q InstallRequest RPC failed: %v", t.Config.Address, err)
+	}
+	a.rpcLogger(t.Config.Name, cert.CertificateManagement_Install_FullMethodName).Infof("%q Install RPC successful", t.Config.Address)
+	return nil
+}
+
+// certInstallLoadCertificateRequest signs a certificate for creq with the signer
+// and builds the Install request loading it.
+func (a *App) certInstallLoadCertificateRequest(ctx context.Context, t *api.Target, subject *certSubject, keyPair *cert.KeyPair, creq *x509.CertificateRequest) (*cert.InstallCertificateRequest, error) {
 	// create certificate from CSR
 	certificate, err := certificateFromCSR(creq, a.Config.CertInstallValidity)
 	if err != nil {
-		return fmt.Errorf("failed certificateFromCSR: %v", err)
+		return nil, fmt.Errorf("failed certificateFromCSR: %v", err)
 	}
+	subject.applyTo(certificate)
 	// sign certificate
-	a.Logger.Infof("%q signing certificate %q with the provided CA", t.Config.Address, certificate.Subject.String())
-	signedCert, err := a.sign(certificate, &caCert)
+	a.targetLogger(t.Config.Name).Infof("%q signing certificate %q with the %s signer", t.Config.Address, certificate.Subject.String(), a.signer)
+	signedCert, err := a.signer.sign(ctx, certificate, creq)
 	if err != nil {
-		return fmt.Errorf("%q failed signing certificate: %v", t.Config.Address, err)
+		return nil, fmt.Errorf("%q failed signing certificate: %v", t.Config.Address, err)
 	}
 	//
 	sCertText, err := CertificateText(signedCert, false)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.Logger.Debugf("%q signed certificate:\n%s\n", t.Config.Address, sCertText)
+	a.targetLogger(t.Config.Name).Debugf("%q signed certificate:\n%s\n", t.Config.Address, sCertText)
 	// encode signed certificate in PEM format
 	b, err := toPEM(signedCert)
 	if err != nil {
-		return fmt.Errorf("%q failed to encode as PEM: %v", t.Config.Address, err)
+		return nil, fmt.Errorf("%q failed to encode as PEM: %v", t.Config.Address, err)
 	}
-	a.Logger.Infof("%q installing certificate id=%s %q", t.Config.Address, a.Config.CertInstallCertificateID, certificate.Subject.String())
+	a.targetLogger(t.Config.Name).Infof("%q installing certificate id=%s %q", t.Config.Address, a.Config.CertInstallCertificateID, certificate.Subject.String())
 
 	// install certificate load certificate request options
 	opts := []gcert.CertOption{
@@ -199,127 +221,11 @@ func (a *App) CertInstall(ctx context.Context, t *api.Target) error {
 			gcert.CertificateID(a.Config.CertInstallCertificateID),
 		)
 	}
-	loadCertReq, err := gcert.NewCertInstallLoadCertificateRequest(opts...)
-	if err != nil {
-		return err
-	}
-	err = stream.Send(loadCertReq)
-	if err != nil {
-		return fmt.Errorf("%q failed sending InstallRequest: %v", t.Config.Address, err)
-	}
-	_, err = stream.Recv()
-	if err != nil {
-		return fmt.Errorf("%q InstallRequest RPC failed: %v", t.Config.Address, err)
-	}
-	a.Logger.Infof("%q Install RPC successful", t.Config.Address)
-	return nil
-}
-
-func (a *App) createLocalCSRInstall(t *api.Target) (*cert.KeyPair, *x509.CertificateRequest, error) {
-	var commonName = a.Config.CertInstallCommonName
-	var ipAddr = a.Config.CertInstallIPAddress
-	if commonName == "" {
-		commonName = t.Config.CommonName
-	}
-	if ipAddr == "" {
-		ipAddr = t.Config.ResolvedIP
-	}
-	privateKey, err := rsa.GenerateKey(rand.Reader, int(a.Config.CertInstallMinKeySize))
-	if err != nil {
-		return nil, nil, err
-	}
-
-	var subj pkix.Name
-	if commonName != "" {
-		subj.CommonName = commonName
-	}
-	if a.Config.CertInstallCountry != "" {
-		subj.Country = []string{a.Config.CertInstallCountry}
-	}
-	if a.Config.CertInstallState != "" {
-		subj.Province = []string{a.Config.CertInstallState}
-	}
-	if a.Config.CertInstallCity != "" {
-		subj.Locality = []string{a.Config.CertInstallCity}
-	}
-	if a.Config.CertInstallOrg != "" {
-		subj.Organization = []string{a.Config.CertInstallOrg}
-	}
-	if a.Config.CertInstallOrgUnit != "" {
-		subj.OrganizationalUnit = []string{a.Config.CertInstallOrgUnit}
-	}
-	if a.Config.CertInstallEmailID != "" {
-		subj.ExtraNames = append(subj.ExtraNames, pkix.AttributeTypeAndValue{
-			Type: oidEmailAddress,
-			Value: asn1.RawValue{
-				Tag:   asn1.TagIA5String,
-				Bytes: []byte(a.Config.CertInstallEmailID),
-			},
-		})
-	}
-
-	var ipAddrs net.IP
-	if ipAddr != "" {
-		ipAddrs = net.ParseIP(ipAddr)
-	}
-	template := x509.CertificateRequest{
-		Subject:            subj,
-		EmailAddresses:     []string{a.Config.CertInstallEmailID},
-		SignatureAlgorithm: x509.SHA256WithRSA,
-		IPAddresses:        make([]net.IP, 0),
-		DNSNames:           []string{commonName},
-	}
-
-	if ipAddrs != nil {
-		template.IPAddresses = append(template.IPAddresses, ipAddrs)
-	}
-
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create Certificate Request: %v", err)
-	}
-	creq, err := x509.ParseCertificateRequest(csrBytes)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed parsing certificate request: %v", err)
-	}
-	return &cert.KeyPair{
-			PrivateKey: pem.EncodeToMemory(&pem.Block{
-				Type:  "RSA PRIVATE KEY",
-				Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-			}),
-			PublicKey: csrBytes,
-		},
-		creq, err
+	return gcert.NewCertInstallLoadCertificateRequest(opts...)
 }
 
-func (a *App) createRemoteCSRInstall(stream cert.CertificateManagement_InstallClient, t *api.Target) (*x509.CertificateRequest, error) {
-	var commonName = a.Config.CertInstallCommonName
-	var ipAddr = a.Config.CertInstallIPAddress
-	if commonName == "" {
-		commonName = t.Config.CommonName
-	}
-	if ipAddr == "" {
-		ipAddr = t.Config.ResolvedIP
-	}
-	csrParamsOpts := []gcert.CertOption{
-		gcert.CertificateType(a.Config.CertInstallCertificateType),
-		gcert.MinKeySize(a.Config.CertInstallMinKeySize),
-		gcert.KeyType(a.Config.CertInstallKeyType),
-		gcert.CommonName(commonName),
-		gcert.Country(a.Config.CertInstallCountry),
-		gcert.State(a.Config.CertInstallState),
-		gcert.City(a.Config.CertInstallCity),
-		gcert.Org(a.Config.CertInstallOrg),
-		gcert.OrgUnit(a.Config.CertInstallOrgUnit),
-		gcert.IPAddress(ipAddr),
-	}
-	if a.Config.CertInstallEmailID != "" {
-		csrParamsOpts = append(csrParamsOpts, gcert.EmailID(a.Config.CertInstallEmailID))
-	}
-	req, err := gcert.NewCertInstallGenerateCSRRequest(
-		gcert.CertificateID(a.Config.CertInstallCertificateID),
-		gcert.CSRParams(csrParamsOpts...),
-	)
+func (a *App) createRemoteCSRInstall(stream cert.CertificateManagement_InstallClient, t *api.Target, subject *certSubject) (*x509.CertificateRequest, error) {
+	req, err := a.certInstallGenerateCSRRequest(t, subject)
 	if err != nil {
 		return nil, err
 	}
@@ -340,7 +246,7 @@ func (a *App) createRemoteCSRInstall(stream cert.CertificateManagement_InstallCl
 		a.printMsg(t.Config.Name, resp)
 	}
 	if a.Config.CertInstallPrintCSR {
-		fmt.Printf("%q genCSR response:\n %s\n", t.Config.Address, prototext.Format(resp))
+		fmt.Fprintf(os.Stderr, "%q genCSR response:\n %s\n", t.Config.Address, prototext.Format(resp))
 	}
 
 	p, rest := pem.Decode(resp.GetGeneratedCsr().GetCsr().GetCsr())
@@ -353,3 +259,73 @@ func (a *App) createRemoteCSRInstall(stream cert.CertificateManagement_InstallCl
 	}
 	return creq, nil
 }
+
+func (a *App) certInstallGenerateCSRRequest(t *api.Target, subject *certSubject) (*cert.InstallCertificateRequest, error) {
+	if dropped := csrParamsDropped(subject); len(dropped) > 0 {
+		a.targetLogger(t.Config.Name).Infof("%q the CSR parameters cannot carry the %s, they are set when signing the certificate",
+			t.Config.Address, strings.Join(dropped, ", "))
+	}
+	csrParamsOpts := append([]gcert.CertOption{
+		gcert.CertificateType(a.Config.CertInstallCertificateType),
+		gcert.MinKeySize(a.Config.CertInstallMinKeySize),
+		gcert.KeyType(a.Config.CertInstallKeyType),
+	}, csrParamsOptions(subject)...)
+	return gcert.NewCertInstallGenerateCSRRequest(
+		gcert.CertificateID(a.Config.CertInstallCertificateID),
+		gcert.CSRParams(csrParamsOpts...),
+	)
+}
+
+func (a *App) certInstallDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	subject, err := a.certInstallSubject().execute(t)
+	if err != nil {
+		return nil, err
+	}
+	if a.Config.CertInstallGenCSR && a.externalSigner() {
+		return []*dryRunRequest{{
+			RPC:  cert.CertificateManagement_Install_FullMethodName,
+			Note: fmt.Sprintf("load_certificate request with a locally generated key pair and a certificate signed by the %s signer", a.Config.CertSigner),
+		}}, nil
+	}
+	if a.Config.CertInstallGenCSR {
+		keyPair, creq, err := createLocalCSR(subject, a.Config.CertInstallKeyType, int(a.Config.CertInstallMinKeySize))
+		if err != nil {
+			return nil, err
+		}
+		req, err := a.certInstallLoadCertificateRequest(ctx, t, subject, keyPair, creq)
+		if err != nil {
+			return nil, err
+		}
+		return []*dryRunRequest{{
+			RPC:     cert.CertificateManagement_Install_FullMethodName,
+			Request: req,
+		}}, nil
+	}
+	cgcReq, err := gcert.NewCertCanGenerateCSRRequest(
+		gcert.CertificateType(a.Config.CertInstallCertificateType),
+		gcert.KeyType(a.Config.CertInstallKeyType),
+		gcert.KeySize(a.Config.CertInstallMinKeySize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req, err := a.certInstallGenerateCSRRequest(t, subject)
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{
+		{
+			RPC:     cert.CertificateManagement_CanGenerateCSR_FullMethodName,
+			Request: cgcReq,
+		},
+		{
+			RPC:     cert.CertificateManagement_Install_FullMethodName,
+			Request: req,
+			Note:    "if the target cannot generate the CSR, the key pair and CSR are generated locally instead",
+		},
+		{
+			RPC:  cert.CertificateManagement_Install_FullMethodName,
+			Note: fmt.Sprintf("load_certificate request with a certificate signed by the %s signer for the CSR returned by the target", a.Config.CertSigner),
+		},
+	}, nil
+}
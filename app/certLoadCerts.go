@@ -8,7 +8,6 @@ import (
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	gcert "github.com/karimra/gnoic/api/cert"
@@ -19,6 +18,8 @@ type certLoadCert struct {
 	rsp *cert.LoadCertificateResponse
 }
 
+func (r *certLoadCert) response() interface{} { return r.rsp }
+
 func (a *App) InitCertLoadCertsFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 
@@ -42,44 +43,38 @@ func (a *App) RunELoadCerts(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *certLoadCert, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &certLoadCert{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.CertLoadCertificate(ctx, t)
-			responseChan <- &certLoadCert{
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certLoadCert{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.CertLoadCertificate(ctx, t)
+		return sendResponse(responseChan, &certLoadCert{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	// result := make([]*certLoadCert, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert LoadCertificate failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
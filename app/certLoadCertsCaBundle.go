@@ -8,7 +8,6 @@ import (
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	gcert "github.com/karimra/gnoic/api/cert"
@@ -19,6 +18,8 @@ type certLoadCABundle struct {
 	rsp *cert.LoadCertificateAuthorityBundleResponse
 }
 
+func (r *certLoadCABundle) response() interface{} { return r.rsp }
+
 func (a *App) InitCertLoadCertsCaBundleFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 
@@ -37,44 +38,38 @@ func (a *App) RunELoadCertsCaBundle(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *certLoadCABundle, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &certLoadCABundle{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.CertLoadCABundle(ctx, t)
-			responseChan <- &certLoadCABundle{
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certLoadCABundle{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.CertLoadCABundle(ctx, t)
+		return sendResponse(responseChan, &certLoadCABundle{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	// result := make([]*certLoadCABundle, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert LoadCA Bundle failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
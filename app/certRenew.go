@@ -0,0 +1,319 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/karimra/gnoic/api"
+)
+
+// certificate renewal actions
+const (
+	renewNone    = "none"
+	renewPending = "pending"
+	renewRenewed = "renewed"
+	renewFailed  = "failed"
+	renewSkipped = "skipped"
+)
+
+// certRenewal is the renewal result of a certificate,
+// the audit findings are the reasons for renewing it.
+type certRenewal struct {
+	*certAudit
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type certRenewResponse struct {
+	TargetError
+	renewals []*certRenewal
+
+	target *api.Target
+}
+
+func (r *certRenewResponse) response() interface{} { return r.renewals }
+
+func (a *App) InitCertRenewFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	//
+	cmd.Flags().StringSliceVar(&a.Config.CertRenewID, "id", []string{}, "certificate ID to renew, all certificates if not set")
+	cmd.Flags().StringVar(&a.Config.CertRenewWindow, "window", "30d", "renew the certificates expiring within this duration, e.g: 30d, 720h")
+	cmd.Flags().IntVar(&a.Config.CertRenewMinKeySize, "min-key-size", 2048, "renew the certificates with an RSA key smaller than this size, and the new RSA keys size")
+	cmd.Flags().StringVar(&a.Config.CertRenewKeyType, "key-type", "", "new keys type, KT_RSA or KT_ECDSA, defaults to the key type of the renewed certificate")
+	cmd.Flags().DurationVar(&a.Config.CertRenewValidity, "validity", 0, "new certificates validity, defaults to the validity of the renewed certificate")
+	cmd.Flags().BoolVar(&a.Config.CertRenewGenCSR, "gen-csr", false, "generate the Certificate Signing Requests locally")
+	cmd.Flags().BoolVar(&a.Config.CertRenewPrintCSR, "print-csr", false, "print the generated Certificate Signing Requests")
+	cmd.Flags().BoolVar(&a.Config.CertRenewSkipValidation, "skip-validation", false, "finalize the rotations without validating the new certificates over a new TLS connection")
+	cmd.Flags().DurationVar(&a.Config.CertRenewValidationTimeout, "validation-timeout", 10*time.Second, "time given to the targets to present the new certificates")
+	//
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) PreRunECertRenew(cmd *cobra.Command, args []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	if _, err := parseDays(a.Config.CertRenewWindow); err != nil {
+		return fmt.Errorf("invalid --window: %v", err)
+	}
+	return nil
+}
+
+func (a *App) RunECertRenew(cmd *cobra.Command, args []string) error {
+	var err error
+	a.signer, err = a.newSigner(a.commandContext(cmd))
+	if err != nil {
+		return err
+	}
+	window, _ := parseDays(a.Config.CertRenewWindow)
+
+	targets, err := a.GetTargets()
+	if err != nil {
+		return err
+	}
+
+	// fetch and audit the certificates of all the targets,
+	// so that serial numbers and keys shared by several targets are found.
+	numTargets := len(targets)
+	responseChan := make(chan *certRenewResponse, numTargets)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certRenewResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Name,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		certs, err := a.CertAudit(ctx, t, a.Config.CertRenewID)
+		renewals := make([]*certRenewal, 0, len(certs))
+		for _, c := range certs {
+			renewals = append(renewals, &certRenewal{certAudit: c, Action: renewNone})
+		}
+		return sendResponse(responseChan, &certRenewResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			renewals: renewals,
+			target:   t,
+		})
+	})
+	close(responseChan)
+
+	rsps := make([]*certRenewResponse, 0, numTargets)
+	all := make([]*certAudit, 0)
+	for rsp := range responseChan {
+		rsps = append(rsps, rsp)
+		for _, r := range rsp.renewals {
+			all = append(all, r.certAudit)
+		}
+	}
+	auditCertificates(all, a.signer.ca(), a.Config.CertRenewMinKeySize, window, window, time.Now())
+
+	// rotate the certificates to renew, using the rolling execution flags.
+	renewTargets := make(map[string]*api.Target)
+	byName := make(map[string]*certRenewResponse)
+	for _, rsp := range rsps {
+		if rsp.Err != nil {
+			continue
+		}
+		for _, r := range rsp.renewals {
+			if r.Status == auditOK {
+				continue
+			}
+			if r.cert == nil {
+				r.Action = renewSkipped
+				r.Error = "invalid certificate, not renewed"
+				continue
+			}
+			r.Action = renewPending
+			renewTargets[rsp.TargetName] = rsp.target
+			byName[rsp.TargetName] = rsp
+		}
+	}
+	a.Logger.Infof("renewing certificates on %d target(s)", len(renewTargets))
+	a.runTargets(renewTargets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+
+		rsp := byName[t.Config.Name]
+		rsp.Err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if rsp.Err != nil {
+			return rsp.Err
+		}
+		defer t.Close()
+		rsp.Err = a.CertRenew(ctx, t, rsp.renewals)
+		return rsp.Err
+	})
+
+	skipped := make(map[string]struct{}, len(a.skipped))
+	for _, n := range a.skipped {
+		skipped[n] = struct{}{}
+	}
+	errs := make([]error, 0, numTargets)
+	renewals := make([]*certRenewal, 0, len(all))
+	for _, rsp := range rsps {
+		for _, r := range rsp.renewals {
+			if r.Action == renewPending {
+				r.Action = renewSkipped
+			}
+			renewals = append(renewals, r)
+		}
+		if _, ok := skipped[rsp.TargetName]; ok {
+			continue
+		}
+		a.addResult(rsp)
+		if rsp.Err != nil {
+			wErr := fmt.Errorf("%q Cert Renew failed: %v", rsp.TargetName, rsp.Err)
+			a.targetLogger(rsp.TargetName).Error(wErr)
+			errs = append(errs, wErr)
+		}
+	}
+	if a.textOutput() {
+		fmt.Print(certRenewTable(renewals))
+	}
+	return a.handleErrs(errs)
+}
+
+// CertRenew rotates the pending renewals of target t, in order.
+// It stops at the first failure, leaving the remaining ones pending.
+func (a *App) CertRenew(ctx context.Context, t *api.Target, renewals []*certRenewal) error {
+	for _, r := range renewals {
+		if r.Action != renewPending {
+			continue
+		}
+		a.targetLogger(t.Config.Name).Infof("%q renewing certificate id=%s: %s", t.Config.Address, r.ID, strings.Join(r.Findings, ", "))
+		_, err := a.CertRotate(ctx, t, a.certRenewParams(r.ID, r.cert))
+		if err != nil {
+			r.Action = renewFailed
+			r.Error = err.Error()
+			return fmt.Errorf("certificate id=%s: %v", r.ID, err)
+		}
+		r.Action = renewRenewed
+	}
+	return nil
+}
+
+// certRenewParams returns the parameters rotating certificate c with ID id,
+// keeping its subject and SANs.
+func (a *App) certRenewParams(id string, c *x509.Certificate) *certRotateParams {
+	validity := a.Config.CertRenewValidity
+	if validity <= 0 {
+		validity = c.NotAfter.Sub(c.NotBefore)
+	}
+	subject := &certSubject{
+		commonName:  c.Subject.CommonName,
+		country:     firstString(c.Subject.Country),
+		state:       firstString(c.Subject.Province),
+		city:        firstString(c.Subject.Locality),
+		org:         firstString(c.Subject.Organization),
+		orgUnit:     firstString(c.Subject.OrganizationalUnit),
+		emailID:     firstString(c.EmailAddresses),
+		dnsNames:    c.DNSNames,
+		ipAddresses: ipStrings(c.IPAddresses),
+		emails:      c.EmailAddresses,
+	}
+	for _, u := range c.URIs {
+		subject.uris = append(subject.uris, u.String())
+	}
+	for _, eku := range c.ExtKeyUsage {
+		for _, n := range extKeyUsageNames() {
+			if extKeyUsages[n].usage == eku {
+				subject.extKeyUsages = append(subject.extKeyUsages, n)
+			}
+		}
+	}
+	keyType, keySize := renewKeyType(c, a.Config.CertRenewKeyType, a.Config.CertRenewMinKeySize)
+	return &certRotateParams{
+		id:         id,
+		certType:   "CT_X509",
+		keyType:    keyType,
+		minKeySize: keySize,
+		subject:    subject,
+		validity:   validity,
+		genCSR:     a.Config.CertRenewGenCSR,
+		printCSR:   a.Config.CertRenewPrintCSR,
+
+		skipValidation:    a.Config.CertRenewSkipValidation,
+		validationTimeout: a.Config.CertRenewValidationTimeout,
+	}
+}
+
+// renewKeyType returns the type and size of the key renewing certificate c.
+// The key type defaults to the type of the key of c,
+// ECDSA keys keep the curve of c, RSA keys are keySize bits long.
+func renewKeyType(c *x509.Certificate, keyType string, keySize int) (string, uint32) {
+	if keyType == "" {
+		keyType = "KT_RSA"
+		if c.PublicKeyAlgorithm == x509.ECDSA {
+			keyType = keyTypeECDSA
+		}
+	}
+	if keyType != keyTypeECDSA {
+		return keyType, uint32(keySize)
+	}
+	if pub, ok := c.PublicKey.(*ecdsa.PublicKey); ok {
+		return keyType, uint32(pub.Curve.Params().BitSize)
+	}
+	return keyType, 256
+}
+
+func firstString(ss []string) string {
+	if len(ss) == 0 {
+		return ""
+	}
+	return ss[0]
+}
+
+func certRenewTable(renewals []*certRenewal) string {
+	sort.Slice(renewals, func(i, j int) bool {
+		if renewals[i].Target == renewals[j].Target {
+			return renewals[i].ID < renewals[j].ID
+		}
+		return renewals[i].Target < renewals[j].Target
+	})
+	tabData := make([][]string, 0, len(renewals))
+	for _, r := range renewals {
+		notAfter := ""
+		if !r.NotAfter.IsZero() {
+			notAfter = r.NotAfter.Format(time.RFC3339)
+		}
+		action := r.Action
+		if r.Error != "" {
+			action = fmt.Sprintf("%s: %s", action, r.Error)
+		}
+		tabData = append(tabData, []string{
+			r.Target,
+			r.ID,
+			r.Subject,
+			notAfter,
+			strconv.Itoa(r.DaysLeft),
+			strings.Join(r.Findings, "\n"),
+			action,
+		})
+	}
+	b := new(bytes.Buffer)
+	table := tablewriter.NewWriter(b)
+	table.SetHeader([]string{"Target Name", "ID", "Subject", "Valid Until", "Days Left", "Reasons", "Action"})
+	formatTable(table)
+	table.AppendBulk(tabData)
+	table.Render()
+	return b.String()
+}
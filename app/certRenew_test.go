@@ -0,0 +1,228 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnoic/api"
+	"github.com/karimra/gnoic/config"
+)
+
+func Test_certRenewParams(t *testing.T) {
+	newKey := func() *rsa.PrivateKey {
+		k, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	create := func(tmpl, parent *x509.Certificate, pub, priv interface{}) *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	now := time.Now()
+	caKey := newKey()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	ca := create(caTmpl, caTmpl, &caKey.PublicKey, caKey)
+
+	oldKey := newKey()
+	old := create(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName:   "r1.example.net",
+			Organization: []string{"example"},
+			Country:      []string{"FR"},
+		},
+		NotBefore:   now.Add(-335 * 24 * time.Hour),
+		NotAfter:    now.Add(30 * 24 * time.Hour),
+		DNSNames:    []string{"r1.example.net", "r1"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1").To4(), net.ParseIP("2001:db8::1")},
+		URIs:        []*url.URL{{Scheme: "spiffe", Host: "example.net", Path: "/r1"}},
+	}, ca, &oldKey.PublicKey, caKey)
+
+	a := New()
+	a.signer = &localSigner{caCert: &tls.Certificate{Certificate: [][]byte{ca.Raw}, PrivateKey: caKey, Leaf: ca}}
+	a.Config.CertRenewMinKeySize = 2048
+	tg := api.NewTargetFromConfig(&config.TargetConfig{Name: "r1", Address: "192.0.2.1:57400"})
+
+	p, err := a.certRenewParams("gnmi", old).forTarget(tg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.subject.commonName != "r1.example.net" || p.subject.org != "example" || p.subject.country != "FR" {
+		t.Errorf("unexpected subject: %+v", p.subject)
+	}
+	keyPair, creq, err := createLocalCSR(p.subject, p.keyType, int(p.minKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _, err := a.certRotateLoadCertificateRequest(a.ctx, tg, p, keyPair, creq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc := req.GetLoadCertificate()
+	if lc.GetCertificateId() != "gnmi" {
+		t.Errorf("certificate ID = %q, want gnmi", lc.GetCertificateId())
+	}
+	if len(lc.GetKeyPair().GetPrivateKey()) == 0 {
+		t.Errorf("missing locally generated key pair")
+	}
+	block, _ := pem.Decode(lc.GetCertificate().GetCertificate())
+	if block == nil {
+		t.Fatal("no PEM certificate in the load request")
+	}
+	renewed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renewed.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("renewed certificate not signed by the CA: %v", err)
+	}
+	if renewed.Subject.String() != old.Subject.String() {
+		t.Errorf("subject = %q, want %q", renewed.Subject, old.Subject)
+	}
+	if !reflect.DeepEqual(renewed.DNSNames, old.DNSNames) ||
+		!reflect.DeepEqual(renewed.IPAddresses, old.IPAddresses) ||
+		!reflect.DeepEqual(renewed.URIs, old.URIs) {
+		t.Errorf("SANs = %v %v %v, want %v %v %v",
+			renewed.DNSNames, renewed.IPAddresses, renewed.URIs,
+			old.DNSNames, old.IPAddresses, old.URIs)
+	}
+	if got, want := renewed.NotAfter.Sub(now), old.NotAfter.Sub(old.NotBefore); got < want-time.Minute || got > want+time.Minute {
+		t.Errorf("validity = %v, want %v", got, want)
+	}
+	if renewed.SerialNumber.Cmp(old.SerialNumber) == 0 {
+		t.Errorf("renewed certificate reuses the serial number")
+	}
+}
+
+func Test_certRenewParamsKeyType(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(10 * 365 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		pub         interface{}
+		keyType     string
+		wantKeyType string
+		wantKeySize uint32
+		wantAlgo    x509.PublicKeyAlgorithm
+	}{
+		{name: "ecdsa", pub: &ecKey.PublicKey, wantKeyType: keyTypeECDSA, wantKeySize: 384, wantAlgo: x509.ECDSA},
+		{name: "ecdsa_to_rsa", pub: &ecKey.PublicKey, keyType: "KT_RSA", wantKeyType: "KT_RSA", wantKeySize: 2048, wantAlgo: x509.RSA},
+		{name: "rsa", pub: &rsaKey.PublicKey, wantKeyType: "KT_RSA", wantKeySize: 2048, wantAlgo: x509.RSA},
+		{name: "rsa_to_ecdsa", pub: &rsaKey.PublicKey, keyType: keyTypeECDSA, wantKeyType: keyTypeECDSA, wantKeySize: 256, wantAlgo: x509.ECDSA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				Subject:      pkix.Name{CommonName: "r1.example.net"},
+				NotBefore:    now.Add(-time.Hour),
+				NotAfter:     now.Add(24 * time.Hour),
+			}, ca, tt.pub, caKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old, err := x509.ParseCertificate(der)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := New()
+			a.signer = &localSigner{caCert: &tls.Certificate{Certificate: [][]byte{ca.Raw}, PrivateKey: caKey, Leaf: ca}}
+			a.Config.CertRenewKeyType = tt.keyType
+			a.Config.CertRenewMinKeySize = 2048
+			tg := api.NewTargetFromConfig(&config.TargetConfig{Name: "r1", Address: "192.0.2.1:57400"})
+
+			p, err := a.certRenewParams("gnmi", old).forTarget(tg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.keyType != tt.wantKeyType || p.minKeySize != tt.wantKeySize {
+				t.Fatalf("key = %s %d, want %s %d", p.keyType, p.minKeySize, tt.wantKeyType, tt.wantKeySize)
+			}
+			if tt.wantKeyType == keyTypeECDSA && !p.localCSR() {
+				t.Errorf("ECDSA keys are not generated locally")
+			}
+			keyPair, creq, err := createLocalCSR(p.subject, p.keyType, int(p.minKeySize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _, err := a.certRotateLoadCertificateRequest(a.ctx, tg, p, keyPair, creq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			block, _ := pem.Decode(req.GetLoadCertificate().GetCertificate().GetCertificate())
+			if block == nil {
+				t.Fatal("no PEM certificate in the load request")
+			}
+			renewed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if renewed.PublicKeyAlgorithm != tt.wantAlgo {
+				t.Errorf("public key algorithm = %v, want %v", renewed.PublicKeyAlgorithm, tt.wantAlgo)
+			}
+			if pub, ok := renewed.PublicKey.(*ecdsa.PublicKey); ok && uint32(pub.Curve.Params().BitSize) != tt.wantKeySize {
+				t.Errorf("curve size = %d, want %d", pub.Curve.Params().BitSize, tt.wantKeySize)
+			}
+			if _, err := tls.X509KeyPair(pem.EncodeToMemory(block), req.GetLoadCertificate().GetKeyPair().GetPrivateKey()); err != nil {
+				t.Errorf("key pair does not match the renewed certificate: %v", err)
+			}
+		})
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 func (a *App) InitCertRevokeCertificatesFlags(cmd *cobra.Command) {
@@ -36,37 +35,31 @@ func (a *App) RunECertRevokeCertificates(cmd *cobra.Command, args []string) erro
 	numTargets := len(targets)
 	responseChan := make(chan *TargetError, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
-					TargetName: t.Config.Address,
-					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err = a.Revoke(ctx, t)
-			responseChan <- &TargetError{
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		err = a.Revoke(ctx, t)
+		return sendResponse(responseChan, &TargetError{
+			TargetName: t.Config.Address,
+			Err:        err,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, len(targets))
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert Revoke failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -77,10 +70,7 @@ func (a *App) RunECertRevokeCertificates(cmd *cobra.Command, args []string) erro
 func (a *App) Revoke(ctx context.Context, t *api.Target) error {
 	certClient := t.CertClient()
 	//
-	opts := make([]gcert.CertOption, 0, len(a.Config.CertRevokeCertificatesCertificateID))
-	for _, cid := range a.Config.CertRevokeCertificatesCertificateID {
-		opts = append(opts, gcert.CertificateID(cid))
-	}
+	opts := a.certRevokeOptions()
 
 	if len(opts) == 0 && a.Config.CertRevokeCertificatesAll {
 		certResponse, err := certClient.GetCertificates(ctx, &cert.GetCertificatesRequest{})
@@ -105,11 +95,43 @@ func (a *App) Revoke(ctx context.Context, t *api.Target) error {
 	}
 	a.printMsg(t.Config.Name, resp)
 	for _, revokeErr := range resp.CertificateRevocationError {
-		a.Logger.Errorf("%q certificateID=%s revoke failed: %v\n", t.Config.Address, revokeErr.GetCertificateId(), revokeErr.GetErrorMessage())
+		a.targetLogger(t.Config.Name).Errorf("%q certificateID=%s revoke failed: %v\n", t.Config.Address, revokeErr.GetCertificateId(), revokeErr.GetErrorMessage())
 	}
 	for _, revoked := range resp.RevokedCertificateId {
-		a.Logger.Infof("%q certificateID=%s revoked successfully\n", t.Config.Address, revoked)
+		a.targetLogger(t.Config.Name).Infof("%q certificateID=%s revoked successfully\n", t.Config.Address, revoked)
 	}
 
 	return nil
 }
+
+func (a *App) certRevokeOptions() []gcert.CertOption {
+	opts := make([]gcert.CertOption, 0, len(a.Config.CertRevokeCertificatesCertificateID))
+	for _, cid := range a.Config.CertRevokeCertificatesCertificateID {
+		opts = append(opts, gcert.CertificateID(cid))
+	}
+	return opts
+}
+
+func (a *App) certRevokeDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	opts := a.certRevokeOptions()
+	if len(opts) == 0 && a.Config.CertRevokeCertificatesAll {
+		return []*dryRunRequest{
+			{
+				RPC:     cert.CertificateManagement_GetCertificates_FullMethodName,
+				Request: gcert.NewCertGetCertificatesRequest(),
+			},
+			{
+				RPC:  cert.CertificateManagement_RevokeCertificates_FullMethodName,
+				Note: "revokes all the certificate IDs returned by GetCertificates",
+			},
+		}, nil
+	}
+	req, err := gcert.NewCertRevokeCertificatesRequest(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{{
+		RPC:     cert.CertificateManagement_RevokeCertificates_FullMethodName,
+		Request: req,
+	}}, nil
+}
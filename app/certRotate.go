@@ -1,26 +1,25 @@
 package app
 
 import (
+	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/tls"
 	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/asn1"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
-	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/karimra/gnoic/api"
 	gcert "github.com/karimra/gnoic/api/cert"
+	"github.com/olekukonko/tablewriter"
 	"github.com/openconfig/gnoi/cert"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -37,31 +36,112 @@ func (a *App) InitCertRotateFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&a.Config.CertRotateCity, "city", "", "CSR city")
 	cmd.Flags().StringVar(&a.Config.CertRotateOrg, "org", "", "CSR organization")
 	cmd.Flags().StringVar(&a.Config.CertRotateOrgUnit, "org-unit", "", "CSR organization unit")
-	cmd.Flags().StringVar(&a.Config.CertRotateIPAddress, "ip-address", "", "CSR IP address")
+	cmd.Flags().StringSliceVar(&a.Config.CertRotateIPAddress, "ip-address", nil, "CSR IP address SANs, IPv4 or IPv6")
+	cmd.Flags().StringSliceVar(&a.Config.CertRotateDNSName, "dns-name", nil, "CSR DNS name SANs")
+	cmd.Flags().StringSliceVar(&a.Config.CertRotateURI, "uri", nil, "CSR URI SANs, e.g: a SPIFFE ID")
+	cmd.Flags().StringSliceVar(&a.Config.CertRotateExtKeyUsage, "ext-key-usage", nil, fmt.Sprintf("certificate extended key usages, one of %q", extKeyUsageNames()))
 	cmd.Flags().StringVar(&a.Config.CertRotateEmailID, "email-id", "", "CSR email ID")
 	cmd.Flags().DurationVar(&a.Config.CertRotateValidity, "validity", 87600*time.Hour, "Certificate validity")
 	cmd.Flags().BoolVar(&a.Config.CertRotatePrintCSR, "print-csr", false, "print the generated Certificate Signing Request")
 	cmd.Flags().BoolVar(&a.Config.CertRotateGenCSR, "gen-csr", false, "generate Certificate Signing Request locally")
+	cmd.Flags().BoolVar(&a.Config.CertRotateSkipValidation, "skip-validation", false, "finalize the rotation without validating the new certificate over a new TLS connection")
+	cmd.Flags().DurationVar(&a.Config.CertRotateValidationTimeout, "validation-timeout", 10*time.Second, "time given to the target to present the new certificate")
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
 	})
 }
 
+// certRotateParams are the parameters of a certificate rotation.
+type certRotateParams struct {
+	id         string
+	certType   string
+	keyType    string
+	minKeySize uint32
+	subject    *certSubject
+	validity   time.Duration
+	genCSR     bool
+	printCSR   bool
+	// validation of the loaded certificate before finalizing
+	skipValidation    bool
+	validationTimeout time.Duration
+}
+
+// rotated certificate validation results
+const (
+	validationPassed  = "passed"
+	validationFailed  = "failed"
+	validationSkipped = "skipped"
+)
+
+// certRotateResult is the outcome of a certificate rotation.
+type certRotateResult struct {
+	ID         string    `json:"id,omitempty"`
+	Serial     string    `json:"serial,omitempty"`
+	NotAfter   time.Time `json:"not-after,omitempty"`
+	Validation string    `json:"validation,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	Finalized  bool      `json:"finalized"`
+}
+
+type certRotateResponse struct {
+	TargetError
+	target string
+	rsp    *certRotateResult
+}
+
+func (r *certRotateResponse) response() interface{} { return r.rsp }
+
+// certRotateFlags returns the rotation parameters set with the cert rotate flags.
+func (a *App) certRotateFlags() *certRotateParams {
+	return &certRotateParams{
+		id:         a.Config.CertRotateCertificateID,
+		certType:   a.Config.CertRotateCertificateType,
+		keyType:    a.Config.CertRotateKeyType,
+		minKeySize: a.Config.CertRotateMinKeySize,
+		subject: certSubject{
+			commonName:   a.Config.CertRotateCommonName,
+			country:      a.Config.CertRotateCountry,
+			state:        a.Config.CertRotateState,
+			city:         a.Config.CertRotateCity,
+			org:          a.Config.CertRotateOrg,
+			orgUnit:      a.Config.CertRotateOrgUnit,
+			emailID:      a.Config.CertRotateEmailID,
+			dnsNames:     a.Config.CertRotateDNSName,
+			ipAddresses:  a.Config.CertRotateIPAddress,
+			uris:         a.Config.CertRotateURI,
+			extKeyUsages: a.Config.CertRotateExtKeyUsage,
+		}.withTargetDefaults(),
+		validity: a.Config.CertRotateValidity,
+		genCSR:   a.Config.CertRotateGenCSR,
+		printCSR: a.Config.CertRotatePrintCSR,
+
+		skipValidation:    a.Config.CertRotateSkipValidation,
+		validationTimeout: a.Config.CertRotateValidationTimeout,
+	}
+}
+
+// forTarget returns a copy of the parameters with the subject templates executed for target t.
+func (p *certRotateParams) forTarget(t *api.Target) (*certRotateParams, error) {
+	subject, err := p.subject.execute(t)
+	if err != nil {
+		return nil, err
+	}
+	np := *p
+	np.subject = subject
+	return &np, nil
+}
+
+// localCSR reports whether the key pair and CSR are generated locally,
+// either as requested or because the target cannot be asked for an ECDSA key.
+func (p *certRotateParams) localCSR() bool {
+	return p.genCSR || p.keyType == keyTypeECDSA
+}
+
 func (a *App) RunECertRotate(cmd *cobra.Command, args []string) error {
 	var err error
-	if a.Config.CertCACert != "" && a.Config.CertCAKey != "" {
-		caCert, err = tls.LoadX509KeyPair(a.Config.CertCACert, a.Config.CertCAKey)
-		if err != nil {
-			return err
-		}
-		if len(caCert.Certificate) != 1 {
-			return errors.New("CA cert and key contains 0 or more than 1 certificate")
-		}
-		c, err := x509.ParseCertificate(caCert.Certificate[0])
-		if c != nil && err == nil {
-			caCert.Leaf = c
-		}
-		a.Logger.Infof("read local CA certs")
+	a.signer, err = a.newSigner(a.commandContext(cmd))
+	if err != nil {
+		return err
 	}
 
 	targets, err := a.GetTargets()
@@ -70,264 +150,267 @@ func (a *App) RunECertRotate(cmd *cobra.Command, args []string) error {
 	}
 
 	numTargets := len(targets)
-	responseChan := make(chan *TargetError, numTargets)
-
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
+	responseChan := make(chan *certRotateResponse, numTargets)
+
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &certRotateResponse{
+				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err = a.CertRotate(ctx, t)
-			responseChan <- &TargetError{
+				},
+				target: t.Config.Name,
+			})
+		}
+		defer t.Close()
+		rsp, err := a.CertRotate(ctx, t, a.certRotateFlags())
+		return sendResponse(responseChan, &certRotateResponse{
+			TargetError: TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			},
+			target: t.Config.Name,
+			rsp:    rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, len(targets))
+	results := make([]*certRotateResponse, 0, len(targets))
 	for rsp := range responseChan {
+		a.addResult(rsp)
+		if rsp.rsp != nil {
+			results = append(results, rsp)
+		}
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Cert Rotate failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 	}
+	if a.textOutput() && len(results) > 0 {
+		fmt.Print(certRotateTable(results))
+	}
 	return a.handleErrs(errs)
 }
 
-func (a *App) CertRotate(ctx context.Context, t *api.Target) error {
+// CertRotate rotates a certificate of target t.
+// The result is returned, along with the error, once a certificate was loaded.
+func (a *App) CertRotate(ctx context.Context, t *api.Target, p *certRotateParams) (*certRotateResult, error) {
+	p, err := p.forTarget(t)
+	if err != nil {
+		return nil, fmt.Errorf("%q %v", t.Config.Address, err)
+	}
+	// canceling the stream without a finalize request makes the target roll back
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	certClient := t.CertClient()
 	stream, err := certClient.Rotate(ctx)
 	if err != nil {
-		return fmt.Errorf("%q failed creating Rotate gRPC stream: %v", t.Config.Address, err)
+		return nil, fmt.Errorf("%q failed creating Rotate gRPC stream: %v", t.Config.Address, err)
 	}
-	if !a.Config.CertRotateGenCSR {
+	genCSR := p.localCSR()
+	if !genCSR {
 		cgcReq, err := gcert.NewCertCanGenerateCSRRequest(
-			gcert.CertificateType(a.Config.CertRotateCertificateType),
-			gcert.KeyType(a.Config.CertRotateKeyType),
-			gcert.KeySize(a.Config.CertRotateMinKeySize),
+			gcert.CertificateType(p.certType),
+			gcert.KeyType(p.keyType),
+			gcert.KeySize(p.minKeySize),
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		cgcResp, err := certClient.CanGenerateCSR(ctx, cgcReq)
 		if err != nil {
-			return fmt.Errorf("%q failed CanGenCSR RPC: %v", t.Config.Name, err)
-		}
-		if !cgcResp.GetCanGenerate() {
-			a.Config.CertRotateGenCSR = true
+			return nil, fmt.Errorf("%q failed CanGenCSR RPC: %v", t.Config.Name, err)
 		}
+		genCSR = !cgcResp.GetCanGenerate()
 	}
 
-	keyPair := new(cert.KeyPair)
+	var keyPair *cert.KeyPair
 	var creq *x509.CertificateRequest
 
-	if a.Config.CertRotateGenCSR {
-		keyPair, creq, err = a.createLocalCSRRotate(t)
+	if genCSR {
+		keyPair, creq, err = createLocalCSR(p.subject, p.keyType, int(p.minKeySize))
 	} else {
-		creq, err = a.createRemoteCSRRotate(stream, t)
+		creq, err = a.createRemoteCSRRotate(stream, t, p)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s, err := CertificateRequestText(creq)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if a.Config.CertRotatePrintCSR {
-		fmt.Printf("%q generated CSR:\n%s\n", t.Config.Address, s)
+	if p.printCSR {
+		fmt.Fprintf(os.Stderr, "%q generated CSR:\n%s\n", t.Config.Address, s)
 	}
-	a.Logger.Debugf("%q generated CSR:\n%s\n", t.Config.Address, s)
+	a.targetLogger(t.Config.Name).Debugf("%q generated CSR:\n%s\n", t.Config.Address, s)
 
-	certificate, err := certificateFromCSR(creq, a.Config.CertRotateValidity)
-	if err != nil {
-		return fmt.Errorf("failed certificateFromCSR: %v", err)
-	}
-	a.Logger.Infof("%q signing certificate %q with the provided CA", t.Config.Address, certificate.Subject.String())
-	signedCert, err := a.sign(certificate, &caCert)
+	loadCertReq, signedCert, err := a.certRotateLoadCertificateRequest(ctx, t, p, keyPair, creq)
 	if err != nil {
-		return fmt.Errorf("failed signing certificate: %v", err)
-	}
-	sCertText, err := CertificateText(signedCert, false)
-	if err != nil {
-		return err
-	}
-	a.Logger.Debugf("%q signed certificate:\n%s\n", t.Config.Address, sCertText)
-	b, err := toPEM(signedCert)
-	if err != nil {
-		return fmt.Errorf("failed toPEM: %v", err)
-	}
-	a.Logger.Infof("%q rotating certificate id=%s %q", t.Config.Address, a.Config.CertRotateCertificateID, certificate.Subject.String())
-
-	// rotate certificate load certificate request options
-	opts := []gcert.CertOption{
-		gcert.Certificate(
-			gcert.CertificateType(a.Config.CertRotateCertificateType),
-			gcert.CertificateBytes(b),
-		),
-		gcert.CertificateID(a.Config.CertRotateCertificateID),
-	}
-	if a.Config.CertRotateGenCSR {
-		// if the csr was generated locally, add the key pair and cert ID
-		opts = append(opts,
-			gcert.KeyPair(
-				gcert.PublicKey(keyPair.GetPublicKey()),
-				gcert.PrivateKey(keyPair.GetPrivateKey()),
-			),
-		)
-	}
-	loadCertReq, err := gcert.NewCertRotateLoadCertificateRequest(opts...)
-	if err != nil {
-		return err
+		return nil, err
 	}
 	a.printMsg(t.Config.Name, loadCertReq)
 	err = stream.Send(loadCertReq)
 	if err != nil {
-		return fmt.Errorf("%q failed sending RotateRequest: %v", t.Config.Address, err)
+		return nil, fmt.Errorf("%q failed sending RotateRequest: %v", t.Config.Address, err)
 	}
 	resp, err := stream.Recv()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	a.printMsg(t.Config.Name, resp)
 
+	result := &certRotateResult{
+		ID:         p.id,
+		Serial:     signedCert.SerialNumber.String(),
+		NotAfter:   signedCert.NotAfter,
+		Validation: validationSkipped,
+	}
+	switch {
+	case p.skipValidation:
+		result.Detail = "--skip-validation is set"
+	case t.Config.Insecure != nil && *t.Config.Insecure:
+		result.Detail = "the target is reached without TLS"
+		a.targetLogger(t.Config.Name).Warnf("%q cannot validate the new certificate: %s", t.Config.Address, result.Detail)
+	default:
+		err = a.validateRotatedCert(ctx, t, signedCert, p.validationTimeout)
+		if err != nil {
+			result.Validation = validationFailed
+			result.Detail = err.Error()
+			return result, fmt.Errorf("%q new certificate validation failed, rotation aborted: %v", t.Config.Address, err)
+		}
+		result.Validation = validationPassed
+		a.targetLogger(t.Config.Name).Infof("%q presents the new certificate serial=%s", t.Config.Address, result.Serial)
+	}
+
 	a.printMsg(t.Config.Name, gcert.NewCertRotateFinalizeRequest())
 	err = stream.Send(gcert.NewCertRotateFinalizeRequest())
 	if err != nil {
-		return fmt.Errorf("%q RotateRequest FinalizeRequest RPC failed: %v", t.Config.Address, err)
+		return result, fmt.Errorf("%q RotateRequest FinalizeRequest RPC failed: %v", t.Config.Address, err)
 	}
 	resp, err = stream.Recv()
 	if err != nil && !errors.Is(err, io.EOF) {
-		return err
+		return result, err
 	}
+	result.Finalized = true
 	a.printMsg(t.Config.Name, resp)
-	a.Logger.Infof("%q Rotate RPC successful", t.Config.Address)
-	return nil
+	a.rpcLogger(t.Config.Name, cert.CertificateManagement_Rotate_FullMethodName).Infof("%q Rotate RPC successful", t.Config.Address)
+	return result, nil
 }
 
-func (a *App) createLocalCSRRotate(t *api.Target) (*cert.KeyPair, *x509.CertificateRequest, error) {
-	var commonName = a.Config.CertRotateCommonName
-	var ipAddr = a.Config.CertRotateIPAddress
-	if commonName == "" {
-		commonName = t.Config.CommonName
-	}
-	if ipAddr == "" {
-		ipAddr = t.Config.ResolvedIP
-	}
-	privateKey, err := rsa.GenerateKey(rand.Reader, int(a.Config.CertRotateMinKeySize))
+// validateRotatedCert checks, over new TLS connections, that target t presents certificate c,
+// in a chain verified by the signer CA (or the system roots if it is unknown).
+// The target is given the timeout to start presenting it.
+func (a *App) validateRotatedCert(ctx context.Context, t *api.Target, c *x509.Certificate, timeout time.Duration) error {
+	tlsConfig, err := t.Config.NewTLSConfig()
 	if err != nil {
-		return nil, nil, err
-	}
-
-	var subj pkix.Name
-	if commonName != "" {
-		subj.CommonName = commonName
+		return err
 	}
-	if a.Config.CertRotateCountry != "" {
-		subj.Country = []string{a.Config.CertRotateCountry}
+	// the chain and the leaf are checked below, the target name may not be in the certificate SANs
+	tlsConfig.InsecureSkipVerify = true
+	opts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
 	}
-	if a.Config.CertRotateState != "" {
-		subj.Province = []string{a.Config.CertRotateState}
+	if ca := a.signer.ca(); ca != nil {
+		opts.Roots = x509.NewCertPool()
+		opts.Roots.AddCert(ca)
 	}
-	if a.Config.CertRotateCity != "" {
-		subj.Locality = []string{a.Config.CertRotateCity}
+	verify := func() error {
+		hctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
+		defer cancel()
+		state, err := t.TLSHandshake(hctx, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("TLS handshake failed: %v", err)
+		}
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("no certificate presented")
+		}
+		leaf := state.PeerCertificates[0]
+		if !leaf.Equal(c) {
+			return fmt.Errorf("presented certificate serial=%s %q is not the new one serial=%s",
+				leaf.SerialNumber, leaf.Subject, c.SerialNumber)
+		}
+		for _, ic := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(ic)
+		}
+		_, err = leaf.Verify(opts)
+		if err != nil {
+			return fmt.Errorf("presented chain verification failed: %v", err)
+		}
+		return nil
 	}
-	if a.Config.CertRotateOrg != "" {
-		subj.Organization = []string{a.Config.CertRotateOrg}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err = verify()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		a.targetLogger(t.Config.Name).Debugf("%q new certificate not validated yet: %v", t.Config.Address, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
 	}
-	if a.Config.CertRotateOrgUnit != "" {
-		subj.OrganizationalUnit = []string{a.Config.CertRotateOrgUnit}
+}
+
+// certRotateLoadCertificateRequest signs a certificate for creq with the signer
+// and builds the Rotate request loading it, along with keyPair if the CSR was generated locally.
+func (a *App) certRotateLoadCertificateRequest(ctx context.Context, t *api.Target, p *certRotateParams, keyPair *cert.KeyPair, creq *x509.CertificateRequest) (*cert.RotateCertificateRequest, *x509.Certificate, error) {
+	certificate, err := certificateFromCSR(creq, p.validity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed certificateFromCSR: %v", err)
 	}
-	if a.Config.CertRotateEmailID != "" {
-		subj.ExtraNames = append(subj.ExtraNames, pkix.AttributeTypeAndValue{
-			Type: oidEmailAddress,
-			Value: asn1.RawValue{
-				Tag:   asn1.TagIA5String,
-				Bytes: []byte(a.Config.CertRotateEmailID),
-			},
-		})
+	p.subject.applyTo(certificate)
+	a.targetLogger(t.Config.Name).Infof("%q signing certificate %q with the %s signer", t.Config.Address, certificate.Subject.String(), a.signer)
+	signedCert, err := a.signer.sign(ctx, certificate, creq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed signing certificate: %v", err)
 	}
-
-	var ipAddrs net.IP
-	if ipAddr != "" {
-		ipAddrs = net.ParseIP(ipAddr)
+	sCertText, err := CertificateText(signedCert, false)
+	if err != nil {
+		return nil, nil, err
 	}
-	template := x509.CertificateRequest{
-		Subject:            subj,
-		EmailAddresses:     []string{a.Config.CertRotateEmailID},
-		SignatureAlgorithm: x509.SHA256WithRSA,
-		IPAddresses:        make([]net.IP, 0),
-		DNSNames:           []string{commonName},
+	a.targetLogger(t.Config.Name).Debugf("%q signed certificate:\n%s\n", t.Config.Address, sCertText)
+	b, err := toPEM(signedCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed toPEM: %v", err)
 	}
+	a.targetLogger(t.Config.Name).Infof("%q rotating certificate id=%s %q", t.Config.Address, p.id, certificate.Subject.String())
 
-	if ipAddrs != nil {
-		template.IPAddresses = append(template.IPAddresses, ipAddrs)
+	// rotate certificate load certificate request options
+	opts := []gcert.CertOption{
+		gcert.Certificate(
+			gcert.CertificateType(p.certType),
+			gcert.CertificateBytes(b),
+		),
+		gcert.CertificateID(p.id),
 	}
-
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create Certificate Request: %v", err)
+	if keyPair != nil {
+		// if the csr was generated locally, add the key pair and cert ID
+		opts = append(opts,
+			gcert.KeyPair(
+				gcert.PublicKey(keyPair.GetPublicKey()),
+				gcert.PrivateKey(keyPair.GetPrivateKey()),
+			),
+		)
 	}
-	creq, err := x509.ParseCertificateRequest(csrBytes)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed parsing certificate request: %v", err)
-	}
-	return &cert.KeyPair{
-			PrivateKey: pem.EncodeToMemory(&pem.Block{
-				Type:  "RSA PRIVATE KEY",
-				Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-			}),
-			PublicKey: csrBytes,
-		},
-		creq, err
+	req, err := gcert.NewCertRotateLoadCertificateRequest(opts...)
+	return req, signedCert, err
 }
 
-func (a *App) createRemoteCSRRotate(stream cert.CertificateManagement_RotateClient, t *api.Target) (*x509.CertificateRequest, error) {
-	var commonName = a.Config.CertRotateCommonName
-	var ipAddr = a.Config.CertRotateIPAddress
-	if commonName == "" {
-		commonName = t.Config.CommonName
-	}
-	if ipAddr == "" {
-		ipAddr = t.Config.ResolvedIP
-	}
-	csrParamsOpts := []gcert.CertOption{
-		gcert.CertificateType(a.Config.CertRotateCertificateType),
-		gcert.MinKeySize(a.Config.CertRotateMinKeySize),
-		gcert.KeyType(a.Config.CertRotateKeyType),
-		gcert.CommonName(commonName),
-		gcert.Country(a.Config.CertRotateCountry),
-		gcert.State(a.Config.CertRotateState),
-		gcert.City(a.Config.CertRotateCity),
-		gcert.Org(a.Config.CertRotateOrg),
-		gcert.OrgUnit(a.Config.CertRotateOrgUnit),
-		gcert.IPAddress(ipAddr),
-	}
-	if a.Config.CertRotateEmailID != "" {
-		csrParamsOpts = append(csrParamsOpts, gcert.EmailID(a.Config.CertRotateEmailID))
-	}
-
-	req, err := gcert.NewCertRotateGenerateCSRRequest(
-		gcert.CertificateID(a.Config.CertRotateCertificateID),
-		gcert.CSRParams(csrParamsOpts...),
-	)
+func (a *App) createRemoteCSRRotate(stream cert.CertificateManagement_RotateClient, t *api.Target, p *certRotateParams) (*x509.CertificateRequest, error) {
+	req, err := a.certRotateGenerateCSRRequest(t, p)
 	if err != nil {
 		return nil, err
 	}
@@ -344,17 +427,139 @@ func (a *App) createRemoteCSRRotate(stream cert.CertificateManagement_RotateClie
 		return nil, fmt.Errorf("%q returned a <nil> CSR response", t.Config.Address)
 	}
 	a.printMsg(t.Config.Name, resp)
-	if a.Config.CertRotatePrintCSR {
-		fmt.Printf("%q genCSR response:\n %s\n", t.Config.Address, prototext.Format(resp))
+	if p.printCSR {
+		fmt.Fprintf(os.Stderr, "%q genCSR response:\n %s\n", t.Config.Address, prototext.Format(resp))
 	}
 
-	p, rest := pem.Decode(resp.GetGeneratedCsr().GetCsr().GetCsr())
-	if p == nil || len(rest) > 0 {
+	block, rest := pem.Decode(resp.GetGeneratedCsr().GetCsr().GetCsr())
+	if block == nil || len(rest) > 0 {
 		return nil, fmt.Errorf("%q failed to decode returned CSR", t.Config.Address)
 	}
-	creq, err := x509.ParseCertificateRequest(p.Bytes)
+	creq, err := x509.ParseCertificateRequest(block.Bytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing certificate request: %v", err)
 	}
 	return creq, nil
 }
+
+func (a *App) certRotateGenerateCSRRequest(t *api.Target, p *certRotateParams) (*cert.RotateCertificateRequest, error) {
+	if dropped := csrParamsDropped(p.subject); len(dropped) > 0 {
+		a.targetLogger(t.Config.Name).Infof("%q the CSR parameters cannot carry the %s, they are set when signing the certificate",
+			t.Config.Address, strings.Join(dropped, ", "))
+	}
+	csrParamsOpts := append([]gcert.CertOption{
+		gcert.CertificateType(p.certType),
+		gcert.MinKeySize(p.minKeySize),
+		gcert.KeyType(p.keyType),
+	}, csrParamsOptions(p.subject)...)
+
+	return gcert.NewCertRotateGenerateCSRRequest(
+		gcert.CertificateID(p.id),
+		gcert.CSRParams(csrParamsOpts...),
+	)
+}
+
+func (a *App) certRotateDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	p, err := a.certRotateFlags().forTarget(t)
+	if err != nil {
+		return nil, err
+	}
+	finalizeNote := "sent once the target presents the new certificate over a new TLS connection, otherwise the rotation is aborted"
+	if p.skipValidation || (t.Config.Insecure != nil && *t.Config.Insecure) {
+		finalizeNote = "sent without validating the new certificate"
+	}
+	if p.localCSR() && a.externalSigner() {
+		return []*dryRunRequest{
+			{
+				RPC:  cert.CertificateManagement_Rotate_FullMethodName,
+				Note: fmt.Sprintf("load_certificate request with a locally generated key pair and a certificate signed by the %s signer", a.Config.CertSigner),
+			},
+			{
+				RPC:     cert.CertificateManagement_Rotate_FullMethodName,
+				Request: gcert.NewCertRotateFinalizeRequest(),
+				Note:    finalizeNote,
+			},
+		}, nil
+	}
+	if p.localCSR() {
+		keyPair, creq, err := createLocalCSR(p.subject, p.keyType, int(p.minKeySize))
+		if err != nil {
+			return nil, err
+		}
+		req, _, err := a.certRotateLoadCertificateRequest(ctx, t, p, keyPair, creq)
+		if err != nil {
+			return nil, err
+		}
+		return []*dryRunRequest{
+			{
+				RPC:     cert.CertificateManagement_Rotate_FullMethodName,
+				Request: req,
+			},
+			{
+				RPC:     cert.CertificateManagement_Rotate_FullMethodName,
+				Request: gcert.NewCertRotateFinalizeRequest(),
+				Note:    finalizeNote,
+			},
+		}, nil
+	}
+	cgcReq, err := gcert.NewCertCanGenerateCSRRequest(
+		gcert.CertificateType(p.certType),
+		gcert.KeyType(p.keyType),
+		gcert.KeySize(p.minKeySize),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req, err := a.certRotateGenerateCSRRequest(t, p)
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{
+		{
+			RPC:     cert.CertificateManagement_CanGenerateCSR_FullMethodName,
+			Request: cgcReq,
+		},
+		{
+			RPC:     cert.CertificateManagement_Rotate_FullMethodName,
+			Request: req,
+			Note:    "if the target cannot generate the CSR, the key pair and CSR are generated locally instead",
+		},
+		{
+			RPC:  cert.CertificateManagement_Rotate_FullMethodName,
+			Note: fmt.Sprintf("load_certificate request with a certificate signed by the %s signer for the CSR returned by the target", a.Config.CertSigner),
+		},
+		{
+			RPC:     cert.CertificateManagement_Rotate_FullMethodName,
+			Request: gcert.NewCertRotateFinalizeRequest(),
+			Note:    finalizeNote,
+		},
+	}, nil
+}
+
+func certRotateTable(results []*certRotateResponse) string {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].target < results[j].target
+	})
+	tabData := make([][]string, 0, len(results))
+	for _, r := range results {
+		validation := r.rsp.Validation
+		if r.rsp.Detail != "" {
+			validation = fmt.Sprintf("%s: %s", validation, r.rsp.Detail)
+		}
+		tabData = append(tabData, []string{
+			r.target,
+			r.rsp.ID,
+			r.rsp.Serial,
+			r.rsp.NotAfter.Format(time.RFC3339),
+			validation,
+			strconv.FormatBool(r.rsp.Finalized),
+		})
+	}
+	b := new(bytes.Buffer)
+	table := tablewriter.NewWriter(b)
+	table.SetHeader([]string{"Target Name", "ID", "Serial", "Valid Until", "Validation", "Finalized"})
+	formatTable(table)
+	table.AppendBulk(tabData)
+	table.Render()
+	return b.String()
+}
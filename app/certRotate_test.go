@@ -0,0 +1,88 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/karimra/gnoic/api"
+	"github.com/karimra/gnoic/config"
+)
+
+func Test_validateRotatedCert(t *testing.T) {
+	newKey := func() *ecdsa.PrivateKey {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	notAfter := time.Now().Add(24 * time.Hour)
+	caKey, otherCAKey, key := newKey(), newKey(), newKey()
+	ca := testCertificate(t, "ca", 1, notAfter, caKey, nil, nil)
+	otherCA := testCertificate(t, "other-ca", 2, notAfter, otherCAKey, nil, nil)
+	rotated := testCertificate(t, "r1", 3, notAfter, key, ca, caKey)
+	previous := testCertificate(t, "r1", 4, notAfter, key, ca, caKey)
+	untrusted := testCertificate(t, "r1", 5, notAfter, key, otherCA, otherCAKey)
+
+	tests := []struct {
+		name      string
+		signed    *x509.Certificate
+		presented *x509.Certificate
+		wantErr   string
+	}{
+		{name: "new_certificate", signed: rotated, presented: rotated},
+		{name: "previous_certificate", signed: rotated, presented: previous, wantErr: "is not the new one serial=3"},
+		{name: "untrusted_chain", signed: untrusted, presented: untrusted, wantErr: "presented chain verification failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+				Certificates: []tls.Certificate{{
+					Certificate: [][]byte{tt.presented.Raw},
+					PrivateKey:  key,
+				}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			go func() {
+				for {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					conn.(*tls.Conn).Handshake()
+					conn.Close()
+				}
+			}()
+
+			a := New()
+			a.signer = &localSigner{caCert: &tls.Certificate{Leaf: ca, PrivateKey: caKey}}
+			tg := api.NewTargetFromConfig(&config.TargetConfig{
+				Name:       "r1",
+				Address:    ln.Addr().String(),
+				Timeout:    time.Second,
+				Insecure:   pointer.ToBool(false),
+				SkipVerify: pointer.ToBool(false),
+			})
+			err = a.validateRotatedCert(a.ctx, tg, tt.signed, 0)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateRotatedCert() err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
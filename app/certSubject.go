@@ -0,0 +1,378 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/karimra/gnoic/api"
+	gcert "github.com/karimra/gnoic/api/cert"
+	"github.com/openconfig/gnoi/cert"
+)
+
+var oidExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+
+// extKeyUsages are the extended key usages settable with --ext-key-usage.
+var extKeyUsages = map[string]struct {
+	usage x509.ExtKeyUsage
+	oid   asn1.ObjectIdentifier
+}{
+	"any":              {x509.ExtKeyUsageAny, asn1.ObjectIdentifier{2, 5, 29, 37, 0}},
+	"server-auth":      {x509.ExtKeyUsageServerAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+	"client-auth":      {x509.ExtKeyUsageClientAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+	"code-signing":     {x509.ExtKeyUsageCodeSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}},
+	"email-protection": {x509.ExtKeyUsageEmailProtection, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}},
+	"time-stamping":    {x509.ExtKeyUsageTimeStamping, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}},
+	"ocsp-signing":     {x509.ExtKeyUsageOCSPSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}},
+}
+
+func extKeyUsageNames() []string {
+	names := make([]string, 0, len(extKeyUsages))
+	for n := range extKeyUsages {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// certSubject is the subject, SANs and extended key usages requested for a certificate.
+// Its values can be templates, executed for each target with certTemplateData,
+// e.g: {{.Name}}.{{.Tags.site}}.example.net
+type certSubject struct {
+	commonName   string
+	country      string
+	state        string
+	city         string
+	org          string
+	orgUnit      string
+	emailID      string
+	dnsNames     []string
+	ipAddresses  []string
+	uris         []string
+	emails       []string
+	extKeyUsages []string
+
+	// set by execute
+	ips    []net.IP
+	urls   []*url.URL
+	usages []x509.ExtKeyUsage
+}
+
+// certTemplateData is the data the certSubject templates are executed with.
+type certTemplateData struct {
+	Name       string
+	Address    string
+	CommonName string
+	ResolvedIP string
+	Tags       map[string]string
+}
+
+// withTargetDefaults returns the subject with the common name, IP address, DNS name
+// and email SAN defaulting to those of the target, if not set.
+func (s certSubject) withTargetDefaults() *certSubject {
+	if s.commonName == "" {
+		s.commonName = "{{.CommonName}}"
+	}
+	if len(s.ipAddresses) == 0 {
+		s.ipAddresses = []string{"{{.ResolvedIP}}"}
+	}
+	if len(s.dnsNames) == 0 {
+		s.dnsNames = []string{s.commonName}
+	}
+	if len(s.emails) == 0 && s.emailID != "" {
+		s.emails = []string{s.emailID}
+	}
+	return &s
+}
+
+// execute returns the subject with its templates executed for target t,
+// the values that are empty once executed are dropped.
+func (s *certSubject) execute(t *api.Target) (*certSubject, error) {
+	data := &certTemplateData{
+		Name:       t.Config.Name,
+		Address:    t.Config.Address,
+		CommonName: t.Config.CommonName,
+		ResolvedIP: t.Config.ResolvedIP,
+		Tags:       t.Config.Tags,
+	}
+	if data.Tags == nil {
+		data.Tags = map[string]string{}
+	}
+	exec := func(v string) (string, error) {
+		if !strings.Contains(v, "{{") {
+			return strings.TrimSpace(v), nil
+		}
+		tpl, err := template.New("subject").
+			Option("missingkey=error").
+			Funcs(templateFuncs).
+			Parse(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid template %q: %v", v, err)
+		}
+		sb := new(strings.Builder)
+		err = tpl.Execute(sb, data)
+		if err != nil {
+			return "", fmt.Errorf("failed executing template %q: %v", v, err)
+		}
+		return strings.TrimSpace(sb.String()), nil
+	}
+	execList := func(vs []string) ([]string, error) {
+		var r []string
+		for _, v := range vs {
+			ev, err := exec(v)
+			if err != nil {
+				return nil, err
+			}
+			if ev != "" {
+				r = append(r, ev)
+			}
+		}
+		return r, nil
+	}
+
+	r := &certSubject{extKeyUsages: s.extKeyUsages}
+	var err error
+	for _, f := range []struct {
+		dst *string
+		v   string
+	}{
+		{&r.commonName, s.commonName},
+		{&r.country, s.country},
+		{&r.state, s.state},
+		{&r.city, s.city},
+		{&r.org, s.org},
+		{&r.orgUnit, s.orgUnit},
+		{&r.emailID, s.emailID},
+	} {
+		*f.dst, err = exec(f.v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		dst *[]string
+		vs  []string
+	}{
+		{&r.dnsNames, s.dnsNames},
+		{&r.ipAddresses, s.ipAddresses},
+		{&r.uris, s.uris},
+		{&r.emails, s.emails},
+	} {
+		*f.dst, err = execList(f.vs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ipAddr := range r.ipAddresses {
+		ip := net.ParseIP(ipAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", ipAddr)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		r.ips = append(r.ips, ip)
+	}
+	for _, u := range r.uris {
+		pu, err := url.Parse(u)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI %q: %v", u, err)
+		}
+		if pu.Scheme == "" {
+			return nil, fmt.Errorf("invalid URI %q: missing scheme", u)
+		}
+		r.urls = append(r.urls, pu)
+	}
+	for _, n := range r.extKeyUsages {
+		eku, ok := extKeyUsages[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown extended key usage %q, must be one of %q", n, extKeyUsageNames())
+		}
+		r.usages = append(r.usages, eku.usage)
+	}
+	return r, nil
+}
+
+func (s *certSubject) pkixName() pkix.Name {
+	var subj pkix.Name
+	subj.CommonName = s.commonName
+	if s.country != "" {
+		subj.Country = []string{s.country}
+	}
+	if s.state != "" {
+		subj.Province = []string{s.state}
+	}
+	if s.city != "" {
+		subj.Locality = []string{s.city}
+	}
+	if s.org != "" {
+		subj.Organization = []string{s.org}
+	}
+	if s.orgUnit != "" {
+		subj.OrganizationalUnit = []string{s.orgUnit}
+	}
+	if s.emailID != "" {
+		subj.ExtraNames = append(subj.ExtraNames, pkix.AttributeTypeAndValue{
+			Type: oidEmailAddress,
+			Value: asn1.RawValue{
+				Tag:   asn1.TagIA5String,
+				Bytes: []byte(s.emailID),
+			},
+		})
+	}
+	return subj
+}
+
+// hasSANs returns true if the subject has at least one SAN.
+func (s *certSubject) hasSANs() bool {
+	return len(s.dnsNames) > 0 || len(s.ips) > 0 || len(s.urls) > 0 || len(s.emails) > 0
+}
+
+// applyTo sets the SANs and extended key usages of the executed subject on certificate c,
+// the SANs of c are kept if the subject has none.
+func (s *certSubject) applyTo(c *x509.Certificate) {
+	if s.hasSANs() {
+		c.DNSNames = s.dnsNames
+		c.IPAddresses = s.ips
+		c.URIs = s.urls
+		c.EmailAddresses = s.emails
+	}
+	if len(s.usages) > 0 {
+		c.ExtKeyUsage = s.usages
+	}
+}
+
+// keyTypeECDSA is the key type of ECDSA keys, the gNOI KeyType enum has no ECDSA value,
+// so ECDSA keys are always generated locally.
+const keyTypeECDSA = "KT_ECDSA"
+
+// generateKey generates a private key of type keyType and size keySize,
+// the size of an ECDSA key is its curve size.
+// The key is returned along with its PEM block.
+func generateKey(keyType string, keySize int) (crypto.Signer, *pem.Block, error) {
+	switch keyType {
+	case keyTypeECDSA:
+		var curve elliptic.Curve
+		switch keySize {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, nil, fmt.Errorf("unsupported ECDSA key size %d, must be one of 256, 384 or 521", keySize)
+		}
+		privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := x509.MarshalECPrivateKey(privateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKey, &pem.Block{Type: "EC PRIVATE KEY", Bytes: b}, nil
+	default:
+		privateKey, err := rsa.GenerateKey(rand.Reader, keySize)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKey, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}, nil
+	}
+}
+
+// createLocalCSR generates a key pair of type keyType and a CSR for the executed subject,
+// the CSR requests its extended key usages, if any.
+func createLocalCSR(s *certSubject, keyType string, keySize int) (*cert.KeyPair, *x509.CertificateRequest, error) {
+	privateKey, keyBlock, err := generateKey(keyType, keySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := x509.CertificateRequest{
+		Subject:        s.pkixName(),
+		DNSNames:       s.dnsNames,
+		IPAddresses:    s.ips,
+		URIs:           s.urls,
+		EmailAddresses: s.emails,
+	}
+	if len(s.extKeyUsages) > 0 {
+		oids := make([]asn1.ObjectIdentifier, 0, len(s.extKeyUsages))
+		for _, n := range s.extKeyUsages {
+			oids = append(oids, extKeyUsages[n].oid)
+		}
+		b, err := asn1.Marshal(oids)
+		if err != nil {
+			return nil, nil, err
+		}
+		tmpl.ExtraExtensions = append(tmpl.ExtraExtensions, pkix.Extension{Id: oidExtKeyUsage, Value: b})
+	}
+
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &tmpl, privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Certificate Request: %v", err)
+	}
+	creq, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing certificate request: %v", err)
+	}
+	return &cert.KeyPair{
+			PrivateKey: pem.EncodeToMemory(keyBlock),
+			PublicKey:  csrBytes,
+		},
+		creq, nil
+}
+
+// csrParamsOptions returns the CSRParams options of the executed subject.
+// The CSRParams carry a single IP address and email,
+// the values they cannot carry are returned by csrParamsDropped.
+func csrParamsOptions(s *certSubject) []gcert.CertOption {
+	opts := []gcert.CertOption{
+		gcert.CommonName(s.commonName),
+		gcert.Country(s.country),
+		gcert.State(s.state),
+		gcert.City(s.city),
+		gcert.Org(s.org),
+		gcert.OrgUnit(s.orgUnit),
+	}
+	if len(s.ipAddresses) > 0 {
+		opts = append(opts, gcert.IPAddress(s.ipAddresses[0]))
+	}
+	if s.emailID != "" {
+		opts = append(opts, gcert.EmailID(s.emailID))
+	}
+	return opts
+}
+
+// csrParamsDropped describes the values of the executed subject the CSRParams cannot carry.
+func csrParamsDropped(s *certSubject) []string {
+	dropped := make([]string, 0)
+	// the target is expected to use the common name as DNS SAN
+	if len(s.dnsNames) > 1 || (len(s.dnsNames) == 1 && s.dnsNames[0] != s.commonName) {
+		dropped = append(dropped, fmt.Sprintf("DNS names %q", s.dnsNames))
+	}
+	if len(s.ipAddresses) > 1 {
+		dropped = append(dropped, fmt.Sprintf("IP addresses %q", s.ipAddresses[1:]))
+	}
+	if len(s.uris) > 0 {
+		dropped = append(dropped, fmt.Sprintf("URIs %q", s.uris))
+	}
+	if len(s.emails) > 1 || (len(s.emails) == 1 && s.emails[0] != s.emailID) {
+		dropped = append(dropped, fmt.Sprintf("emails %q", s.emails))
+	}
+	if len(s.extKeyUsages) > 0 {
+		dropped = append(dropped, fmt.Sprintf("extended key usages %q", s.extKeyUsages))
+	}
+	return dropped
+}
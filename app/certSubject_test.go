@@ -0,0 +1,141 @@
+package app
+
+import (
+	"crypto/x509"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/karimra/gnoic/api"
+	"github.com/karimra/gnoic/config"
+)
+
+func Test_certSubject_execute(t *testing.T) {
+	tg := api.NewTargetFromConfig(&config.TargetConfig{
+		Name:       "r1",
+		Address:    "r1:57400",
+		CommonName: "r1.lab",
+		ResolvedIP: "192.0.2.1",
+		Tags:       map[string]string{"site": "par1"},
+	})
+	tests := []struct {
+		name    string
+		subject *certSubject
+		want    *certSubject
+		wantErr string
+	}{
+		{
+			name:    "target_defaults",
+			subject: certSubject{}.withTargetDefaults(),
+			want: &certSubject{
+				commonName:  "r1.lab",
+				dnsNames:    []string{"r1.lab"},
+				ipAddresses: []string{"192.0.2.1"},
+			},
+		},
+		{
+			name: "templates",
+			subject: certSubject{
+				commonName:   "{{.Name}}.{{.Tags.site}}.example.net",
+				org:          "example",
+				dnsNames:     []string{"{{.Name}}.{{.Tags.site}}.example.net", "{{.Name}}"},
+				ipAddresses:  []string{"{{.ResolvedIP}}", "2001:db8::1"},
+				uris:         []string{"spiffe://example.net/{{.Tags.site}}/{{.Name}}"},
+				extKeyUsages: []string{"server-auth", "client-auth"},
+			}.withTargetDefaults(),
+			want: &certSubject{
+				commonName:   "r1.par1.example.net",
+				org:          "example",
+				dnsNames:     []string{"r1.par1.example.net", "r1"},
+				ipAddresses:  []string{"192.0.2.1", "2001:db8::1"},
+				uris:         []string{"spiffe://example.net/par1/r1"},
+				extKeyUsages: []string{"server-auth", "client-auth"},
+			},
+		},
+		{
+			name:    "missing_tag",
+			subject: &certSubject{commonName: "{{.Name}}.{{.Tags.region}}"},
+			wantErr: "map has no entry for key",
+		},
+		{
+			name:    "invalid_ip",
+			subject: &certSubject{ipAddresses: []string{"{{.Name}}"}},
+			wantErr: `invalid IP address "r1"`,
+		},
+		{
+			name:    "uri_without_scheme",
+			subject: &certSubject{uris: []string{"example.net/r1"}},
+			wantErr: "missing scheme",
+		},
+		{
+			name:    "unknown_ext_key_usage",
+			subject: &certSubject{extKeyUsages: []string{"web"}},
+			wantErr: `unknown extended key usage "web"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.subject.execute(tg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("execute() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.commonName != tt.want.commonName || got.org != tt.want.org ||
+				!reflect.DeepEqual(got.dnsNames, tt.want.dnsNames) ||
+				!reflect.DeepEqual(got.ipAddresses, tt.want.ipAddresses) ||
+				!reflect.DeepEqual(got.uris, tt.want.uris) ||
+				!reflect.DeepEqual(got.extKeyUsages, tt.want.extKeyUsages) {
+				t.Errorf("execute() = %+v, want %+v", got, tt.want)
+			}
+			if len(got.ips) != len(tt.want.ipAddresses) || len(got.urls) != len(tt.want.uris) ||
+				len(got.usages) != len(tt.want.extKeyUsages) {
+				t.Errorf("execute() parsed %v %v %v", got.ips, got.urls, got.usages)
+			}
+		})
+	}
+}
+
+func Test_createLocalCSR(t *testing.T) {
+	tg := api.NewTargetFromConfig(&config.TargetConfig{Name: "r1", Address: "r1:57400"})
+	s, err := (&certSubject{
+		commonName:   "r1.example.net",
+		dnsNames:     []string{"r1.example.net", "r1"},
+		ipAddresses:  []string{"192.0.2.1", "2001:db8::1"},
+		uris:         []string{"spiffe://example.net/r1"},
+		extKeyUsages: []string{"server-auth"},
+	}).execute(tg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, creq, err := createLocalCSR(s, "KT_RSA", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(creq.DNSNames, s.dnsNames) || len(creq.IPAddresses) != 2 ||
+		!creq.IPAddresses[1].Equal(net.ParseIP("2001:db8::1")) ||
+		len(creq.URIs) != 1 || creq.URIs[0].String() != "spiffe://example.net/r1" {
+		t.Errorf("CSR SANs = %v %v %v", creq.DNSNames, creq.IPAddresses, creq.URIs)
+	}
+	var hasEKU bool
+	for _, ext := range creq.Extensions {
+		hasEKU = hasEKU || ext.Id.Equal(oidExtKeyUsage)
+	}
+	if !hasEKU {
+		t.Errorf("CSR does not request the extended key usages")
+	}
+
+	c, err := certificateFromCSR(creq, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.applyTo(c)
+	if !reflect.DeepEqual(c.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}) {
+		t.Errorf("certificate extended key usages = %v, want server auth", c.ExtKeyUsage)
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -132,6 +133,19 @@ func printSubjectInformation(subj *pkix.Name, pkAlgo x509.PublicKeyAlgorithm, pk
 	return nil
 }
 
+// publicKeySize returns the size in bits of a certificate public key, 0 if unknown.
+func publicKeySize(pk interface{}) int {
+	switch pk := pk.(type) {
+	case *rsa.PublicKey:
+		return pk.N.BitLen()
+	case *ecdsa.PublicKey:
+		return pk.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
 func printSubjKeyId(ext pkix.Extension, buf *strings.Builder) error {
 	// subjectKeyIdentifier: RFC 5280, 4.2.1.2
 	buf.WriteString(fmt.Sprintf("%12sX509v3 Subject Key Identifier:", ""))
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/karimra/gnoic/api"
+)
+
+const redacted = "<redacted>"
+
+// request fields holding secrets, never printed by --dry-run nor recorded in the journal.
+// The string and bytes fields of the messages listed are redacted too.
+var redactedFields = []string{"private_key", "cleartext", "password", "hashed", "salt", "token"}
+
+// dryRunRequest is a request that a command would send to a target.
+type dryRunRequest struct {
+	RPC     string
+	Request proto.Message
+	// what the request depends on or is followed by, if it can't be built locally
+	Note string
+}
+
+type dryRunResponse struct {
+	TargetError
+	reqs []*dryRunRequest
+}
+
+func (r *dryRunResponse) response() interface{} {
+	rs := make([]map[string]interface{}, 0, len(r.reqs))
+	for _, req := range r.reqs {
+		m := map[string]interface{}{"rpc": req.RPC}
+		if req.Request != nil {
+			v, err := normalizeResponse(redactMsg(req.Request))
+			if err != nil {
+				v = err.Error()
+			}
+			m["request"] = v
+		}
+		if req.Note != "" {
+			m["note"] = req.Note
+		}
+		rs = append(rs, m)
+	}
+	return rs
+}
+
+// mutating commands supporting --dry-run and the function listing
+// the requests they send to a target.
+var dryRunCommands = map[string]func(a *App, ctx context.Context, t *api.Target) ([]*dryRunRequest, error){
+	"cert-install":        (*App).certInstallDryRun,
+	"cert-revoke":         (*App).certRevokeDryRun,
+	"cert-rotate":         (*App).certRotateDryRun,
+	"factory-reset-start": (*App).factoryResetStartDryRun,
+	"file-put":            (*App).filePutDryRun,
+	"file-remove":         (*App).fileRemoveDryRun,
+	"os-activate":         (*App).osActivateDryRun,
+	"os-install":          (*App).osInstallDryRun,
+	"system-kill-process": (*App).systemKillProcessDryRun,
+	"system-reboot":       (*App).systemRebootDryRun,
+	"system-set-package":  (*App).systemSetPackageDryRun,
+}
+
+// DryRun runs the command, or with --dry-run, prints the requests
+// it would send to each target without connecting to them.
+func (a *App) DryRun(cmd *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	if !a.Config.DryRun {
+		return runE(cmd, args)
+	}
+	fn, ok := dryRunCommands[a.Config.Command()]
+	if !ok {
+		return UsageError(fmt.Errorf("%q does not support --dry-run", cmd.CommandPath()))
+	}
+	ctx := a.commandContext(cmd)
+	if err := a.dryRunSigner(ctx); err != nil {
+		return err
+	}
+	targets, err := a.GetTargets()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(targets))
+	for n := range targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(targets))
+	for _, n := range names {
+		t := targets[n]
+		reqs, err := fn(a, ctx, t)
+		rsp := &dryRunResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			reqs: reqs,
+		}
+		a.addResult(rsp)
+		if err != nil {
+			wErr := fmt.Errorf("%q dry-run failed: %v", t.Config.Name, err)
+			a.targetLogger(t.Config.Name).Error(wErr)
+			errs = append(errs, wErr)
+			continue
+		}
+		if a.textOutput() {
+			printDryRunRequests(t.Config.Name, reqs)
+		}
+	}
+	return a.handleErrs(errs)
+}
+
+// dryRunSigner sets the signer of the certificates of the cert install and rotate commands.
+// The external signers are not used since they would issue the certificates.
+func (a *App) dryRunSigner(ctx context.Context) error {
+	switch a.Config.Command() {
+	case "cert-install", "cert-rotate":
+	default:
+		return nil
+	}
+	if a.externalSigner() {
+		return nil
+	}
+	var err error
+	a.signer, err = a.newSigner(ctx)
+	return err
+}
+
+func printDryRunRequests(name string, reqs []*dryRunRequest) {
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "%q dry-run, %d request(s):\n", name, len(reqs))
+	for _, req := range reqs {
+		fmt.Fprintf(sb, "%s\n", req.RPC)
+		if req.Request != nil {
+			fmt.Fprintf(sb, "%s\n", prototext.Format(redactMsg(req.Request)))
+		}
+		if req.Note != "" {
+			fmt.Fprintf(sb, "# %s\n", req.Note)
+		}
+	}
+	fmt.Print(sb.String())
+}
+
+// redactMsg returns a copy of m with the redactedFields values replaced.
+func redactMsg(m proto.Message) proto.Message {
+	m = proto.Clone(m)
+	redactFields(m.ProtoReflect(), false)
+	return m
+}
+
+// redactFields replaces the redactedFields values of m,
+// all its string and bytes fields if all is true.
+func redactFields(m protoreflect.Message, all bool) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		secret := all || sInList(string(fd.Name()), redactedFields)
+		switch {
+		case secret && fd.Kind() == protoreflect.BytesKind && !fd.IsList() && !fd.IsMap():
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(redacted)))
+		case secret && fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap():
+			m.Set(fd, protoreflect.ValueOfString(redacted))
+		case fd.Kind() == protoreflect.MessageKind && fd.IsList():
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				redactFields(l.Get(i).Message(), secret)
+			}
+		case fd.Kind() == protoreflect.MessageKind && !fd.IsMap():
+			redactFields(v.Message(), secret)
+		}
+		return true
+	})
+}
+
+// dryRunContent reads a local file the way the streaming commands send it,
+// it returns the number of content requests and the file hash.
+func dryRunContent(filename string, chunkSize uint64, hashMethod string) (int, []byte, error) {
+	var h hash.Hash
+	switch hashMethod {
+	case "MD5":
+		h = md5.New()
+	case "SHA256":
+		h = sha256.New()
+	default:
+		h = sha512.New()
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, nil, err
+	}
+	if chunkSize == 0 {
+		return 0, h.Sum(nil), nil
+	}
+	return int((uint64(n) + chunkSize - 1) / chunkSize), h.Sum(nil), nil
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnoi/cert"
+	"github.com/openconfig/gnoi/common"
+	"github.com/openconfig/gnoi/file"
+	"github.com/openconfig/gnoi/types"
+)
+
+func Test_redactMsg(t *testing.T) {
+	req := &cert.InstallCertificateRequest{
+		InstallRequest: &cert.InstallCertificateRequest_LoadCertificate{
+			LoadCertificate: &cert.LoadCertificateRequest{
+				KeyPair: &cert.KeyPair{
+					PrivateKey: []byte("secret"),
+					PublicKey:  []byte("public"),
+				},
+				CertificateId: "id1",
+			},
+		},
+	}
+	got := redactMsg(req).(*cert.InstallCertificateRequest)
+	if kp := got.GetLoadCertificate().GetKeyPair(); string(kp.GetPrivateKey()) != redacted || string(kp.GetPublicKey()) != "public" {
+		t.Errorf("redactMsg() key pair = %v", kp)
+	}
+	if string(req.GetLoadCertificate().GetKeyPair().GetPrivateKey()) != "secret" {
+		t.Errorf("redactMsg() modified the original request")
+	}
+}
+
+func Test_redactMsgCredentials(t *testing.T) {
+	req := &file.TransferToRemoteRequest{
+		RemoteDownload: &common.RemoteDownload{
+			Path: "server:/path",
+			Credentials: &types.Credentials{
+				Username: "admin",
+				Password: &types.Credentials_Hashed{Hashed: &types.HashType{
+					Method: types.HashType_SHA256,
+					Hash:   []byte("hash"),
+				}},
+			},
+		},
+	}
+	got := redactMsg(req).(*file.TransferToRemoteRequest).GetRemoteDownload()
+	if h := got.GetCredentials().GetHashed(); string(h.GetHash()) != redacted || h.GetMethod() != types.HashType_SHA256 {
+		t.Errorf("redactMsg() hashed password = %v", h)
+	}
+	if got.GetCredentials().GetUsername() != "admin" || got.GetPath() != "server:/path" {
+		t.Errorf("redactMsg() redacted non secret fields: %v", got)
+	}
+}
@@ -0,0 +1,332 @@
+package app
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnoi/healthz"
+	"github.com/openconfig/gnoi/system"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/karimra/gnoic/api"
+	gsystem "github.com/karimra/gnoic/api/system"
+)
+
+// probes run by the exporter, in their run order
+var exporterProbes = []string{"time", "healthz", "certs", "os", "ping"}
+
+var (
+	probeSuccessDesc = prometheus.NewDesc("gnoic_probe_success",
+		"Whether the last probe of the target succeeded.", []string{"target", "probe"}, nil)
+	probeDurationDesc = prometheus.NewDesc("gnoic_probe_duration_seconds",
+		"Duration of the last probe of the target.", []string{"target", "probe"}, nil)
+	timeOffsetDesc = prometheus.NewDesc("gnoic_system_time_offset_seconds",
+		"Offset of the target clock from the local clock.", []string{"target"}, nil)
+	healthzUnhealthyDesc = prometheus.NewDesc("gnoic_healthz_unhealthy_components",
+		"Number of components of the target with a non healthy status.", []string{"target"}, nil)
+	certExpiryDesc = prometheus.NewDesc("gnoic_certificate_expiry_days",
+		"Days until the certificate expires, negative once expired.", []string{"target", "certificate_id"}, nil)
+	osInfoDesc = prometheus.NewDesc("gnoic_os_info",
+		"Running OS version of the target, always 1.", []string{"target", "version"}, nil)
+	pingRTTDesc = prometheus.NewDesc("gnoic_ping_rtt_seconds",
+		"Average round trip time of the pings sent by the target.", []string{"target", "destination"}, nil)
+	pingLossDesc = prometheus.NewDesc("gnoic_ping_loss_ratio",
+		"Ratio of the pings sent by the target without a reply.", []string{"target", "destination"}, nil)
+)
+
+// exporterCollector exposes the metrics of the last probes round.
+type exporterCollector struct {
+	m       sync.RWMutex
+	metrics []prometheus.Metric
+}
+
+func (c *exporterCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		probeSuccessDesc, probeDurationDesc, timeOffsetDesc, healthzUnhealthyDesc,
+		certExpiryDesc, osInfoDesc, pingRTTDesc, pingLossDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (c *exporterCollector) Collect(ch chan<- prometheus.Metric) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	for _, m := range c.metrics {
+		ch <- m
+	}
+}
+
+func (c *exporterCollector) set(metrics []prometheus.Metric) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.metrics = metrics
+}
+
+func (a *App) InitExporterFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	//
+	cmd.Flags().StringVar(&a.Config.ExporterListenAddress, "listen-address", ":9808", "address the metrics are served on")
+	cmd.Flags().StringVar(&a.Config.ExporterMetricsPath, "metrics-path", "/metrics", "HTTP path the metrics are served on")
+	cmd.Flags().DurationVar(&a.Config.ExporterInterval, "interval", time.Minute, "interval between probes of a target")
+	cmd.Flags().StringSliceVar(&a.Config.ExporterProbes, "probes", exporterProbes, fmt.Sprintf("probes to run, any of %q. ping runs only if --ping-destination is set", exporterProbes))
+	cmd.Flags().StringSliceVar(&a.Config.ExporterPingDestination, "ping-destination", []string{}, "destinations pinged from each target")
+	cmd.Flags().Int32Var(&a.Config.ExporterPingCount, "ping-count", 3, "number of pings sent to each destination")
+	//
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) PreRunEExporter(cmd *cobra.Command, args []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	if a.Config.ExporterInterval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	if a.Config.ExporterPingCount <= 0 {
+		return errors.New("--ping-count must be positive")
+	}
+	probes := make([]string, 0, len(a.Config.ExporterProbes))
+	for _, p := range a.Config.ExporterProbes {
+		if !sInList(p, exporterProbes) {
+			return fmt.Errorf("unknown probe %q, expected any of %q", p, exporterProbes)
+		}
+		if p == "ping" && len(a.Config.ExporterPingDestination) == 0 {
+			if cmd.Flags().Changed("probes") {
+				return errors.New("the ping probe requires --ping-destination")
+			}
+			continue
+		}
+		probes = append(probes, p)
+	}
+	a.Config.ExporterProbes = probes
+	return nil
+}
+
+func (a *App) RunEExporter(cmd *cobra.Command, args []string) error {
+	collector := new(exporterCollector)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	mux := http.NewServeMux()
+	mux.Handle(a.Config.ExporterMetricsPath, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	srv := &http.Server{
+		Addr:              a.Config.ExporterListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	a.Logger.Infof("serving metrics on %s%s, probing every %s: %v",
+		a.Config.ExporterListenAddress, a.Config.ExporterMetricsPath, a.Config.ExporterInterval, a.Config.ExporterProbes)
+
+	ctx := a.commandContext(cmd)
+	ticker := time.NewTicker(a.Config.ExporterInterval)
+	defer ticker.Stop()
+	for {
+		collector.set(a.exporterRound(ctx))
+		select {
+		case <-ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(ctx)
+		case err := <-errCh:
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// exporterRound probes all the targets and returns the resulting metrics.
+// The targets skipped by --max-failures or --canary are those of the round only.
+func (a *App) exporterRound(ctx context.Context) []prometheus.Metric {
+	a.m.Lock()
+	a.durations = nil
+	a.skipped = nil
+	a.m.Unlock()
+	targets, err := a.GetTargets()
+	if err != nil {
+		a.Logger.Errorf("failed to get targets: %v", err)
+		return nil
+	}
+	m := new(sync.Mutex)
+	metrics := make([]prometheus.Metric, 0)
+	a.runTargets(targets, func(t *api.Target) error {
+		ms, err := a.exporterProbe(ctx, t)
+		m.Lock()
+		metrics = append(metrics, ms...)
+		m.Unlock()
+		return err
+	})
+	return metrics
+}
+
+// exporterProbe runs the probes against target t, within the probing interval.
+func (a *App) exporterProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Config.ExporterInterval)
+	defer cancel()
+	name := t.Config.Name
+	metrics := make([]prometheus.Metric, 0)
+	probeResult := func(probe string, start time.Time, err error) {
+		success := 1.0
+		if err != nil {
+			success = 0
+			a.targetLogger(name).Errorf("%q %s probe failed: %v", name, probe, err)
+		}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, success, name, probe),
+			prometheus.MustNewConstMetric(probeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), name, probe),
+		)
+	}
+	err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+	if err != nil {
+		for _, p := range a.Config.ExporterProbes {
+			probeResult(p, time.Now(), err)
+		}
+		return metrics, err
+	}
+	defer t.Close()
+
+	var errs []error
+	for _, p := range a.Config.ExporterProbes {
+		start := time.Now()
+		var ms []prometheus.Metric
+		switch p {
+		case "time":
+			ms, err = a.exporterTimeProbe(ctx, t)
+		case "healthz":
+			ms, err = a.exporterHealthzProbe(ctx, t)
+		case "certs":
+			ms, err = a.exporterCertsProbe(ctx, t)
+		case "os":
+			ms, err = a.exporterOSProbe(ctx, t)
+		case "ping":
+			ms, err = a.exporterPingProbe(ctx, t)
+		}
+		metrics = append(metrics, ms...)
+		probeResult(p, start, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// exporterTimeProbe measures the target clock offset,
+// assuming the target time is read halfway through the RPC.
+func (a *App) exporterTimeProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	start := time.Now()
+	rsp, err := t.SystemClient().Time(ctx, gsystem.NewSystemTimeRequest())
+	if err != nil {
+		return nil, err
+	}
+	rtt := time.Since(start)
+	offset := time.Unix(0, int64(rsp.GetTime())).Sub(start.Add(rtt / 2))
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(timeOffsetDesc, prometheus.GaugeValue, offset.Seconds(), t.Config.Name),
+	}, nil
+}
+
+func (a *App) exporterHealthzProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	r := a.HealthzList(ctx, t)
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	unhealthy := 0
+	for _, s := range r.rsp.GetStatuses() {
+		if s.GetStatus() != healthz.Status_STATUS_HEALTHY {
+			unhealthy++
+		}
+	}
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(healthzUnhealthyDesc, prometheus.GaugeValue, float64(unhealthy), t.Config.Name),
+	}, nil
+}
+
+func (a *App) exporterCertsProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	rsp, err := a.CertGetCertificates(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	metrics := make([]prometheus.Metric, 0, len(rsp.GetCertificateInfo()))
+	for _, certInfo := range rsp.GetCertificateInfo() {
+		block, _ := pem.Decode(certInfo.GetCertificate().GetCertificate())
+		if block == nil {
+			return metrics, fmt.Errorf("certificate %q: failed to decode PEM", certInfo.GetCertificateId())
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return metrics, fmt.Errorf("certificate %q: %v", certInfo.GetCertificateId(), err)
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(certExpiryDesc, prometheus.GaugeValue,
+			time.Until(cert.NotAfter).Hours()/24, t.Config.Name, certInfo.GetCertificateId()))
+	}
+	return metrics, nil
+}
+
+func (a *App) exporterOSProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	rsp, err := a.OsVerify(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(osInfoDesc, prometheus.GaugeValue, 1, t.Config.Name, rsp.GetVersion()),
+	}, nil
+}
+
+// exporterPingProbe pings each destination from the target, the probe
+// fails if any of them fails.
+func (a *App) exporterPingProbe(ctx context.Context, t *api.Target) ([]prometheus.Metric, error) {
+	dsts := make([]string, len(a.Config.ExporterPingDestination))
+	copy(dsts, a.Config.ExporterPingDestination)
+	sort.Strings(dsts)
+	metrics := make([]prometheus.Metric, 0, 2*len(dsts))
+	var errs []error
+	for _, dst := range dsts {
+		req, err := gsystem.NewSystemPingRequest(
+			gsystem.Destination(dst),
+			gsystem.Count(a.Config.ExporterPingCount),
+		)
+		if err != nil {
+			return metrics, err
+		}
+		rsps, err := a.systemPing(ctx, t, req, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("destination %q: %v", dst, err))
+			continue
+		}
+		ms, err := pingSummaryMetrics(t.Config.Name, dst, rsps)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("destination %q: %v", dst, err))
+			continue
+		}
+		metrics = append(metrics, ms...)
+	}
+	return metrics, errors.Join(errs...)
+}
+
+// pingSummaryMetrics returns the RTT and loss metrics of the ping summary,
+// the last of the ping responses.
+func pingSummaryMetrics(target, dst string, rsps []*system.PingResponse) ([]prometheus.Metric, error) {
+	if len(rsps) == 0 || rsps[len(rsps)-1].GetSent() == 0 {
+		return nil, errors.New("missing ping summary")
+	}
+	summary := rsps[len(rsps)-1]
+	loss := 1 - float64(summary.GetReceived())/float64(summary.GetSent())
+	return []prometheus.Metric{
+		prometheus.MustNewConstMetric(pingRTTDesc, prometheus.GaugeValue,
+			time.Duration(summary.GetAvgTime()).Seconds(), target, dst),
+		prometheus.MustNewConstMetric(pingLossDesc, prometheus.GaugeValue, loss, target, dst),
+	}, nil
+}
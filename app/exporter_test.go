@@ -0,0 +1,186 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnoi/system"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+)
+
+// gatherMetrics returns the gauge values exposed by c, indexed by name and labels,
+// e.g: gnoic_ping_loss_ratio{destination=10.0.0.1,target=r1}
+func gatherMetrics(t *testing.T, c *exporterCollector) map[string]float64 {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(c)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%s", l.GetName(), l.GetValue()))
+			}
+			sort.Strings(labels)
+			values[fmt.Sprintf("%s{%s}", mf.GetName(), strings.Join(labels, ","))] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestPreRunEExporter(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantProbes []string
+		wantErr    string
+	}{
+		{
+			name:       "default_probes_without_ping_destination",
+			wantProbes: []string{"time", "healthz", "certs", "os"},
+		},
+		{
+			name:       "default_probes_with_ping_destination",
+			args:       []string{"--ping-destination", "10.0.0.1"},
+			wantProbes: []string{"time", "healthz", "certs", "os", "ping"},
+		},
+		{
+			name:    "unknown_probe",
+			args:    []string{"--probes", "time,bgp"},
+			wantErr: `unknown probe "bgp"`,
+		},
+		{
+			name:    "ping_without_destination",
+			args:    []string{"--probes", "ping"},
+			wantErr: "the ping probe requires --ping-destination",
+		},
+		{
+			name:    "zero_interval",
+			args:    []string{"--interval", "0s"},
+			wantErr: "--interval must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.Config.SetLogger(a.Logger)
+			cmd := &cobra.Command{Use: "exporter"}
+			a.InitExporterFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := a.PreRunEExporter(cmd, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("PreRunEExporter() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a.Config.ExporterProbes, tt.wantProbes) {
+				t.Errorf("probes = %v, want %v", a.Config.ExporterProbes, tt.wantProbes)
+			}
+		})
+	}
+}
+
+func Test_pingSummaryMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		rsps    []*system.PingResponse
+		want    map[string]float64
+		wantErr bool
+	}{
+		{
+			name: "summary",
+			rsps: []*system.PingResponse{
+				{Source: "10.0.0.1", Time: int64(2 * time.Millisecond), Sequence: 1},
+				{Source: "10.0.0.1", Time: int64(4 * time.Millisecond), Sequence: 2},
+				{Source: "10.0.0.1", Sent: 4, Received: 3, AvgTime: int64(3 * time.Millisecond)},
+			},
+			want: map[string]float64{
+				"gnoic_ping_rtt_seconds{destination=10.0.0.1,target=r1}": 0.003,
+				"gnoic_ping_loss_ratio{destination=10.0.0.1,target=r1}":  0.25,
+			},
+		},
+		{
+			name: "all_lost",
+			rsps: []*system.PingResponse{
+				{Source: "10.0.0.1", Sent: 3, Received: 0},
+			},
+			want: map[string]float64{
+				"gnoic_ping_rtt_seconds{destination=10.0.0.1,target=r1}": 0,
+				"gnoic_ping_loss_ratio{destination=10.0.0.1,target=r1}":  1,
+			},
+		},
+		{
+			name: "nothing_sent",
+			rsps: []*system.PingResponse{
+				{Source: "10.0.0.1", Sent: 0, Received: 0},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no_responses",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := pingSummaryMetrics("r1", "10.0.0.1", tt.rsps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("pingSummaryMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			c := new(exporterCollector)
+			c.set(ms)
+			if got := gatherMetrics(t, c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pingSummaryMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_exporterCollector(t *testing.T) {
+	c := new(exporterCollector)
+	c.set([]prometheus.Metric{
+		prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, 1, "r1", "time"),
+		prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, 1, "r2", "time"),
+		prometheus.MustNewConstMetric(certExpiryDesc, prometheus.GaugeValue, 30, "r1", "gnmi"),
+	})
+	// r2 is gone and the r1 certificate was replaced
+	c.set([]prometheus.Metric{
+		prometheus.MustNewConstMetric(probeSuccessDesc, prometheus.GaugeValue, 0, "r1", "time"),
+		prometheus.MustNewConstMetric(certExpiryDesc, prometheus.GaugeValue, 365, "r1", "gnmi2"),
+	})
+	want := map[string]float64{
+		"gnoic_probe_success{probe=time,target=r1}":                     0,
+		"gnoic_certificate_expiry_days{certificate_id=gnmi2,target=r1}": 365,
+	}
+	if got := gatherMetrics(t, c); !reflect.DeepEqual(got, want) {
+		t.Errorf("collected %v, want the last round metrics %v", got, want)
+	}
+}
+
+func Test_exporterRoundResetsSkipped(t *testing.T) {
+	a := New()
+	a.Config.SetLogger(a.Logger)
+	// skipped by the failure budget of the previous round
+	a.skipped = []string{"r1", "r2"}
+	a.exporterRound(a.ctx)
+	if len(a.skipped) != 0 {
+		t.Errorf("skipped targets kept across rounds: %v", a.skipped)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/openconfig/gnoi/factory_reset"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type factoryResetStartResponse struct {
@@ -16,6 +15,8 @@ type factoryResetStartResponse struct {
 	rsp *factory_reset.StartResponse
 }
 
+func (r *factoryResetStartResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitFactoryResetStartFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -36,37 +37,31 @@ func (a *App) RunEFactoryResetStart(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *factoryResetStartResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &factoryResetStartResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			responseChan <- a.FactoryResetStart(ctx, t)
-		}(t)
-	}
-	a.wg.Wait()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &factoryResetStartResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Address,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		return sendResponse(responseChan, a.FactoryResetStart(ctx, t))
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*factoryResetStartResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q FactoryReset Start failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -79,10 +74,7 @@ func (a *App) RunEFactoryResetStart(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) FactoryResetStart(ctx context.Context, t *api.Target) *factoryResetStartResponse {
-	req := &factory_reset.StartRequest{
-		FactoryOs: a.Config.FactoryResetStartFactoryOS,
-		ZeroFill:  a.Config.FactoryResetStartZeroFill,
-	}
+	req := a.factoryResetStartRequest()
 	a.printMsg(t.Config.Name, req)
 	fr := factory_reset.NewFactoryResetClient(t.Conn())
 	rsp, err := fr.Start(ctx, req)
@@ -94,3 +86,17 @@ func (a *App) FactoryResetStart(ctx context.Context, t *api.Target) *factoryRese
 		rsp: rsp,
 	}
 }
+
+func (a *App) factoryResetStartRequest() *factory_reset.StartRequest {
+	return &factory_reset.StartRequest{
+		FactoryOs: a.Config.FactoryResetStartFactoryOS,
+		ZeroFill:  a.Config.FactoryResetStartZeroFill,
+	}
+}
+
+func (a *App) factoryResetStartDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	return []*dryRunRequest{{
+		RPC:     factory_reset.FactoryReset_Start_FullMethodName,
+		Request: a.factoryResetStartRequest(),
+	}}, nil
+}
@@ -17,7 +17,6 @@ import (
 	"github.com/openconfig/gnoi/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -26,6 +25,8 @@ type fileGetResponse struct {
 	file []string
 }
 
+func (r *fileGetResponse) response() interface{} { return r.file }
+
 func (a *App) InitFileGetFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -47,44 +48,38 @@ func (a *App) RunEFileGet(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *fileGetResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &fileGetResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			filename, err := a.FileGet(ctx, t)
-			responseChan <- &fileGetResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &fileGetResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				file: filename,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		filename, err := a.FileGet(ctx, t)
+		return sendResponse(responseChan, &fileGetResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			file: filename,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*fileGetResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q File Get failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -93,7 +88,7 @@ func (a *App) RunEFileGet(cmd *cobra.Command, args []string) error {
 
 	for _, r := range result {
 		for _, f := range r.file {
-			a.Logger.Infof("%q file %q saved", r.TargetName, f)
+			a.targetLogger(r.TargetName).Infof("%q file %q saved", r.TargetName, f)
 		}
 	}
 	return a.handleErrs(errs)
@@ -105,7 +100,12 @@ func (a *App) FileGet(ctx context.Context, t *api.Target) ([]string, error) {
 	files := make([]string, 0, numFiles)
 	errs := make([]error, 0, numFiles)
 	for _, f := range a.Config.FileGetFile {
-		fs, err := a.fileGet(ctx, t, fileClient, f)
+		var fs []string
+		err := t.Retry(ctx, file.File_Get_FullMethodName, func(ctx context.Context) error {
+			var err error
+			fs, err = a.fileGet(ctx, t, fileClient, f)
+			return err
+		})
 		if err != nil {
 			errs = append(errs, err)
 			continue
@@ -157,20 +157,20 @@ func (a *App) fileGet(ctx context.Context, t *api.Target, fileClient file.FileCl
 			return nil, err
 		}
 
-		a.Logger.Debug(prototext.Format(getResponse))
+		a.targetLogger(t.Config.Name).Debug(prototext.Format(getResponse))
 
 		content := getResponse.GetContents()
 		if content != nil {
-			a.Logger.Infof("%q received %d bytes", t.Config.Address, len(content))
+			a.targetLogger(t.Config.Name).Infof("%q received %d bytes", t.Config.Address, len(content))
 			b.Write(content)
 			continue
 		}
 		h := getResponse.GetHash()
 		if h == nil {
-			a.Logger.Infof("%q received nil hash", t.Config.Address)
+			a.targetLogger(t.Config.Name).Infof("%q received nil hash", t.Config.Address)
 			return nil, nil
 		}
-		a.Logger.Debugf("%q received hash method %s", t.Config.Address, h.Method)
+		a.targetLogger(t.Config.Name).Debugf("%q received hash method %s", t.Config.Address, h.Method)
 		err = a.compareFileHash(t.Config.Address, b, h)
 		if err != nil {
 			return nil, fmt.Errorf("%q hash err: %v", t.Config.Address, err)
@@ -190,7 +190,7 @@ func (a *App) fileGet(ctx context.Context, t *api.Target, fileClient file.FileCl
 	}
 	defer f.Close()
 	f.Write(b.Bytes())
-	a.Logger.Debugf("%q wrote local file %q", t.Config.Address, name)
+	a.targetLogger(t.Config.Name).Debugf("%q wrote local file %q", t.Config.Address, name)
 	return files, nil
 }
 
@@ -215,11 +215,11 @@ func (a *App) compareFileHash(tName string, b *bytes.Buffer, ht *types.HashType)
 	cHash = h.Sum(nil)
 	r = bytes.Compare(cHash, ht.Hash)
 	if r != 0 {
-		a.Logger.Errorf("%q wrong Hash_%s: received: %x", tName, ht.Method.String(), ht.Hash)
-		a.Logger.Errorf("%q wrong Hash_%s: calculated: %x", tName, ht.Method.String(), cHash)
+		a.targetLogger(tName).Errorf("%q wrong Hash_%s: received: %x", tName, ht.Method.String(), ht.Hash)
+		a.targetLogger(tName).Errorf("%q wrong Hash_%s: calculated: %x", tName, ht.Method.String(), cHash)
 		return fmt.Errorf("%q wrong Hash_%s: recv: %x, calc: %x", tName, ht.Method.String(), ht.Hash, cHash)
 	}
-	a.Logger.Debugf("%q Hash_%s recv: %x", tName, ht.Method.String(), ht.Hash)
-	a.Logger.Debugf("%q Hash_%s calc: %x", tName, ht.Method.String(), cHash)
+	a.targetLogger(tName).Debugf("%q Hash_%s recv: %x", tName, ht.Method.String(), ht.Hash)
+	a.targetLogger(tName).Debugf("%q Hash_%s calc: %x", tName, ht.Method.String(), cHash)
 	return nil
 }
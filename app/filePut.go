@@ -19,7 +19,6 @@ import (
 	"github.com/openconfig/gnoi/file"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 const (
@@ -31,6 +30,8 @@ type filePutResponse struct {
 	file []string
 }
 
+func (r *filePutResponse) response() interface{} { return r.file }
+
 func (a *App) InitFilePutFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -88,44 +89,38 @@ func (a *App) RunEFilePut(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *filePutResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &filePutResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			filename, err := a.FilePut(ctx, t)
-			responseChan <- &filePutResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &filePutResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				file: filename,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		filename, err := a.FilePut(ctx, t)
+		return sendResponse(responseChan, &filePutResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			file: filename,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*filePutResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q File Put failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -134,7 +129,7 @@ func (a *App) RunEFilePut(cmd *cobra.Command, args []string) error {
 
 	for _, r := range result {
 		for _, f := range r.file {
-			a.Logger.Infof("%q file %q written successfully", r.TargetName, f)
+			a.targetLogger(r.TargetName).Infof("%q file %q written successfully", r.TargetName, f)
 		}
 	}
 	return a.handleErrs(errs)
@@ -153,22 +148,15 @@ func (a *App) FilePut(ctx context.Context, t *api.Target) ([]string, error) {
 	for _, filename := range a.Config.FilePutFile {
 		go func(filename string) {
 			defer wg.Done()
-			fi, err := os.Stat(filename)
+			remoteName, fPerm, err := a.filePutRemote(filename)
 			if err != nil {
-				errChan <- fmt.Errorf("file %q stat err: %v", filename, err)
+				errChan <- err
 				return
 			}
-			fPerm := a.Config.FilePutPermissions
-			if fPerm == 0 {
-				fPerm = decimalToOctal(uint32(fi.Mode().Perm()))
-				a.Logger.Infof("setting remote file permission to %d", fPerm)
-			}
-			var remoteName = a.Config.FilePutDst
-			if numFiles > 1 {
-				remoteName = filepath.Join(remoteName, filename)
-			}
 
-			err = a.filePut(ctx, t, fileClient, filename, remoteName, fPerm)
+			err = t.Retry(ctx, file.File_Put_FullMethodName, func(ctx context.Context) error {
+				return a.filePut(ctx, t, fileClient, filename, remoteName, fPerm)
+			})
 			if err != nil {
 				errChan <- err
 				return
@@ -196,13 +184,32 @@ func (a *App) FilePut(ctx context.Context, t *api.Target) ([]string, error) {
 	return files, err
 }
 
+// filePutRemote returns the remote file name and permissions of a local file.
+func (a *App) filePutRemote(filename string) (string, uint32, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return "", 0, fmt.Errorf("file %q stat err: %v", filename, err)
+	}
+	fPerm := a.Config.FilePutPermissions
+	if fPerm == 0 {
+		fPerm = decimalToOctal(uint32(fi.Mode().Perm()))
+		a.Logger.Infof("setting remote file permission to %d", fPerm)
+	}
+	var remoteName = a.Config.FilePutDst
+	if len(a.Config.FilePutFile) > 1 {
+		remoteName = filepath.Join(remoteName, filename)
+	}
+	return remoteName, fPerm, nil
+}
+
 func (a *App) filePut(ctx context.Context, t *api.Target, fileClient file.FileClient, localFile, remote string, perm uint32) error {
 	// open local file
 	f, err := os.Open(localFile)
 	if err != nil {
-		a.Logger.Errorf("failed opening file %q: %v", localFile, err)
+		a.targetLogger(t.Config.Name).Errorf("failed opening file %q: %v", localFile, err)
 		return err
 	}
+	defer f.Close()
 	// start stream
 	stream, err := fileClient.Put(ctx)
 	if err != nil {
@@ -246,20 +253,20 @@ func (a *App) filePut(ctx context.Context, t *api.Target, fileClient file.FileCl
 			break
 		}
 		h.Write(b[:n])
-		a.Logger.Debugf("%q file=%q, remote=%q writing %d byte(s)", t.Config.Address, localFile, remote, n)
+		a.targetLogger(t.Config.Name).Debugf("%q file=%q, remote=%q writing %d byte(s)", t.Config.Address, localFile, remote, n)
 		reqContents := &file.PutRequest{
 			Request: &file.PutRequest_Contents{
 				Contents: b[:n],
 			},
 		}
-		a.Logger.Debug(reqContents)
+		a.targetLogger(t.Config.Name).Debug(reqContents)
 		err = stream.Send(reqContents)
 		if err != nil {
 			return err
 		}
 	}
 	// send Hash
-	a.Logger.Infof("%q sending file=%q hash", t.Config.Address, localFile)
+	a.targetLogger(t.Config.Name).Infof("%q sending file=%q hash", t.Config.Address, localFile)
 	reqHash, err := gfile.NewPutHashRequest(
 		gfile.Hash(a.Config.FilePutHashMethod, h.Sum(nil)),
 	)
@@ -275,3 +282,39 @@ func (a *App) filePut(ctx context.Context, t *api.Target, fileClient file.FileCl
 	a.printMsg(t.Config.Name, rsp)
 	return err
 }
+
+func (a *App) filePutDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	reqs := make([]*dryRunRequest, 0, 3*len(a.Config.FilePutFile))
+	for _, filename := range a.Config.FilePutFile {
+		remoteName, fPerm, err := a.filePutRemote(filename)
+		if err != nil {
+			return nil, err
+		}
+		req, err := gfile.NewPutOpenRequest(
+			gfile.Permissions(fPerm),
+			gfile.FileName(remoteName),
+		)
+		if err != nil {
+			return nil, err
+		}
+		n, sum, err := dryRunContent(filename, a.Config.FilePutChunkSize, a.Config.FilePutHashMethod)
+		if err != nil {
+			return nil, err
+		}
+		reqHash, err := gfile.NewPutHashRequest(
+			gfile.Hash(a.Config.FilePutHashMethod, sum),
+		)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs,
+			&dryRunRequest{RPC: file.File_Put_FullMethodName, Request: req},
+			&dryRunRequest{
+				RPC:  file.File_Put_FullMethodName,
+				Note: fmt.Sprintf("%d contents request(s) of up to %d byte(s) from %q", n, a.Config.FilePutChunkSize, filename),
+			},
+			&dryRunRequest{RPC: file.File_Put_FullMethodName, Request: reqHash},
+		)
+	}
+	return reqs, nil
+}
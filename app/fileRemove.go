@@ -9,7 +9,6 @@ import (
 	"github.com/openconfig/gnoi/file"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type fileRemoveResponse struct {
@@ -17,6 +16,8 @@ type fileRemoveResponse struct {
 	file []string
 }
 
+func (r *fileRemoveResponse) response() interface{} { return r.file }
+
 func (a *App) InitFileRemoveFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -36,44 +37,38 @@ func (a *App) RunEFileRemove(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *fileRemoveResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &fileRemoveResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			filename, err := a.FileRemove(ctx, t)
-			responseChan <- &fileRemoveResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &fileRemoveResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				file: filename,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		filename, err := a.FileRemove(ctx, t)
+		return sendResponse(responseChan, &fileRemoveResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			file: filename,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*fileRemoveResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q File Remove failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -82,7 +77,7 @@ func (a *App) RunEFileRemove(cmd *cobra.Command, args []string) error {
 
 	for _, r := range result {
 		for _, f := range r.file {
-			a.Logger.Infof("%q file %q removed successfully", r.TargetName, f)
+			a.targetLogger(r.TargetName).Infof("%q file %q removed successfully", r.TargetName, f)
 		}
 	}
 	return a.handleErrs(errs)
@@ -110,7 +105,7 @@ func (a *App) fileRemove(ctx context.Context, t *api.Target, fileClient file.Fil
 		return err
 	}
 	if isDir {
-		a.Logger.Debugf("%q remote=%q is a directory", t.Config.Address, path)
+		a.targetLogger(t.Config.Name).Debugf("%q remote=%q is a directory", t.Config.Address, path)
 		r, err := fileClient.Stat(ctx, &file.StatRequest{Path: path})
 		if err != nil {
 			return err
@@ -119,7 +114,7 @@ func (a *App) fileRemove(ctx context.Context, t *api.Target, fileClient file.Fil
 			return fmt.Errorf("%q path %q is an empty directory", t.Config.Address, path)
 		}
 		for _, s := range r.Stats {
-			a.Logger.Debugf("%q removing file %q", t.Config.Address, s.Path)
+			a.targetLogger(t.Config.Name).Debugf("%q removing file %q", t.Config.Address, s.Path)
 			err = a.fileRemove(ctx, t, fileClient, s.Path)
 			if err != nil {
 				return err
@@ -127,7 +122,19 @@ func (a *App) fileRemove(ctx context.Context, t *api.Target, fileClient file.Fil
 		}
 		return nil
 	}
-	a.Logger.Debugf("%q remote=%q is a file", t.Config.Address, path)
+	a.targetLogger(t.Config.Name).Debugf("%q remote=%q is a file", t.Config.Address, path)
 	_, err = fileClient.Remove(ctx, &file.RemoveRequest{RemoteFile: path})
 	return err
 }
+
+func (a *App) fileRemoveDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	reqs := make([]*dryRunRequest, 0, len(a.Config.FileRemovePath))
+	for _, path := range a.Config.FileRemovePath {
+		reqs = append(reqs, &dryRunRequest{
+			RPC:     file.File_Remove_FullMethodName,
+			Request: &file.RemoveRequest{RemoteFile: path},
+			Note:    "if the remote path is a directory, each of its files is removed instead",
+		})
+	}
+	return reqs, nil
+}
@@ -3,7 +3,6 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
@@ -16,7 +15,6 @@ import (
 	"github.com/openconfig/gnoi/file"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -25,6 +23,8 @@ type fileStatResponse struct {
 	rsp []*fileStatInfo
 }
 
+func (r *fileStatResponse) response() interface{} { return r.rsp }
+
 type fileStatInfo struct {
 	StatInfo *file.StatInfo `json:"stat-info,omitempty"`
 	IsDir    bool           `json:"is-dir,omitempty"`
@@ -51,64 +51,44 @@ func (a *App) RunEFileStat(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *fileStatResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &fileStatResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.FileStat(ctx, t)
-			responseChan <- &fileStatResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &fileStatResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.FileStat(ctx, t)
+		return sendResponse(responseChan, &fileStatResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*fileStatResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q File Stat failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 		result = append(result, rsp)
 	}
-	switch a.Config.Format {
-	case "json":
-		for _, r := range result {
-			tRsp := targetResponse{
-				Target:   r.TargetName,
-				Response: r.rsp,
-			}
-			b, err := json.MarshalIndent(tRsp, "", "  ")
-			if err != nil {
-				a.Logger.Errorf("failed to marshal file state response from %q: %v", r.TargetName, err)
-				continue
-			}
-			fmt.Println(string(b))
-		}
-	default:
+	if a.textOutput() {
 		fmt.Print(a.statTable(result))
 	}
 
@@ -135,13 +115,13 @@ func (a *App) fileStat(ctx context.Context, t *api.Target, fileClient file.FileC
 	if err != nil {
 		return nil, fmt.Errorf("%q file %q stat err: %v", t.Config.Address, path, err)
 	}
-	a.Logger.Debugf("%q File Stat Response:\n%s", t.Config.Address, prototext.Format(r))
+	a.targetLogger(t.Config.Name).Debugf("%q File Stat Response:\n%s", t.Config.Address, prototext.Format(r))
 	a.printMsg(t.Config.Name, r)
 	rsps := make([]*fileStatInfo, 0, len(r.Stats))
 	for _, si := range r.Stats {
 		isDir, err := a.isDir(ctx, fileClient, si.Path)
 		if err != nil {
-			a.Logger.Errorf("%q file %q isDir err: %v", t.Config.Address, path, err)
+			a.targetLogger(t.Config.Name).Errorf("%q file %q isDir err: %v", t.Config.Address, path, err)
 			continue
 		}
 
@@ -153,11 +133,11 @@ func (a *App) fileStat(ctx context.Context, t *api.Target, fileClient file.FileC
 		if isDir && a.Config.FileStatRecursive {
 			fsi, err := a.fileStat(ctx, t, fileClient, si.Path)
 			if err != nil {
-				a.Logger.Errorf("%q file %q stat err: %v", t.Config.Address, si.Path, err)
+				a.targetLogger(t.Config.Name).Errorf("%q file %q stat err: %v", t.Config.Address, si.Path, err)
 				continue
 			}
 			for _, fs := range fsi {
-				a.Logger.Debugf("%q adding file %q", t.Config.Address, fs.StatInfo.Path)
+				a.targetLogger(t.Config.Name).Debugf("%q adding file %q", t.Config.Address, fs.StatInfo.Path)
 				rsps = append(rsps, fs)
 			}
 		}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/karimra/gnoic/config"
+)
+
+// destructive returns true if the command disrupts or erases the targets state,
+// it is then subject to confirmation, --max-targets and the policy file.
+func (a *App) destructive() bool {
+	switch a.Config.Command() {
+	case "factory-reset-start",
+		"file-remove",
+		"os-activate",
+		"system-kill-process",
+		"system-reboot":
+		return true
+	case "cert-revoke":
+		return a.Config.CertRevokeCertificatesAll
+	}
+	return false
+}
+
+// Guard checks a destructive command against --max-targets and the policy file,
+// then asks for confirmation before running it, unless --yes or --dry-run is set.
+// The command runs against the targets that were checked,
+// the targets are not resolved again in case the inventory changed in between.
+func (a *App) Guard(cmd *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	if !a.destructive() {
+		return runE(cmd, args)
+	}
+	targets, err := a.Config.GetTargets()
+	if err != nil {
+		return err
+	}
+	a.guardedTargets = targets
+	defer func() { a.guardedTargets = nil }()
+	maxTargets := a.Config.MaxTargets
+	if a.Config.PolicyFile != "" {
+		p, err := config.LoadPolicy(a.Config.PolicyFile)
+		if err != nil {
+			return err
+		}
+		if maxTargets == 0 {
+			maxTargets = p.MaxTargets
+		}
+		violations, err := a.Config.CheckPolicy(p, a.Config.Command(), targets, time.Now())
+		if err != nil {
+			return err
+		}
+		if len(violations) > 0 {
+			for _, v := range violations {
+				a.Logger.Error(v)
+			}
+			return fmt.Errorf("%q refused: %d policy violation(s)", cmd.CommandPath(), len(violations))
+		}
+	}
+	if maxTargets > 0 && len(targets) > maxTargets {
+		return fmt.Errorf("%q refused: %d targets selected, max-targets is %d", cmd.CommandPath(), len(targets), maxTargets)
+	}
+	if a.Config.Yes || a.Config.DryRun {
+		return runE(cmd, args)
+	}
+	names := make([]string, 0, len(targets))
+	for n := range targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	ok, err := confirm(fmt.Sprintf("%q will run against %d target(s):\n  %s\n",
+		cmd.CommandPath(), len(names), strings.Join(names, "\n  ")))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("aborted")
+	}
+	return runE(cmd, args)
+}
+
+// confirm prints msg and waits for the user confirmation on the terminal.
+func confirm(msg string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("cannot ask for confirmation: stdin is not a terminal, use --yes to skip it")
+	}
+	fmt.Fprintf(os.Stderr, "%sContinue? [y/N]: ", msg)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestGuardTargets(t *testing.T) {
+	root := &cobra.Command{Use: "gnoic"}
+	system := &cobra.Command{Use: "system"}
+	reboot := &cobra.Command{Use: "reboot"}
+	root.AddCommand(system)
+	system.AddCommand(reboot)
+
+	a := New()
+	a.Config.SetLogger(a.Logger)
+	a.Config.SetCommand(reboot)
+	a.Config.Address = []string{"r1:57400", "r2:57400"}
+	a.Config.MaxTargets = 2
+	a.Config.Yes = true
+	var got []string
+	err := a.Guard(reboot, nil, func(*cobra.Command, []string) error {
+		// the targets change after the guard checks
+		a.Config.Address = []string{"r1:57400", "r2:57400", "r3:57400"}
+		targets, err := a.GetTargets()
+		if err != nil {
+			return err
+		}
+		for n := range targets {
+			got = append(got, n)
+		}
+		sort.Strings(got)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"r1:57400", "r2:57400"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("command ran against %v, want the guarded targets %v", got, want)
+	}
+	if a.guardedTargets != nil {
+		t.Errorf("guarded targets kept after the command: %v", a.guardedTargets)
+	}
+}
@@ -2,13 +2,11 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/openconfig/gnoi/healthz"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	ghealthz "github.com/karimra/gnoic/api/healthz"
@@ -19,6 +17,8 @@ type healthzAckResponse struct {
 	rsp *healthz.AcknowledgeResponse
 }
 
+func (r *healthzAckResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitHealthzAckFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -39,37 +39,31 @@ func (a *App) RunEHealthzAck(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *healthzAckResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &healthzAckResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			responseChan <- a.HealthAck(ctx, t)
-		}(t)
-	}
-	a.wg.Wait()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &healthzAckResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Address,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		return sendResponse(responseChan, a.HealthAck(ctx, t))
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*healthzAckResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Healthz Acknowledge failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -77,18 +71,12 @@ func (a *App) RunEHealthzAck(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, r := range result {
-		fmt.Printf("target %q:\n", r.TargetName)
 		a.printMsg(r.TargetName, r.rsp)
-		switch a.Config.Format {
-		case "json":
-			b, err := json.MarshalIndent(r.rsp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal to JSON: %v", err)
-			}
-			fmt.Println(string(b))
-		default:
-			fmt.Println(a.healthzGetTree(r.rsp.GetStatus(), "  "))
+		if !a.textOutput() {
+			continue
 		}
+		fmt.Printf("target %q:\n", r.TargetName)
+		fmt.Println(a.healthzGetTree(r.rsp.GetStatus(), "  "))
 	}
 	return a.handleErrs(errs)
 }
@@ -7,10 +7,8 @@ import (
 	"os"
 
 	"github.com/openconfig/gnoi/healthz"
-	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 
 	"github.com/karimra/gnoic/api"
@@ -22,6 +20,8 @@ type healthzArtifactResponse struct {
 	rsp *healthz.ArtifactResponse
 }
 
+func (r *healthzArtifactResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitHealthzArtifactFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -41,37 +41,31 @@ func (a *App) RunEHealthzArtifact(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *healthzArtifactResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &healthzArtifactResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			responseChan <- a.HealthArtifact(ctx, t)
-		}(t)
-	}
-	a.wg.Wait()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &healthzArtifactResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Address,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		return sendResponse(responseChan, a.HealthArtifact(ctx, t))
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*healthzArtifactResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Healthz Artifact failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -153,8 +147,9 @@ func (a *App) HealthArtifact(ctx context.Context, t *api.Target) *healthzArtifac
 
 func (a *App) handleFileArtifact(targetName string, h *healthz.ArtifactResponse_Header, stream healthz.Healthz_ArtifactClient) error {
 	id := h.Header.GetId()
-	log.Infof("%s: received file header for artifactID: %s", targetName, id)
-	fmt.Println(prototext.Format(h.Header))
+	logger := a.targetLogger(targetName)
+	logger.Infof("received file header for artifactID: %s", id)
+	logger.Debugf("artifact header:\n%s", prototext.Format(h.Header))
 
 	b := new(bytes.Buffer)
 	for {
@@ -164,14 +159,14 @@ func (a *App) handleFileArtifact(targetName string, h *healthz.ArtifactResponse_
 		}
 		switch content := rsp.GetContents().(type) {
 		case *healthz.ArtifactResponse_Trailer:
-			log.Infof("%s: received trailer for artifactID: %s", targetName, id)
-			log.Infof("%s: received %d bytes in total", targetName, b.Len())
-			log.Infof("%s: comparing file HASH", targetName)
+			logger.Infof("received trailer for artifactID: %s", id)
+			logger.Infof("received %d bytes in total", b.Len())
+			logger.Infof("comparing file HASH")
 			err = a.compareFileHash(targetName, b, h.Header.GetFile().GetHash())
 			if err != nil {
 				return fmt.Errorf("%s: hash err: %v", targetName, err)
 			}
-			log.Infof("%s: HASH OK", targetName)
+			logger.Infof("HASH OK")
 			fi, err := os.Create(h.Header.GetFile().GetName())
 			if err != nil {
 				return err
@@ -180,7 +175,7 @@ func (a *App) handleFileArtifact(targetName string, h *healthz.ArtifactResponse_
 			_, err = fi.Write(b.Bytes())
 			return err
 		case *healthz.ArtifactResponse_Bytes:
-			log.Infof("received %d bytes for artifactID: %s", len(content.Bytes), id)
+			logger.Infof("received %d bytes for artifactID: %s", len(content.Bytes), id)
 			_, err = b.Write(content.Bytes)
 			if err != nil {
 				return err
@@ -193,16 +188,18 @@ func (a *App) handleFileArtifact(targetName string, h *healthz.ArtifactResponse_
 
 func (a *App) handleCustomArtifact(targetName string, h *healthz.ArtifactResponse_Header, _ healthz.Healthz_ArtifactClient) error {
 	id := h.Header.GetId()
-	log.Infof("%s: received custom header for artifactID: %s", targetName, id)
-	fmt.Println(prototext.Format(h.Header))
+	logger := a.targetLogger(targetName)
+	logger.Infof("received custom header for artifactID: %s", id)
+	logger.Debugf("artifact header:\n%s", prototext.Format(h.Header))
 	//
 	return nil
 }
 
 func (a *App) handleProtoArtifact(targetName string, h *healthz.ArtifactResponse_Header, _ healthz.Healthz_ArtifactClient) error {
 	id := h.Header.GetId()
-	log.Infof("%s: received proto header for artifactID: %s", targetName, id)
-	fmt.Println(prototext.Format(h.Header))
+	logger := a.targetLogger(targetName)
+	logger.Infof("received proto header for artifactID: %s", id)
+	logger.Debugf("artifact header:\n%s", prototext.Format(h.Header))
 	//
 	return nil
 }
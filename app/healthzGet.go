@@ -3,14 +3,12 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/openconfig/gnoi/healthz"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 
 	"github.com/karimra/gnoic/api"
@@ -23,6 +21,8 @@ type healthzGetResponse struct {
 	rsp *healthz.GetResponse
 }
 
+func (r *healthzGetResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitHealthzFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -50,37 +50,31 @@ func (a *App) RunEHealthzGet(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *healthzGetResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &healthzGetResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			responseChan <- a.HealthzGet(ctx, t)
-		}(t)
-	}
-	a.wg.Wait()
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
+
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &healthzGetResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Address,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		return sendResponse(responseChan, a.HealthzGet(ctx, t))
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*healthzGetResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Healthz Get failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -88,18 +82,12 @@ func (a *App) RunEHealthzGet(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, r := range result {
-		fmt.Printf("target %q:\n", r.TargetName)
 		a.printMsg(r.TargetName, r.rsp)
-		switch a.Config.Format {
-		case "json":
-			b, err := json.MarshalIndent(r.rsp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal to JSON: %v", err)
-			}
-			fmt.Println(string(b))
-		default:
-			fmt.Println(a.healthzGetTree(r.rsp.GetComponent(), "  "))
+		if !a.textOutput() {
+			continue
 		}
+		fmt.Printf("target %q:\n", r.TargetName)
+		fmt.Println(a.healthzGetTree(r.rsp.GetComponent(), "  "))
 	}
 	return a.handleErrs(errs)
 }
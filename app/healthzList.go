@@ -3,7 +3,6 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 
@@ -11,7 +10,6 @@ import (
 	"github.com/openconfig/gnoi/healthz"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	ghealthz "github.com/karimra/gnoic/api/healthz"
@@ -23,6 +21,8 @@ type healthzListResponse struct {
 	rsp *healthz.ListResponse
 }
 
+func (r *healthzListResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitHealthzListFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -43,37 +43,31 @@ func (a *App) RunEHealthzList(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *healthzListResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &healthzListResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			responseChan <- a.HealthzList(ctx, t)
-		}(t)
-	}
-	a.wg.Wait()
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &healthzListResponse{
+				TargetError: TargetError{
+					TargetName: t.Config.Address,
+					Err:        err,
+				},
+			})
+		}
+		defer t.Close()
+		return sendResponse(responseChan, a.HealthzList(ctx, t))
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*healthzListResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Healthz List failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -81,22 +75,16 @@ func (a *App) RunEHealthzList(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, r := range result {
-		fmt.Printf("target %q:\n", r.TargetName)
 		a.printMsg(r.TargetName, r.rsp)
-		switch a.Config.Format {
-		case "json":
-			b, err := json.MarshalIndent(r.rsp, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal to JSON: %v", err)
-			}
-			fmt.Println(string(b))
-		default:
-			s, err := healthzListTable(result)
-			if err != nil {
-				return err
-			}
-			fmt.Println(s)
+		if !a.textOutput() {
+			continue
+		}
+		fmt.Printf("target %q:\n", r.TargetName)
+		s, err := healthzListTable(result)
+		if err != nil {
+			return err
 		}
+		fmt.Println(s)
 	}
 	return a.handleErrs(errs)
 }
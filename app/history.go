@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func (a *App) InitHistoryFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+	//
+	cmd.Flags().StringVar(&a.Config.HistoryTarget, "target", "", "only show the commands run against this target name or address, glob patterns are supported")
+	cmd.Flags().StringVar(&a.Config.HistoryCommand, "command", "", "only show this command, e.g: system-reboot, glob patterns are supported")
+	cmd.Flags().StringVar(&a.Config.HistoryFrom, "from", "", "only show the commands started after this time, RFC3339 or a duration ago, e.g: 24h")
+	cmd.Flags().StringVar(&a.Config.HistoryTo, "to", "", "only show the commands started before this time, RFC3339 or a duration ago")
+	cmd.Flags().IntVar(&a.Config.HistoryLast, "last", 0, "only show the last N matching commands")
+	//
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func (a *App) PreRunEHistory(cmd *cobra.Command, args []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	if a.Config.HistoryLast < 0 {
+		return errors.New("--last must be positive")
+	}
+	if _, err := parseHistoryTime(a.Config.HistoryFrom, time.Now()); err != nil {
+		return err
+	}
+	_, err := parseHistoryTime(a.Config.HistoryTo, time.Now())
+	return err
+}
+
+func (a *App) RunEHistory(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	from, _ := parseHistoryTime(a.Config.HistoryFrom, now)
+	to, _ := parseHistoryTime(a.Config.HistoryTo, now)
+	entries, err := readJournal(a.journalFile(), func(e *journalEntry) bool {
+		return e.matches(a.Config.HistoryTarget, a.Config.HistoryCommand, from, to)
+	})
+	if err != nil {
+		return err
+	}
+	if a.Config.HistoryLast > 0 && len(entries) > a.Config.HistoryLast {
+		entries = entries[len(entries)-a.Config.HistoryLast:]
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	switch a.Config.Format {
+	default:
+		fmt.Print(historyTable(entries))
+	case "json":
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "ndjson":
+		for _, e := range entries {
+			err = enc.Encode(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseHistoryTime parses an RFC3339 time or a duration before now,
+// a zero time is returned for an empty string.
+func parseHistoryTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 or a duration", s)
+	}
+	return t, nil
+}
+
+// readJournal returns the journal entries for which keep returns true, oldest first.
+func readJournal(filename string, keep func(e *journalEntry) bool) ([]*journalEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	entries := make([]*journalEntry, 0)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		e := new(journalEntry)
+		err = json.Unmarshal(sc.Bytes(), e)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
+
+func (e *journalEntry) matches(target, command string, from, to time.Time) bool {
+	if !from.IsZero() && e.Start.Before(from) {
+		return false
+	}
+	if !to.IsZero() && e.Start.After(to) {
+		return false
+	}
+	if command != "" {
+		if ok, _ := path.Match(command, e.Command); !ok {
+			return false
+		}
+	}
+	if target == "" {
+		return true
+	}
+	for _, t := range e.Targets {
+		for _, v := range []string{t.Name, t.Address} {
+			if ok, _ := path.Match(target, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func historyTable(entries []*journalEntry) string {
+	tabData := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		targets := make([]string, 0, len(e.Targets))
+		for _, t := range e.Targets {
+			s := t.Name
+			if t.Status != "" && t.Status != statusSuccess {
+				s += " (" + t.Status + ")"
+			}
+			targets = append(targets, s)
+		}
+		tabData = append(tabData, []string{
+			e.Start.Local().Format(time.RFC3339),
+			e.Command,
+			strings.Join(targets, "\n"),
+			fmt.Sprintf("%d", e.ExitCode),
+			e.End.Sub(e.Start).Round(time.Millisecond).String(),
+			e.User + "@" + e.Hostname,
+		})
+	}
+	b := new(bytes.Buffer)
+	table := tablewriter.NewWriter(b)
+	table.SetHeader([]string{"Start", "Command", "Targets", "Exit Code", "Duration", "User"})
+	formatTable(table)
+	table.AppendBulk(tabData)
+	table.Render()
+	return b.String()
+}
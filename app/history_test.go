@@ -0,0 +1,43 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_journalEntryMatches(t *testing.T) {
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	e := &journalEntry{
+		Start:   start,
+		Command: "system-reboot",
+		Targets: []*journalTarget{
+			{Name: "spine1", Address: "10.0.0.1:57400"},
+			{Name: "spine2", Address: "10.0.0.2:57400"},
+		},
+	}
+	tests := []struct {
+		name    string
+		target  string
+		command string
+		from    time.Time
+		to      time.Time
+		want    bool
+	}{
+		{name: "all", want: true},
+		{name: "target_name", target: "spine2", want: true},
+		{name: "target_address_glob", target: "10.0.0.1:*", want: true},
+		{name: "other_target", target: "leaf1", want: false},
+		{name: "command_glob", command: "system-*", want: true},
+		{name: "other_command", command: "file-put", want: false},
+		{name: "in_range", from: start.Add(-time.Hour), to: start.Add(time.Hour), want: true},
+		{name: "before_range", from: start.Add(time.Minute), want: false},
+		{name: "after_range", to: start.Add(-time.Minute), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.matches(tt.target, tt.command, tt.from, tt.to); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
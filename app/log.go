@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var logFormats = []string{"text", "json"}
+
+// setLogger configures the logger from the --log-file, --log-format, --log-level
+// and --debug flags. The log messages are written to stderr, or to the log file,
+// never to stdout which only carries the command results.
+// Each log line carries the command name.
+func (a *App) setLogger() error {
+	level := log.InfoLevel
+	if a.Config.LogLevel != "" {
+		var err error
+		level, err = log.ParseLevel(a.Config.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Config.Debug {
+		level = log.DebugLevel
+	}
+	logger := log.New()
+	logger.SetLevel(level)
+	switch a.Config.LogFormat {
+	case "json":
+		logger.SetFormatter(&log.JSONFormatter{})
+	case "", "text":
+		logger.SetFormatter(&log.TextFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q, expected one of %q", a.Config.LogFormat, logFormats)
+	}
+	logger.SetOutput(os.Stderr)
+	if a.Config.LogFile != "" {
+		// the log file is kept open across the interactive shell commands
+		if a.logFile == nil || a.logFile.Name() != a.Config.LogFile {
+			f, err := os.OpenFile(a.Config.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to open log file: %v", err)
+			}
+			if a.logFile != nil {
+				a.logFile.Close()
+			}
+			a.logFile = f
+		}
+		logger.SetOutput(a.logFile)
+	}
+	a.Logger = log.NewEntry(logger)
+	if cmd := a.Config.Command(); cmd != "" {
+		a.Logger = a.Logger.WithField("command", cmd)
+	}
+	return nil
+}
+
+// targetLogger returns the logger of the target with the given name or address.
+func (a *App) targetLogger(name string) *log.Entry {
+	if n, ok := a.targetNames.Load(name); ok {
+		name = n.(string)
+	}
+	return a.Logger.WithField("target", name)
+}
+
+// rpcLogger returns the logger of the target for an RPC, e.g: /gnoi.system.System/Reboot.
+func (a *App) rpcLogger(name, rpc string) *log.Entry {
+	return a.targetLogger(name).WithField("rpc", rpc)
+}
+
+// logUnaryInterceptor logs the unary RPCs status and duration at debug level.
+func (a *App) logUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		a.logRPC(cc.Target(), method, start, err)
+		return err
+	}
+}
+
+// logStreamInterceptor logs the streaming RPCs creation status at debug level.
+func (a *App) logStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		a.logRPC(cc.Target(), method, start, err)
+		return s, err
+	}
+}
+
+func (a *App) logRPC(address, method string, start time.Time, err error) {
+	a.rpcLogger(address, method).
+		WithField("code", status.Code(err).String()).
+		WithField("duration", time.Since(start).String()).
+		Debug("RPC done")
+}
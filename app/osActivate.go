@@ -9,7 +9,6 @@ import (
 	gnoios "github.com/openconfig/gnoi/os"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type osActivateResponse struct {
@@ -17,6 +16,8 @@ type osActivateResponse struct {
 	rsp *gnoios.ActivateResponse
 }
 
+func (r *osActivateResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitOSActivateFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -39,43 +40,37 @@ func (a *App) RunEOSActivate(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *osActivateResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &osActivateResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Name,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.OsActivate(ctx, t)
-			responseChan <- &osActivateResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &osActivateResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Name,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.OsActivate(ctx, t)
+		return sendResponse(responseChan, &osActivateResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 	errs := make([]error, 0, numTargets)
 	result := make([]*osActivateResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Os Activate failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -83,20 +78,35 @@ func (a *App) RunEOSActivate(cmd *cobra.Command, args []string) error {
 		a.printMsg(rsp.TargetName, rsp.rsp)
 	}
 	for _, r := range result {
-		a.Logger.Infof("target %q activate response %q", r.TargetName, r.rsp)
+		a.targetLogger(r.TargetName).Infof("target %q activate response %q", r.TargetName, r.rsp)
 	}
 	return a.handleErrs(errs)
 }
 
 func (a *App) OsActivate(ctx context.Context, t *api.Target) (*gnoios.ActivateResponse, error) {
-	req, err := gos.NewActivateRequest(
+	req, err := a.osActivateRequest()
+	if err != nil {
+		return nil, err
+	}
+	a.printMsg(t.Config.Name, req)
+	return gnoios.NewOSClient(t.Conn()).Activate(ctx, req)
+}
+
+func (a *App) osActivateRequest() (*gnoios.ActivateRequest, error) {
+	return gos.NewActivateRequest(
 		gos.Version(a.Config.OsActivateVersion),
 		gos.StandbySupervisor(a.Config.OsActivateStandbySupervisor),
 		gos.NoReboot(a.Config.OsActivateNoReboot),
 	)
+}
+
+func (a *App) osActivateDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	req, err := a.osActivateRequest()
 	if err != nil {
 		return nil, err
 	}
-	a.printMsg(t.Config.Name, req)
-	return gnoios.NewOSClient(t.Conn()).Activate(ctx, req)
+	return []*dryRunRequest{{
+		RPC:     gnoios.OS_Activate_FullMethodName,
+		Request: req,
+	}}, nil
 }
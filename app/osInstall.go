@@ -14,14 +14,15 @@ import (
 	gnoios "github.com/openconfig/gnoi/os"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type osInstallResponse struct {
 	TargetError
-	// rsp *gnoios.InstallResponse
+	rsp *gnoios.InstallResponse
 }
 
+func (r *osInstallResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitOSInstallFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -55,103 +56,100 @@ func (a *App) RunEOSInstall(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *osInstallResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &osInstallResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Name,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			a.Logger.Infof("starting install RPC")
-			err = a.OsInstall(ctx, t)
-			responseChan <- &osInstallResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &osInstallResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Name,
 					Err:        err,
 				},
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		a.targetLogger(t.Config.Name).Infof("starting install RPC")
+		var rsp *gnoios.InstallResponse
+		err = t.Retry(ctx, gnoios.OS_Install_FullMethodName, func(ctx context.Context) error {
+			var err error
+			rsp, err = a.OsInstall(ctx, t)
+			return err
+		})
+		return sendResponse(responseChan, &osInstallResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
+	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
-			fmt.Printf("%+v\n", rsp)
+			wErr := fmt.Errorf("%q OS Install failed: %v", rsp.TargetName, rsp.Err)
+			a.targetLogger(rsp.TargetName).Error(wErr)
+			errs = append(errs, wErr)
+			continue
 		}
+		a.targetLogger(rsp.TargetName).Infof("%q OS Install validated version %q", rsp.TargetName, rsp.rsp.GetValidated().GetVersion())
 	}
-	return nil
+	return a.handleErrs(errs)
 }
 
-func (a *App) OsInstall(ctx context.Context, t *api.Target) error {
+func (a *App) OsInstall(ctx context.Context, t *api.Target) (*gnoios.InstallResponse, error) {
 	// start stream
 	osc := gnoios.NewOSClient(t.Conn())
 	osInstallClient, err := osc.Install(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.Logger.Infof("target %q: starting Install stream", t.Config.Name)
+	a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Infof("target %q: starting Install stream", t.Config.Name)
 
-	pkgInfo, err := os.Stat(a.Config.OsInstallPackage)
-	if err != nil {
-		return err
-	}
-	req, err := gos.NewOSInstallTransferRequest(
-		gos.Version(a.Config.OsInstallVersion),
-		gos.StandbySupervisor(a.Config.OsInstallStandbySupervisor),
-		gos.PackageSize(uint64(pkgInfo.Size())),
-	)
+	req, err := a.osInstallTransferRequest()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	a.printMsg(t.Config.Name, req)
 	err = osInstallClient.Send(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 RCV:
-	a.Logger.Debugf("target %q: OS Install stream rcv...", t.Config.Name)
+	a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Debugf("target %q: OS Install stream rcv...", t.Config.Name)
 	rsp, err := osInstallClient.Recv()
 	if err != nil {
-		a.Logger.Debugf("target %q: OS Install stream rcv err: %v", t.Config.Name, err)
-		return err
+		a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Debugf("target %q: OS Install stream rcv err: %v", t.Config.Name, err)
+		return nil, err
 	}
-	a.Logger.Debugf("target %q: OS Install stream got: %+v", t.Config.Name, rsp)
+	a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Debugf("target %q: OS Install stream got: %+v", t.Config.Name, rsp)
 	a.printMsg(t.Config.Name, rsp)
-	switch rsp := rsp.GetResponse().(type) {
+	switch r := rsp.GetResponse().(type) {
 	case *gnoios.InstallResponse_TransferReady:
 		err = a.osInstallTransferContent(ctx, t, osInstallClient)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		a.Logger.Debugf("target %q: sent transfer end...", t.Config.Name)
+		a.targetLogger(t.Config.Name).Debugf("target %q: sent transfer end...", t.Config.Name)
 		goto RCV
 	case *gnoios.InstallResponse_Validated:
-		a.Logger.Debugf("target %q: Validated %v", t.Config.Name, rsp.Validated.String())
-		return nil
+		a.targetLogger(t.Config.Name).Debugf("target %q: Validated %v", t.Config.Name, r.Validated.String())
+		return rsp, nil
 	case *gnoios.InstallResponse_InstallError:
-		a.Logger.Errorf("target %q Install RPC failed: %v: %v", t.Config.Name, rsp.InstallError.GetType(), rsp.InstallError.GetDetail())
-		return fmt.Errorf("%v: %v", rsp.InstallError.GetType(), rsp.InstallError.GetDetail())
+		a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Errorf("target %q Install RPC failed: %v: %v", t.Config.Name, r.InstallError.GetType(), r.InstallError.GetDetail())
+		return nil, fmt.Errorf("%v: %v", r.InstallError.GetType(), r.InstallError.GetDetail())
 	case *gnoios.InstallResponse_SyncProgress:
-		a.Logger.Debugf("target %q: SyncProgress %v", t.Config.Name, rsp.SyncProgress.String())
+		a.targetLogger(t.Config.Name).Debugf("target %q: SyncProgress %v", t.Config.Name, r.SyncProgress.String())
 		time.Sleep(time.Second)
 		goto RCV
 	case *gnoios.InstallResponse_TransferProgress:
-		a.Logger.Infof("target %q: TransferProgress %v", t.Config.Name, rsp.TransferProgress.String())
+		a.targetLogger(t.Config.Name).Infof("target %q: TransferProgress %v", t.Config.Name, r.TransferProgress.String())
 		goto RCV
 	}
-	return nil
+	return rsp, nil
 }
 
 func (a *App) osInstallTransferContent(ctx context.Context, t *api.Target, osic gnoios.OS_InstallClient) error {
@@ -165,7 +163,7 @@ func (a *App) osInstallTransferContent(ctx context.Context, t *api.Target, osic
 	doneCh := make(chan struct{})
 
 	go func() {
-		defer a.Logger.Infof("target %q: TransferContent done...", t.Config.Name)
+		defer a.targetLogger(t.Config.Name).Infof("target %q: TransferContent done...", t.Config.Name)
 		for {
 			select {
 			case <-ctx.Done():
@@ -181,11 +179,11 @@ func (a *App) osInstallTransferContent(ctx context.Context, t *api.Target, osic
 				a.printMsg(t.Config.Name, rsp)
 				switch rsp := rsp.GetResponse().(type) {
 				case *gnoios.InstallResponse_InstallError:
-					a.Logger.Errorf("target %q Install Content Transfer RPC failed: %v: %v", t.Config.Name, rsp.InstallError.GetType(), rsp.InstallError.GetDetail())
+					a.rpcLogger(t.Config.Name, gnoios.OS_Install_FullMethodName).Errorf("target %q Install Content Transfer RPC failed: %v: %v", t.Config.Name, rsp.InstallError.GetType(), rsp.InstallError.GetDetail())
 					errCh <- fmt.Errorf("%v: %v", rsp.InstallError.GetType(), rsp.InstallError.GetDetail())
 					return
 				case *gnoios.InstallResponse_TransferProgress:
-					a.Logger.Infof("target %q: TransferProgress %v", t.Config.Name, rsp.TransferProgress.String())
+					a.targetLogger(t.Config.Name).Infof("target %q: TransferProgress %v", t.Config.Name, rsp.TransferProgress.String())
 				}
 			}
 		}
@@ -204,16 +202,16 @@ OUTER:
 			n, err := r.Read(buf[:cap(buf)])
 			if err != nil {
 				if err == io.EOF {
-					a.Logger.Debugf("target %q: file read EOF", t.Config.Name)
+					a.targetLogger(t.Config.Name).Debugf("target %q: file read EOF", t.Config.Name)
 					break OUTER
 				}
-				a.Logger.Errorf("target %q: file read err: %v", t.Config.Name, err)
+				a.targetLogger(t.Config.Name).Errorf("target %q: file read err: %v", t.Config.Name, err)
 				close(doneCh)
 				return err
 			}
-			a.Logger.Debugf("target %q: read %d bytes from file", t.Config.Name, n)
+			a.targetLogger(t.Config.Name).Debugf("target %q: read %d bytes from file", t.Config.Name, n)
 			buf = buf[:n]
-			a.Logger.Debugf("target %q: sending %d bytes", t.Config.Name, n)
+			a.targetLogger(t.Config.Name).Debugf("target %q: sending %d bytes", t.Config.Name, n)
 			err = osic.Send(&gnoios.InstallRequest{
 				Request: &gnoios.InstallRequest_TransferContent{
 					TransferContent: buf,
@@ -225,6 +223,44 @@ OUTER:
 		}
 	}
 	close(doneCh)
-	a.Logger.Infof("target %q: sending TransferEnd", t.Config.Name)
+	a.targetLogger(t.Config.Name).Infof("target %q: sending TransferEnd", t.Config.Name)
 	return osic.Send(gos.NewOSInstallTransferEnd())
 }
+
+func (a *App) osInstallTransferRequest() (*gnoios.InstallRequest, error) {
+	pkgInfo, err := os.Stat(a.Config.OsInstallPackage)
+	if err != nil {
+		return nil, err
+	}
+	return gos.NewOSInstallTransferRequest(
+		gos.Version(a.Config.OsInstallVersion),
+		gos.StandbySupervisor(a.Config.OsInstallStandbySupervisor),
+		gos.PackageSize(uint64(pkgInfo.Size())),
+	)
+}
+
+func (a *App) osInstallDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	req, err := a.osInstallTransferRequest()
+	if err != nil {
+		return nil, err
+	}
+	n, _, err := dryRunContent(a.Config.OsInstallPackage, a.Config.OsInstallContentSize, "")
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{
+		{
+			RPC:     gnoios.OS_Install_FullMethodName,
+			Request: req,
+			Note:    "the package is transferred only if the target does not already have this version",
+		},
+		{
+			RPC:  gnoios.OS_Install_FullMethodName,
+			Note: fmt.Sprintf("%d transfer_content request(s) of up to %d byte(s) from %q", n, a.Config.OsInstallContentSize, a.Config.OsInstallPackage),
+		},
+		{
+			RPC:     gnoios.OS_Install_FullMethodName,
+			Request: gos.NewOSInstallTransferEnd(),
+		},
+	}, nil
+}
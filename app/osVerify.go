@@ -3,7 +3,6 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 
@@ -11,7 +10,6 @@ import (
 	gnoios "github.com/openconfig/gnoi/os"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	gos "github.com/karimra/gnoic/api/os"
@@ -22,6 +20,8 @@ type osVerifyResponse struct {
 	rsp *gnoios.VerifyResponse
 }
 
+func (r *osVerifyResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitOSVerifyFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -40,65 +40,45 @@ func (a *App) RunEOSVerify(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *osVerifyResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &osVerifyResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Name,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.OsVerify(ctx, t)
-			responseChan <- &osVerifyResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &osVerifyResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Name,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.OsVerify(ctx, t)
+		return sendResponse(responseChan, &osVerifyResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 	errs := make([]error, 0, numTargets)
 	result := make([]*osVerifyResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Os Verify failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 		result = append(result, rsp)
 		a.printMsg(rsp.TargetName, rsp.rsp)
 	}
-	switch a.Config.Format {
-	default:
+	if a.textOutput() {
 		fmt.Println(a.osVerifyTable(result))
-	case "json":
-		for _, r := range result {
-			tRsp := targetResponse{
-				Target:   r.TargetName,
-				Response: r.rsp,
-			}
-			b, err := json.MarshalIndent(tRsp, "", "  ")
-			if err != nil {
-				a.Logger.Errorf("failed to marshal os verify response from %q: %v", r.TargetName, err)
-				continue
-			}
-			fmt.Println(string(b))
-		}
 	}
 
 	return a.handleErrs(errs)
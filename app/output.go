@@ -0,0 +1,249 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	statusSuccess = "success"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+
+	templateFormatPrefix = "template="
+)
+
+var outputFormats = []string{"text", "json", "yaml", "ndjson", "csv", templateFormatPrefix + "<go template>"}
+
+var csvHeader = []string{"target", "command", "status", "error", "code", "duration", "response"}
+
+// result is the envelope of a target result,
+// it is printed by all the commands when the output format is not text.
+type result struct {
+	Target   string      `json:"target" yaml:"target"`
+	Command  string      `json:"command" yaml:"command"`
+	Status   string      `json:"status" yaml:"status"`
+	Error    string      `json:"error,omitempty" yaml:"error,omitempty"`
+	Code     string      `json:"code,omitempty" yaml:"code,omitempty"`
+	Duration string      `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Response interface{} `json:"response,omitempty" yaml:"response,omitempty"`
+	// the target could not be reached
+	connection bool
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseFormat validates the output format and parses its template, if any.
+func (a *App) parseFormat() error {
+	a.outputTemplate = nil
+	switch a.Config.Format {
+	case "", "text", "json", "yaml", "ndjson", "csv":
+		return nil
+	}
+	if !strings.HasPrefix(a.Config.Format, templateFormatPrefix) {
+		return fmt.Errorf("unknown output format %q, expected one of %q", a.Config.Format, outputFormats)
+	}
+	tpl, err := template.New("output").
+		Funcs(templateFuncs).
+		Parse(strings.TrimPrefix(a.Config.Format, templateFormatPrefix))
+	if err != nil {
+		return fmt.Errorf("invalid output template: %v", err)
+	}
+	a.outputTemplate = tpl
+	return nil
+}
+
+// textOutput returns true if the commands print their human readable output.
+func (a *App) textOutput() bool {
+	return a.Config.Format == "" || a.Config.Format == "text"
+}
+
+// setDuration records the time spent handling a target,
+// under both its name and address since the commands report either.
+func (a *App) setDuration(name, address string, d time.Duration) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.durations == nil {
+		a.durations = make(map[string]time.Duration)
+	}
+	a.durations[name] = d
+	a.durations[address] = d
+}
+
+// addResult wraps a target response in a result envelope,
+// the results are printed once all the targets are done.
+func (a *App) addResult(r targetResult) {
+	res := &result{
+		Target:  r.targetName(),
+		Command: a.Config.Command(),
+		Status:  statusSuccess,
+	}
+	if err := r.targetErr(); err != nil {
+		res.Status = statusFailed
+		res.Error = err.Error()
+		code := errorCode(err)
+		res.Code = code.String()
+		res.connection = code == codes.Unavailable
+	}
+	if !a.textOutput() || a.Config.Watch > 0 {
+		rsp, err := normalizeResponse(r.response())
+		if err != nil {
+			a.targetLogger(res.Target).Errorf("%q failed to convert response: %v", res.Target, err)
+		}
+		res.Response = rsp
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if d, ok := a.durations[res.Target]; ok {
+		res.Duration = d.Round(time.Millisecond).String()
+	}
+	a.results = append(a.results, res)
+}
+
+// targetResults returns the recorded results and the skipped targets, sorted by target.
+func (a *App) targetResults() []*result {
+	a.m.Lock()
+	defer a.m.Unlock()
+	rs := make([]*result, 0, len(a.results)+len(a.skipped))
+	rs = append(rs, a.results...)
+	for _, n := range a.skipped {
+		rs = append(rs, &result{
+			Target:  n,
+			Command: a.Config.Command(),
+			Status:  statusSkipped,
+		})
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Target < rs[j].Target
+	})
+	return rs
+}
+
+// printResults prints the targets results in the configured output format.
+func (a *App) printResults() error {
+	if a.textOutput() {
+		return nil
+	}
+	rs := a.targetResults()
+	a.pm.Lock()
+	defer a.pm.Unlock()
+	switch a.Config.Format {
+	case "json":
+		b, err := json.MarshalIndent(rs, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(rs)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	case "ndjson":
+		for _, r := range rs {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+		}
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		err := w.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+		for _, r := range rs {
+			rsp := ""
+			if r.Response != nil {
+				b, err := json.Marshal(r.Response)
+				if err != nil {
+					return err
+				}
+				rsp = string(b)
+			}
+			err = w.Write([]string{r.Target, r.Command, r.Status, r.Error, r.Code, r.Duration, rsp})
+			if err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		if a.outputTemplate == nil {
+			return nil
+		}
+		for _, r := range rs {
+			sb := new(strings.Builder)
+			err := a.outputTemplate.Execute(sb, r)
+			if err != nil {
+				return fmt.Errorf("%q failed to execute output template: %v", r.Target, err)
+			}
+			s := sb.String()
+			if !strings.HasSuffix(s, "\n") {
+				s += "\n"
+			}
+			fmt.Print(s)
+		}
+	}
+	return nil
+}
+
+// normalizeResponse converts a response to plain maps and lists, so that
+// it is encoded the same way in all formats. Protobuf messages are converted
+// using their proto field names.
+func normalizeResponse(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	}
+	var b []byte
+	var err error
+	switch m := v.(type) {
+	case proto.Message:
+		b, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	default:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			items := make([]interface{}, 0, rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				item, err := normalizeResponse(rv.Index(i).Interface())
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return items, nil
+		}
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
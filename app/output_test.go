@@ -0,0 +1,71 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openconfig/gnoi/system"
+)
+
+func Test_normalizeResponse(t *testing.T) {
+	var nilRsp *system.TimeResponse
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "nil",
+			v:    nil,
+			want: nil,
+		},
+		{
+			name: "typed_nil",
+			v:    nilRsp,
+			want: nil,
+		},
+		{
+			name: "proto_message",
+			v:    &system.PingResponse{Source: "1.1.1.1", Sequence: 1, Time: 1000},
+			want: map[string]interface{}{
+				"source":   "1.1.1.1",
+				"sequence": float64(1),
+				"time":     "1000",
+			},
+		},
+		{
+			name: "proto_messages",
+			v: []*system.PingResponse{
+				{Source: "1.1.1.1"},
+				{Source: "1.1.1.2"},
+			},
+			want: []interface{}{
+				map[string]interface{}{"source": "1.1.1.1"},
+				map[string]interface{}{"source": "1.1.1.2"},
+			},
+		},
+		{
+			name: "strings",
+			v:    []string{"/tmp/a", "/tmp/b"},
+			want: []interface{}{"/tmp/a", "/tmp/b"},
+		},
+		{
+			name: "map",
+			v:    map[string]bool{"can_generate": true},
+			want: map[string]interface{}{"can_generate": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeResponse(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalizeResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
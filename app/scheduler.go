@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karimra/gnoic/api"
+)
+
+// runTargets runs fn against each target, honoring the global
+// --max-concurrency, --batch-size, --canary and --max-failures flags.
+// It returns once all started targets are done.
+// Targets that are not started because the failure budget is used up
+// are recorded as skipped.
+func (a *App) runTargets(targets map[string]*api.Target, fn func(t *api.Target) error) {
+	names := make([]string, 0, len(targets))
+	for n := range targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	maxFailures := a.maxFailures(len(names))
+	var failures int64
+	budgetUsed := func() bool {
+		return maxFailures > 0 && atomic.LoadInt64(&failures) >= int64(maxFailures)
+	}
+
+	var sem chan struct{}
+	if a.Config.MaxConcurrency > 0 {
+		sem = make(chan struct{}, a.Config.MaxConcurrency)
+	}
+
+	batches := a.targetBatches(names)
+	for i, batch := range batches {
+		wg := new(sync.WaitGroup)
+		for j, n := range batch {
+			if sem != nil {
+				sem <- struct{}{}
+			}
+			if budgetUsed() {
+				if sem != nil {
+					<-sem
+				}
+				a.Logger.Errorf("failure budget of %d target(s) used up, not starting %d remaining target(s)",
+					maxFailures, remaining(batches, i, j))
+				wg.Wait()
+				a.skipTargets(batches, i, j)
+				return
+			}
+			wg.Add(1)
+			go func(t *api.Target) {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				start := time.Now()
+				err := a.traceTarget(t, fn)
+				a.setDuration(t.Config.Name, t.Config.Address, time.Since(start))
+				if err != nil {
+					atomic.AddInt64(&failures, 1)
+				}
+			}(targets[n])
+		}
+		wg.Wait()
+		if i == 0 && a.Config.Canary > 0 && len(batches) > 1 && atomic.LoadInt64(&failures) > 0 {
+			a.Logger.Errorf("canary batch failed, not starting %d remaining target(s)", remaining(batches, 1, 0))
+			a.skipTargets(batches, 1, 0)
+			return
+		}
+	}
+}
+
+// targetBatches splits the sorted target names into batches.
+// The first batch holds the canary targets, if any.
+func (a *App) targetBatches(names []string) [][]string {
+	batches := make([][]string, 0)
+	if a.Config.Canary > 0 && a.Config.Canary < len(names) {
+		batches = append(batches, names[:a.Config.Canary])
+		names = names[a.Config.Canary:]
+	}
+	if a.Config.BatchSize <= 0 {
+		if len(names) > 0 {
+			batches = append(batches, names)
+		}
+		return batches
+	}
+	for len(names) > 0 {
+		end := a.Config.BatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		batches = append(batches, names[:end])
+		names = names[end:]
+	}
+	return batches
+}
+
+// maxFailures returns the number of failed targets after which
+// no new targets are started, 0 means no limit.
+func (a *App) maxFailures(numTargets int) int {
+	mf, err := parseMaxFailures(a.Config.MaxFailures, numTargets)
+	if err != nil {
+		// validated in PreRun
+		return 0
+	}
+	return mf
+}
+
+// parseMaxFailures parses a failure budget given as a count (e.g "5")
+// or as a percentage of the number of targets (e.g "10%").
+func parseMaxFailures(s string, numTargets int) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("invalid max-failures percentage %q", s)
+		}
+		if p == 0 {
+			return 0, nil
+		}
+		return int(math.Ceil(p * float64(numTargets) / 100)), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid max-failures value %q", s)
+	}
+	return n, nil
+}
+
+func (a *App) skipTargets(batches [][]string, i, j int) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	for ; i < len(batches); i++ {
+		a.skipped = append(a.skipped, batches[i][j:]...)
+		j = 0
+	}
+}
+
+func remaining(batches [][]string, i, j int) int {
+	n := 0
+	for ; i < len(batches); i++ {
+		n += len(batches[i][j:])
+		j = 0
+	}
+	return n
+}
+
+type targetResult interface {
+	targetErr() error
+	targetName() string
+	response() interface{}
+}
+
+// sendResponse sends r to ch and returns the target error
+// so that the scheduler can account for failed targets.
+func sendResponse[T targetResult](ch chan<- T, r T) error {
+	ch <- r
+	return r.targetErr()
+}
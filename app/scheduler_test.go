@@ -0,0 +1,236 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karimra/gnoic/api"
+	"github.com/karimra/gnoic/config"
+)
+
+// testTargets returns n targets named t00, t01...
+func testTargets(n int) map[string]*api.Target {
+	targets := make(map[string]*api.Target, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("t%02d", i)
+		targets[name] = api.NewTargetFromConfig(&config.TargetConfig{Name: name, Address: name + ":57400"})
+	}
+	return targets
+}
+
+func Test_runTargets(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		batchSize      int
+		canary         int
+		maxFailures    string
+		failing        []string
+		wantRun        []string
+		wantSkipped    []string
+	}{
+		{
+			name:    "no_failure",
+			canary:  1,
+			wantRun: []string{"t00", "t01", "t02", "t03", "t04", "t05"},
+		},
+		{
+			name:        "canary_failure",
+			batchSize:   2,
+			canary:      1,
+			failing:     []string{"t00"},
+			wantRun:     []string{"t00"},
+			wantSkipped: []string{"t01", "t02", "t03", "t04", "t05"},
+		},
+		{
+			name:      "canary_success",
+			batchSize: 2,
+			canary:    1,
+			failing:   []string{"t03"},
+			wantRun:   []string{"t00", "t01", "t02", "t03", "t04", "t05"},
+		},
+		{
+			name:        "max_failures_count",
+			batchSize:   2,
+			maxFailures: "2",
+			failing:     []string{"t00", "t01"},
+			wantRun:     []string{"t00", "t01"},
+			wantSkipped: []string{"t02", "t03", "t04", "t05"},
+		},
+		{
+			name:           "max_failures_count_within_batch",
+			maxConcurrency: 1,
+			batchSize:      4,
+			maxFailures:    "1",
+			failing:        []string{"t01"},
+			wantRun:        []string{"t00", "t01"},
+			wantSkipped:    []string{"t02", "t03", "t04", "t05"},
+		},
+		{
+			name:        "max_failures_percent",
+			batchSize:   2,
+			maxFailures: "50%",
+			failing:     []string{"t00", "t01", "t03"},
+			wantRun:     []string{"t00", "t01", "t02", "t03"},
+			wantSkipped: []string{"t04", "t05"},
+		},
+		{
+			name:        "failures_within_budget",
+			batchSize:   2,
+			maxFailures: "3",
+			failing:     []string{"t00", "t05"},
+			wantRun:     []string{"t00", "t01", "t02", "t03", "t04", "t05"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.Config.MaxConcurrency = tt.maxConcurrency
+			a.Config.BatchSize = tt.batchSize
+			a.Config.Canary = tt.canary
+			a.Config.MaxFailures = tt.maxFailures
+
+			var m sync.Mutex
+			run := make([]string, 0)
+			a.runTargets(testTargets(6), func(t *api.Target) error {
+				m.Lock()
+				run = append(run, t.Config.Name)
+				m.Unlock()
+				for _, n := range tt.failing {
+					if n == t.Config.Name {
+						return errors.New("failed")
+					}
+				}
+				return nil
+			})
+			sort.Strings(run)
+			if !reflect.DeepEqual(run, tt.wantRun) {
+				t.Errorf("run targets = %v, want %v", run, tt.wantRun)
+			}
+			if !reflect.DeepEqual(a.skipped, tt.wantSkipped) {
+				t.Errorf("skipped targets = %v, want %v", a.skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func Test_runTargetsConcurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		batchSize      int
+		want           int64
+	}{
+		{name: "max_concurrency", maxConcurrency: 3, want: 3},
+		{name: "batch_size", batchSize: 2, want: 2},
+		{name: "max_concurrency_below_batch_size", maxConcurrency: 2, batchSize: 5, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New()
+			a.Config.MaxConcurrency = tt.maxConcurrency
+			a.Config.BatchSize = tt.batchSize
+
+			var active, peak, count int64
+			a.runTargets(testTargets(12), func(t *api.Target) error {
+				n := atomic.AddInt64(&active, 1)
+				defer atomic.AddInt64(&active, -1)
+				for {
+					p := atomic.LoadInt64(&peak)
+					if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+						break
+					}
+				}
+				atomic.AddInt64(&count, 1)
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+			if count != 12 {
+				t.Errorf("run %d targets, want 12", count)
+			}
+			if peak > tt.want {
+				t.Errorf("%d targets run concurrently, want at most %d", peak, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseMaxFailures(t *testing.T) {
+	type args struct {
+		s          string
+		numTargets int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			args: args{
+				s:          "",
+				numTargets: 10,
+			},
+			want: 0,
+		},
+		{
+			name: "count",
+			args: args{
+				s:          "3",
+				numTargets: 10,
+			},
+			want: 3,
+		},
+		{
+			name: "percent",
+			args: args{
+				s:          "10%",
+				numTargets: 800,
+			},
+			want: 80,
+		},
+		{
+			name: "percent_round_up",
+			args: args{
+				s:          "10%",
+				numTargets: 5,
+			},
+			want: 1,
+		},
+		{
+			name: "invalid_percent",
+			args: args{
+				s:          "110%",
+				numTargets: 5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative",
+			args: args{
+				s:          "-1",
+				numTargets: 5,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMaxFailures(tt.args.s, tt.args.numTargets)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMaxFailures() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseMaxFailures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/karimra/gnoic/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func (a *App) RunESecretsSet(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if name == "" {
+		return errors.New("missing secret name")
+	}
+	value, err := readSecretValue(fmt.Sprintf("Value for secret %q: ", name))
+	if err != nil {
+		return err
+	}
+	store, err := a.Config.SecretsStore()
+	if err != nil {
+		return err
+	}
+	secrets, err := store.Load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	err = store.Save(secrets)
+	if err != nil {
+		return err
+	}
+	a.Logger.Infof("secret %q saved in %q", name, store.Path())
+	return nil
+}
+
+func (a *App) RunESecretsList(cmd *cobra.Command, args []string) error {
+	store, err := a.Config.SecretsStore()
+	if err != nil {
+		return err
+	}
+	names, err := store.Names()
+	if err != nil {
+		return err
+	}
+	switch a.Config.Format {
+	default:
+		for _, n := range names {
+			fmt.Println(n)
+		}
+	case "json":
+		b, err := json.MarshalIndent(names, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
+
+func (a *App) RunESecretsRemove(cmd *cobra.Command, args []string) error {
+	store, err := a.Config.SecretsStore()
+	if err != nil {
+		return err
+	}
+	secrets, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[args[0]]; !ok {
+		return fmt.Errorf("secret %q not found in %q", args[0], store.Path())
+	}
+	delete(secrets, args[0])
+	return store.Save(secrets)
+}
+
+// readSecretValue prompts for a secret value without echo,
+// or reads it from the first line of stdin if it is not a terminal.
+func readSecretValue(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return config.ReadSecret(prompt)
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed reading secret value from stdin: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
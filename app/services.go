@@ -3,14 +3,12 @@ package app
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"sort"
 
 	"github.com/karimra/gnoic/api"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc/metadata"
 	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
@@ -19,6 +17,8 @@ type reflectionResponse struct {
 	rsp *reflectpb.ServerReflectionResponse
 }
 
+func (r *reflectionResponse) response() interface{} { return r.rsp }
+
 func (a *App) RunEServices(cmd *cobra.Command, args []string) error {
 	targets, err := a.GetTargets()
 	if err != nil {
@@ -27,19 +27,18 @@ func (a *App) RunEServices(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *reflectionResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go a.reflectionServicesRequest(cmd.Context(), t, responseChan)
-	}
-	a.wg.Wait()
+	a.runTargets(targets, func(t *api.Target) error {
+		return sendResponse(responseChan, a.reflectionServicesRequest(cmd.Context(), t))
+	})
 	close(responseChan)
 	errs := make([]error, 0, numTargets)
 	result := make([]*reflectionResponse, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q Services failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -65,7 +64,7 @@ func (a *App) reflectionServicesTable(rs []*reflectionResponse) string {
 				})
 			}
 		default:
-			a.Logger.Printf("%s: unexpected message type: %T", rsp.TargetName, rsp.rsp)
+			a.targetLogger(rsp.TargetName).Printf("%s: unexpected message type: %T", rsp.TargetName, rsp.rsp)
 		}
 	}
 
@@ -78,50 +77,45 @@ func (a *App) reflectionServicesTable(rs []*reflectionResponse) string {
 	return b.String()
 }
 
-func (a *App) reflectionServicesRequest(ctx context.Context, t *api.Target, rspCh chan<- *reflectionResponse) {
-	defer a.wg.Done()
+func (a *App) reflectionServicesRequest(ctx context.Context, t *api.Target) *reflectionResponse {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
 
 	err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
 	if err != nil {
-		rspCh <- &reflectionResponse{
+		return &reflectionResponse{
 			TargetError: TargetError{
 				TargetName: t.Config.Name,
 				Err:        err,
 			},
 		}
-		return
 	}
 	defer t.Close()
 
 	rfc := reflectpb.NewServerReflectionClient(t.Conn())
 	info, err := rfc.ServerReflectionInfo(ctx)
 	if err != nil {
-		rspCh <- &reflectionResponse{
+		return &reflectionResponse{
 			TargetError: TargetError{
 				TargetName: t.Config.Name,
 				Err:        err,
 			},
 		}
-		return
 	}
 	req := &reflectpb.ServerReflectionRequest{
 		MessageRequest: &reflectpb.ServerReflectionRequest_ListServices{},
 	}
 	err = info.Send(req)
 	if err != nil {
-		rspCh <- &reflectionResponse{
+		return &reflectionResponse{
 			TargetError: TargetError{
 				TargetName: t.Config.Name,
 				Err:        err,
 			},
 		}
-		return
 	}
 	rsp, err := info.Recv()
-	rspCh <- &reflectionResponse{
+	return &reflectionResponse{
 		TargetError: TargetError{
 			TargetName: t.Config.Name,
 			Err:        err,
@@ -131,21 +125,7 @@ func (a *App) reflectionServicesRequest(ctx context.Context, t *api.Target, rspC
 }
 
 func (a *App) printCMDOutput(rs []*reflectionResponse, fn func([]*reflectionResponse) string) {
-	switch a.Config.Format {
-	default:
+	if a.textOutput() {
 		fmt.Println(fn(rs))
-	case "json":
-		for _, r := range rs {
-			tRsp := targetResponse{
-				Target:   r.TargetName,
-				Response: r.rsp,
-			}
-			b, err := json.MarshalIndent(tRsp, "", "  ")
-			if err != nil {
-				a.Logger.Errorf("failed to marshal Target response from %q: %v", r.TargetName, err)
-				continue
-			}
-			fmt.Println(string(b))
-		}
 	}
 }
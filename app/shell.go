@@ -0,0 +1,531 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/openconfig/gnoi/cert"
+	"github.com/openconfig/gnoi/file"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	shellCompletionTTL = 30 * time.Second
+	// time given to a target to answer a completion RPC
+	shellCompletionTimeout = 2 * time.Second
+)
+
+// flags completed with remote paths, indexed by command name
+var shellRemotePathFlags = map[string]string{
+	"file-get":    "file",
+	"file-remove": "path",
+	"file-stat":   "path",
+}
+
+var shellBuiltins = []prompt.Suggest{
+	{Text: "use", Description: "set the target, group or selector the commands run against"},
+	{Text: "exit", Description: "exit the shell"},
+}
+
+type shell struct {
+	a   *App
+	ctx context.Context
+	// persistent flags values the commands run with,
+	// the values given when starting the shell, updated by `use`
+	flags map[string]*shellFlag
+
+	m     sync.Mutex
+	cache map[string]*shellCompletion
+	// names of the targets found in the config file or inventory,
+	// loaded when the shell starts and on `use`
+	names []string
+}
+
+type shellFlag struct {
+	value   []string
+	changed bool
+}
+
+type shellCompletion struct {
+	suggests []prompt.Suggest
+	expires  time.Time
+	// set while the suggestions are fetched
+	fetching bool
+}
+
+func (a *App) RunEShell(cmd *cobra.Command, args []string) error {
+	s := &shell{
+		a:     a,
+		ctx:   a.ctx,
+		flags: make(map[string]*shellFlag),
+		cache: make(map[string]*shellCompletion),
+	}
+	a.RootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		s.flags[f.Name] = &shellFlag{value: flagValue(f), changed: f.Changed}
+	})
+	// resolve the targets once, so that the password is asked before the prompt starts
+	_, err := a.Config.GetTargets()
+	if err != nil {
+		a.Logger.Warnf("no targets: %v", err)
+	}
+	s.names = s.loadTargetNames()
+	a.inShell = true
+	defer func() { a.inShell = false }()
+
+	p := prompt.New(s.execute, s.complete,
+		prompt.OptionTitle("gnoic"),
+		prompt.OptionLivePrefix(s.prefix),
+		prompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool {
+			in = strings.TrimSpace(in)
+			return breakline && (in == "exit" || in == "quit")
+		}),
+	)
+	p.Run()
+	return nil
+}
+
+func (s *shell) prefix() (string, bool) {
+	ctx := s.context()
+	if ctx == "" {
+		return "gnoic> ", true
+	}
+	return fmt.Sprintf("gnoic [%s]> ", ctx), true
+}
+
+// context returns the selector or the addresses the commands run against.
+func (s *shell) context() string {
+	if sel := s.flags["select"]; sel != nil && len(sel.value) > 0 && sel.value[0] != "" {
+		return sel.value[0]
+	}
+	if addr := s.flags["address"]; addr != nil {
+		return strings.Join(addr.value, ",")
+	}
+	return ""
+}
+
+func (s *shell) execute(line string) {
+	args, err := splitArgs(line)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if len(args) == 0 {
+		return
+	}
+	switch args[0] {
+	case "exit", "quit":
+		return
+	case "use":
+		err = s.use(args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return
+	case "shell":
+		fmt.Fprintln(os.Stderr, "Error: already in the shell")
+		return
+	}
+	err = s.resetFlags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	// interrupting a command does not exit the shell
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	setCommandsContext(s.a.RootCmd, ctx)
+
+	s.a.RootCmd.SetArgs(args)
+	// the command errors are printed by cobra
+	s.a.RootCmd.Execute()
+}
+
+// setCommandsContext sets the context of cmd and its sub commands,
+// cobra passes its context to the executed command only if that one has none.
+func setCommandsContext(cmd *cobra.Command, ctx context.Context) {
+	cmd.SetContext(ctx)
+	for _, c := range cmd.Commands() {
+		setCommandsContext(c, ctx)
+	}
+}
+
+// use sets the target context of the following commands:
+// a group name, a target name, a selector expression or an address.
+func (s *shell) use(args []string) error {
+	if len(args) == 0 {
+		ctx := s.context()
+		if ctx == "" {
+			ctx = "all targets"
+		}
+		fmt.Println(ctx)
+		return nil
+	}
+	if len(args) > 1 {
+		return errors.New("use expects a single target, group or selector")
+	}
+	name := args[0]
+	s.names = s.loadTargetNames()
+	switch {
+	case name == "":
+		s.setContext(nil, "")
+	case strings.ContainsAny(name, "=!(") || strings.Contains(name, " in "):
+		s.setContext(nil, name)
+	case sInList(name, s.a.Config.Groups()):
+		s.setContext(nil, "group="+name)
+	case sInList(name, s.names):
+		s.setContext(nil, "name="+name)
+	default:
+		s.setContext(strings.Split(name, ","), "")
+	}
+	s.m.Lock()
+	s.cache = make(map[string]*shellCompletion)
+	s.m.Unlock()
+	return nil
+}
+
+func (s *shell) setContext(addresses []string, sel string) {
+	s.flags["address"] = &shellFlag{value: addresses, changed: len(addresses) > 0}
+	s.flags["select"] = &shellFlag{value: []string{sel}, changed: sel != ""}
+}
+
+// loadTargetNames returns the names of the targets found in the config file or inventory.
+func (s *shell) loadTargetNames() []string {
+	addr, sel := s.flags["address"], s.flags["select"]
+	s.setContext(nil, "")
+	defer func() { s.flags["address"], s.flags["select"] = addr, sel }()
+	if s.resetFlags() != nil {
+		return nil
+	}
+	tcs, err := s.a.Config.GetTargets()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(tcs))
+	for n := range tcs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resetFlags sets the commands local flags to their default values
+// and the persistent flags to the shell values.
+func (s *shell) resetFlags() error {
+	var err error
+	var reset func(cmd *cobra.Command)
+	reset = func(cmd *cobra.Command) {
+		cmd.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+			if serr := setFlagValue(f, defaultFlagValue(f), false); serr != nil && err == nil {
+				err = fmt.Errorf("flag %q: %v", f.Name, serr)
+			}
+		})
+		for _, c := range cmd.Commands() {
+			reset(c)
+		}
+	}
+	reset(s.a.RootCmd)
+	s.a.RootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		sf, ok := s.flags[f.Name]
+		if !ok {
+			return
+		}
+		if serr := setFlagValue(f, sf.value, sf.changed); serr != nil && err == nil {
+			err = fmt.Errorf("flag %q: %v", f.Name, serr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.a.Config.SetPersistantFlagsFromFile(s.a.RootCmd)
+	return nil
+}
+
+func (s *shell) complete(d prompt.Document) []prompt.Suggest {
+	word := d.GetWordBeforeCursor()
+	args := strings.Fields(d.TextBeforeCursor())
+	if word != "" && len(args) > 0 {
+		args = args[:len(args)-1]
+	}
+	if len(args) == 0 {
+		suggests := append([]prompt.Suggest{}, shellBuiltins...)
+		suggests = append(suggests, commandSuggests(s.a.RootCmd)...)
+		return prompt.FilterHasPrefix(suggests, word, true)
+	}
+	if args[0] == "use" {
+		if len(args) > 1 {
+			return nil
+		}
+		suggests := make([]prompt.Suggest, 0)
+		for _, g := range s.a.Config.Groups() {
+			suggests = append(suggests, prompt.Suggest{Text: g, Description: "group"})
+		}
+		for _, n := range s.names {
+			suggests = append(suggests, prompt.Suggest{Text: n, Description: "target"})
+		}
+		return prompt.FilterHasPrefix(suggests, word, true)
+	}
+	cmd, _, err := s.a.RootCmd.Find(args)
+	if err != nil {
+		return nil
+	}
+	if f := valueFlag(cmd, args[len(args)-1]); f != nil {
+		return s.completeFlagValue(cmd, f, word)
+	}
+	if strings.HasPrefix(word, "-") {
+		return prompt.FilterHasPrefix(flagSuggests(cmd), word, true)
+	}
+	return prompt.FilterHasPrefix(commandSuggests(cmd), word, true)
+}
+
+// completeFlagValue completes remote file paths and certificate IDs.
+func (s *shell) completeFlagValue(cmd *cobra.Command, f *pflag.Flag, word string) []prompt.Suggest {
+	name := commandName(cmd)
+	switch {
+	case shellRemotePathFlags[name] == f.Name:
+		dir := "/"
+		if i := strings.LastIndex(word, "/"); i > 0 {
+			dir = word[:i]
+		}
+		return prompt.FilterHasPrefix(s.remoteCompletion("path:"+dir, func(ctx context.Context, fc file.FileClient, _ cert.CertificateManagementClient) ([]prompt.Suggest, error) {
+			rsp, err := fc.Stat(ctx, &file.StatRequest{Path: dir})
+			if err != nil {
+				return nil, err
+			}
+			suggests := make([]prompt.Suggest, 0, len(rsp.GetStats()))
+			for _, si := range rsp.GetStats() {
+				p := si.GetPath()
+				if !strings.HasPrefix(p, "/") {
+					p = path.Join(dir, p)
+				}
+				suggests = append(suggests, prompt.Suggest{Text: p, Description: fmt.Sprintf("%d bytes", si.GetSize())})
+			}
+			return suggests, nil
+		}), word, false)
+	case strings.HasPrefix(name, "cert-") && f.Name == "id":
+		return prompt.FilterHasPrefix(s.remoteCompletion("cert-id", func(ctx context.Context, _ file.FileClient, cc cert.CertificateManagementClient) ([]prompt.Suggest, error) {
+			rsp, err := cc.GetCertificates(ctx, new(cert.GetCertificatesRequest))
+			if err != nil {
+				return nil, err
+			}
+			suggests := make([]prompt.Suggest, 0, len(rsp.GetCertificateInfo()))
+			for _, ci := range rsp.GetCertificateInfo() {
+				suggests = append(suggests, prompt.Suggest{Text: ci.GetCertificateId(), Description: ci.GetCertificate().GetType().String()})
+			}
+			return suggests, nil
+		}), word, false)
+	}
+	return nil
+}
+
+// remoteCompletion returns the cached result of fn run against the first target of the shell context.
+// Since the completion runs on each key stroke, fn runs in the background with a short timeout,
+// its result is suggested once it is cached.
+func (s *shell) remoteCompletion(key string, fn func(context.Context, file.FileClient, cert.CertificateManagementClient) ([]prompt.Suggest, error)) []prompt.Suggest {
+	s.m.Lock()
+	defer s.m.Unlock()
+	c, ok := s.cache[key]
+	if ok && (c.fetching || time.Now().Before(c.expires)) {
+		return c.suggests
+	}
+	if !ok {
+		c = new(shellCompletion)
+		s.cache[key] = c
+	}
+	// failures are cached too, so that an unreachable target is not dialed on each key stroke
+	c.expires = time.Now().Add(shellCompletionTTL)
+	if s.resetFlags() != nil {
+		return c.suggests
+	}
+	targets, err := s.a.GetTargets()
+	if err != nil || len(targets) == 0 {
+		return c.suggests
+	}
+	names := make([]string, 0, len(targets))
+	for n := range targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	t := targets[names[0]]
+	timeout := shellCompletionTimeout
+	if t.Config.Timeout > 0 && t.Config.Timeout < timeout {
+		timeout = t.Config.Timeout
+	}
+	opts := s.a.createBaseDialOpts()
+
+	c.fetching = true
+	go func() {
+		ctx, cancel := context.WithTimeout(s.ctx, timeout)
+		defer cancel()
+		var suggests []prompt.Suggest
+		err := t.CreateGrpcClient(ctx, opts...)
+		if err == nil {
+			suggests, err = fn(ctx, file.NewFileClient(t.Conn()), t.CertClient())
+			t.Close()
+		}
+		s.m.Lock()
+		defer s.m.Unlock()
+		c.fetching = false
+		c.expires = time.Now().Add(shellCompletionTTL)
+		c.suggests = suggests
+	}()
+	return c.suggests
+}
+
+func commandSuggests(cmd *cobra.Command) []prompt.Suggest {
+	suggests := make([]prompt.Suggest, 0, len(cmd.Commands()))
+	for _, c := range cmd.Commands() {
+		if c.Hidden || c.Name() == "shell" {
+			continue
+		}
+		suggests = append(suggests, prompt.Suggest{Text: c.Name(), Description: c.Short})
+	}
+	return suggests
+}
+
+func flagSuggests(cmd *cobra.Command) []prompt.Suggest {
+	suggests := make([]prompt.Suggest, 0)
+	add := func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		suggests = append(suggests, prompt.Suggest{Text: "--" + f.Name, Description: f.Usage})
+	}
+	cmd.LocalFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(add)
+	return suggests
+}
+
+// valueFlag returns the flag arg refers to, if it expects a value.
+func valueFlag(cmd *cobra.Command, arg string) *pflag.Flag {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return nil
+	}
+	var f *pflag.Flag
+	if strings.HasPrefix(arg, "--") {
+		f = cmd.Flags().Lookup(arg[2:])
+		if f == nil {
+			f = cmd.InheritedFlags().Lookup(arg[2:])
+		}
+	} else if len(arg) == 2 {
+		f = cmd.Flags().ShorthandLookup(arg[1:])
+		if f == nil {
+			f = cmd.InheritedFlags().ShorthandLookup(arg[1:])
+		}
+	}
+	if f == nil || f.NoOptDefVal != "" {
+		return nil
+	}
+	return f
+}
+
+// commandName returns the command name prefixed with its parents names, e.g: "file-stat"
+func commandName(cmd *cobra.Command) string {
+	names := make([]string, 0)
+	for c := cmd; c != nil && c.HasParent(); c = c.Parent() {
+		names = append([]string{c.Name()}, names...)
+	}
+	return strings.Join(names, "-")
+}
+
+func flagValue(f *pflag.Flag) []string {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.GetSlice()
+	}
+	return []string{f.Value.String()}
+}
+
+func defaultFlagValue(f *pflag.Flag) []string {
+	if _, ok := f.Value.(pflag.SliceValue); ok {
+		d := strings.Trim(f.DefValue, "[]")
+		if d == "" {
+			return []string{}
+		}
+		return strings.Split(d, ",")
+	}
+	return []string{f.DefValue}
+}
+
+func setFlagValue(f *pflag.Flag, v []string, changed bool) error {
+	var err error
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		err = sv.Replace(v)
+	} else if len(v) > 0 {
+		err = f.Value.Set(v[0])
+	}
+	f.Changed = changed
+	return err
+}
+
+func sInList(s string, l []string) bool {
+	for i := range l {
+		if s == l[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitArgs splits a command line into arguments,
+// honoring single and double quotes and backslash escapes.
+func splitArgs(s string) ([]string, error) {
+	args := make([]string, 0)
+	sb := new(strings.Builder)
+	var quote rune
+	inArg, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			sb.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, sb.String())
+				sb.Reset()
+				inArg = false
+			}
+		default:
+			sb.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, sb.String())
+	}
+	return args, nil
+}
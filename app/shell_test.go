@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/openconfig/gnoi/cert"
+	"github.com/openconfig/gnoi/file"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+func Test_splitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "  ",
+			want: []string{},
+		},
+		{
+			name: "fields",
+			in:   "file stat  --path /var/log",
+			want: []string{"file", "stat", "--path", "/var/log"},
+		},
+		{
+			name: "quotes",
+			in:   `system ping --destination "1.1.1.1" --format 'template={{.Target}} {{.Status}}'`,
+			want: []string{"system", "ping", "--destination", "1.1.1.1", "--format", "template={{.Target}} {{.Status}}"},
+		},
+		{
+			name: "escape",
+			in:   `file stat --path /tmp/a\ b`,
+			want: []string{"file", "stat", "--path", "/tmp/a b"},
+		},
+		{
+			name: "empty_quotes",
+			in:   `use ""`,
+			want: []string{"use", ""},
+		},
+		{
+			name:    "unterminated",
+			in:      `use "router1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitArgs(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitArgs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setCommandsContext(t *testing.T) {
+	type ctxKey struct{}
+	var got context.Context
+	root := &cobra.Command{Use: "gnoic"}
+	root.AddCommand(&cobra.Command{
+		Use: "time",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			got = cmd.Context()
+			return nil
+		},
+	})
+	root.SetArgs([]string{"time"})
+	for _, id := range []string{"first", "second"} {
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, id))
+		setCommandsContext(root, ctx)
+		if err := root.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		cancel()
+		if got.Value(ctxKey{}) != id {
+			t.Errorf("command ran with the %v context, want the %s one", got.Value(ctxKey{}), id)
+		}
+	}
+}
+
+func Test_remoteCompletion(t *testing.T) {
+	srv := grpc.NewServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	a := New()
+	a.InitGlobalFlags()
+	a.Config.SetLogger(a.Logger)
+	s := &shell{
+		a:   a,
+		ctx: a.ctx,
+		flags: map[string]*shellFlag{
+			"address":  {value: []string{l.Addr().String()}, changed: true},
+			"insecure": {value: []string{"true"}, changed: true},
+		},
+		cache: make(map[string]*shellCompletion),
+	}
+	var calls int32
+	release := make(chan struct{})
+	slow := func(ctx context.Context, _ file.FileClient, _ cert.CertificateManagementClient) ([]prompt.Suggest, error) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return []prompt.Suggest{{Text: "gnmi"}}, nil
+	}
+
+	// the key strokes typed while the target answers are not blocked
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		if got := s.remoteCompletion("cert-id", slow); len(got) != 0 {
+			t.Fatalf("remoteCompletion() = %v before the target answered", got)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("remoteCompletion() blocked for %v", d)
+		}
+	}
+	close(release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := s.remoteCompletion("cert-id", slow)
+		if len(got) == 1 && got[0].Text == "gnmi" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("remoteCompletion() = %v, want the cached gnmi suggestion", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("completion RPC run %d times, want 1", n)
+	}
+}
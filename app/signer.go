@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// certificates signers
+const (
+	signerLocal = "local"
+	signerVault = "vault"
+	signerACME  = "acme"
+)
+
+var signers = []string{signerLocal, signerVault, signerACME}
+
+// signer issues the targets certificates.
+type signer interface {
+	// sign returns a certificate for csr. tmpl holds the subject, SANs
+	// and validity built by gnoic, a signer applies them if its backend allows it.
+	sign(ctx context.Context, tmpl *x509.Certificate, csr *x509.CertificateRequest) (*x509.Certificate, error)
+	// ca returns the CA certificate issuing the certificates, nil if unknown.
+	ca() *x509.Certificate
+	// String returns the signer name, used in logs.
+	String() string
+}
+
+// newSigner returns the signer selected with --signer.
+func (a *App) newSigner(ctx context.Context) (signer, error) {
+	switch a.Config.CertSigner {
+	case "", signerLocal:
+		if a.Config.CertCACert == "" || a.Config.CertCAKey == "" {
+			return nil, UsageError(errors.New("missing --ca-cert and --ca-key flags"))
+		}
+		caCert, err := loadCACert(a.Config.CertCACert, a.Config.CertCAKey)
+		if err != nil {
+			return nil, err
+		}
+		a.Logger.Infof("read local CA certs")
+		return &localSigner{caCert: caCert}, nil
+	case signerVault:
+		return a.newVaultSigner(ctx)
+	case signerACME:
+		return a.newACMESigner()
+	}
+	return nil, UsageError(fmt.Errorf("unknown signer %q, must be one of %q", a.Config.CertSigner, signers))
+}
+
+// externalSigner returns true if the certificates are signed by a remote PKI.
+func (a *App) externalSigner() bool {
+	return a.Config.CertSigner != "" && a.Config.CertSigner != signerLocal
+}
+
+// signerHTTPClient returns the HTTP client used to reach the vault and acme signers.
+func (a *App) signerHTTPClient() (*http.Client, error) {
+	tlsConfig := new(tls.Config)
+	if a.Config.CertSignerTLSCA != "" {
+		b, err := os.ReadFile(a.Config.CertSignerTLSCA)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no PEM certificate found", a.Config.CertSignerTLSCA)
+		}
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}, nil
+}
+
+// localSigner signs the certificates with a CA certificate and key read from files.
+type localSigner struct {
+	caCert *tls.Certificate
+}
+
+func (s *localSigner) sign(_ context.Context, tmpl *x509.Certificate, _ *x509.CertificateRequest) (*x509.Certificate, error) {
+	derCert, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert.Leaf, tmpl.PublicKey, s.caCert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(derCert)
+}
+
+func (s *localSigner) ca() *x509.Certificate { return s.caCert.Leaf }
+
+func (s *localSigner) String() string { return signerLocal }
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+)
+
+// acmeSigner submits the CSRs to an ACME (RFC 8555) server.
+// It does not solve challenges: the server must consider the identifiers
+// of the CSRs as authorized, e.g: using an external account binding.
+type acmeSigner struct {
+	client *acme.Client
+	eab    *acme.ExternalAccountBinding
+	caCert *x509.Certificate
+
+	registerOnce sync.Once
+	registerErr  error
+}
+
+func (a *App) newACMESigner() (*acmeSigner, error) {
+	if a.Config.CertACMEDirectory == "" {
+		return nil, UsageError(errors.New("missing --acme-directory flag"))
+	}
+	s := new(acmeSigner)
+	var err error
+	var key crypto.Signer
+	if a.Config.CertACMEAccountKey != "" {
+		key, err = readPrivateKey(a.Config.CertACMEAccountKey)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hc, err := a.signerHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	s.client = &acme.Client{
+		Key:          key,
+		DirectoryURL: a.Config.CertACMEDirectory,
+		HTTPClient:   hc,
+		UserAgent:    "gnoic",
+	}
+	if a.Config.CertACMEEABKID != "" {
+		k, err := a.Config.ResolveSecret(a.Config.CertACMEEABKey)
+		if err != nil {
+			return nil, err
+		}
+		hmacKey, err := base64.RawURLEncoding.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --acme-eab-key: %v", err)
+		}
+		s.eab = &acme.ExternalAccountBinding{
+			KID: a.Config.CertACMEEABKID,
+			Key: hmacKey,
+		}
+	}
+	if a.Config.CertCACert != "" {
+		s.caCert, err = readCertificate(a.Config.CertCACert)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// register creates the ACME account, or finds the existing one, once.
+func (s *acmeSigner) register(ctx context.Context) error {
+	s.registerOnce.Do(func() {
+		_, err := s.client.Register(ctx, &acme.Account{ExternalAccountBinding: s.eab}, acme.AcceptTOS)
+		if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+			s.registerErr = fmt.Errorf("ACME account registration failed: %v", err)
+		}
+	})
+	return s.registerErr
+}
+
+// sign orders a certificate for the identifiers of csr and finalizes the order with it.
+// The ACME server decides the certificate contents, tmpl is not used.
+func (s *acmeSigner) sign(ctx context.Context, _ *x509.Certificate, csr *x509.CertificateRequest) (*x509.Certificate, error) {
+	err := s.register(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := acmeIdentifiers(csr)
+	if len(ids) == 0 {
+		return nil, errors.New("the CSR has no DNS name, IP address or common name to order a certificate for")
+	}
+	order, err := s.client.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ACME new order failed: %v", err)
+	}
+	for _, u := range order.AuthzURLs {
+		z, err := s.client.GetAuthorization(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("ACME authorization failed: %v", err)
+		}
+		if z.Status != acme.StatusValid {
+			return nil, fmt.Errorf("ACME authorization of %q is %s: the identifier must be authorized by the server, gnoic does not solve challenges",
+				z.Identifier.Value, z.Status)
+		}
+	}
+	order, err = s.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, fmt.Errorf("ACME order failed: %v", err)
+	}
+	der, _, err := s.client.CreateOrderCert(ctx, order.FinalizeURL, csr.Raw, false)
+	if err != nil {
+		return nil, fmt.Errorf("ACME order finalization failed: %v", err)
+	}
+	if len(der) == 0 {
+		return nil, errors.New("ACME server returned no certificate")
+	}
+	return x509.ParseCertificate(der[0])
+}
+
+// ca returns the CA set with --ca-cert, if any.
+func (s *acmeSigner) ca() *x509.Certificate { return s.caCert }
+
+func (s *acmeSigner) String() string { return signerACME }
+
+// acmeIdentifiers returns the identifiers of the certificate ordered for csr:
+// its DNS names and IP addresses, or its common name.
+func acmeIdentifiers(csr *x509.CertificateRequest) []acme.AuthzID {
+	ids := acme.DomainIDs(csr.DNSNames...)
+	ids = append(ids, acme.IPIDs(ipStrings(csr.IPAddresses)...)...)
+	if len(ids) == 0 && csr.Subject.CommonName != "" {
+		if net.ParseIP(csr.Subject.CommonName) != nil {
+			return acme.IPIDs(csr.Subject.CommonName)
+		}
+		return acme.DomainIDs(csr.Subject.CommonName)
+	}
+	return ids
+}
+
+func ipStrings(ips []net.IP) []string {
+	ss := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ss = append(ss, ip.String())
+	}
+	return ss
+}
+
+// readPrivateKey reads a PEM encoded PKCS#8, PKCS#1 or EC private key.
+func readPrivateKey(filename string) (crypto.Signer, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM private key found", filename)
+	}
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if s, ok := k.(crypto.Signer); ok {
+			return s, nil
+		}
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported private key", filename)
+}
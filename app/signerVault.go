@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultSigner signs the certificates using the sign endpoint of a HashiCorp Vault PKI secrets engine:
+// POST /v1/<mount>/sign/<role>
+type vaultSigner struct {
+	client    *http.Client
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	role      string
+	caCert    *x509.Certificate
+}
+
+// vaultResponse is the body of the Vault API responses.
+type vaultResponse struct {
+	Data struct {
+		Certificate string `json:"certificate,omitempty"`
+	} `json:"data,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func (a *App) newVaultSigner(ctx context.Context) (*vaultSigner, error) {
+	s := &vaultSigner{
+		addr:      a.Config.CertVaultAddr,
+		token:     a.Config.CertVaultToken,
+		namespace: a.Config.CertVaultNamespace,
+		mount:     strings.Trim(a.Config.CertVaultMount, "/"),
+		role:      a.Config.CertVaultRole,
+	}
+	if s.addr == "" {
+		s.addr = os.Getenv("VAULT_ADDR")
+	}
+	if s.token == "" {
+		s.token = os.Getenv("VAULT_TOKEN")
+	}
+	if s.namespace == "" {
+		s.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if s.addr == "" {
+		return nil, UsageError(errors.New("missing --vault-addr flag or VAULT_ADDR environment variable"))
+	}
+	if s.role == "" {
+		return nil, UsageError(errors.New("missing --vault-role flag"))
+	}
+	s.addr = strings.TrimSuffix(s.addr, "/")
+	var err error
+	s.token, err = a.Config.ResolveSecret(s.token)
+	if err != nil {
+		return nil, err
+	}
+	s.client, err = a.signerHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	if a.Config.CertCACert != "" {
+		s.caCert, err = readCertificate(a.Config.CertCACert)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	rsp, err := s.do(ctx, http.MethodGet, fmt.Sprintf("/v1/%s/cert/ca", s.mount), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.caCert, err = parsePEMCertificate([]byte(rsp.Data.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("vault returned an invalid CA certificate: %v", err)
+	}
+	return s, nil
+}
+
+func (s *vaultSigner) sign(ctx context.Context, tmpl *x509.Certificate, csr *x509.CertificateRequest) (*x509.Certificate, error) {
+	body := map[string]interface{}{
+		"csr":         string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})),
+		"common_name": tmpl.Subject.CommonName,
+		"ttl":         fmt.Sprintf("%ds", int64(time.Until(tmpl.NotAfter).Seconds())),
+		"format":      "pem",
+	}
+	altNames := append(append([]string{}, tmpl.DNSNames...), tmpl.EmailAddresses...)
+	if len(altNames) > 0 {
+		body["alt_names"] = strings.Join(altNames, ",")
+	}
+	if len(tmpl.IPAddresses) > 0 {
+		body["ip_sans"] = strings.Join(ipStrings(tmpl.IPAddresses), ",")
+	}
+	if len(tmpl.URIs) > 0 {
+		uris := make([]string, 0, len(tmpl.URIs))
+		for _, u := range tmpl.URIs {
+			uris = append(uris, u.String())
+		}
+		body["uri_sans"] = strings.Join(uris, ",")
+	}
+	rsp, err := s.do(ctx, http.MethodPost, fmt.Sprintf("/v1/%s/sign/%s", s.mount, s.role), body)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parsePEMCertificate([]byte(rsp.Data.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("vault returned an invalid certificate: %v", err)
+	}
+	return c, nil
+}
+
+func (s *vaultSigner) do(ctx context.Context, method, path string, body interface{}) (*vaultResponse, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.addr+path, rd)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("X-Vault-Token", s.token)
+	}
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	r, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault %s %s: %v", method, path, err)
+	}
+	defer r.Body.Close()
+	rb, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	rsp := new(vaultResponse)
+	// error responses are not always JSON
+	jErr := json.Unmarshal(rb, rsp)
+	if r.StatusCode/100 != 2 {
+		if jErr == nil && len(rsp.Errors) > 0 {
+			return nil, fmt.Errorf("vault %s %s: %s: %s", method, path, r.Status, strings.Join(rsp.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault %s %s: %s", method, path, r.Status)
+	}
+	if jErr != nil {
+		return nil, fmt.Errorf("vault %s %s: invalid response: %v", method, path, jErr)
+	}
+	return rsp, nil
+}
+
+// ca returns the CA set with --ca-cert, or the CA of the PKI mount.
+func (s *vaultSigner) ca() *x509.Certificate { return s.caCert }
+
+func (s *vaultSigner) String() string { return signerVault }
+
+func parsePEMCertificate(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
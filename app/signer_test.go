@@ -0,0 +1,373 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCSR returns a CSR for r1.example.net and 192.0.2.1.
+func testCSR(t *testing.T) *x509.CertificateRequest {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "r1.example.net"},
+		DNSNames:    []string{"r1.example.net"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1").To4()},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return csr
+}
+
+// testSignCSR signs csr with ca, as a PKI would, and returns the PEM certificate.
+func testSignCSR(t *testing.T, csr *x509.CertificateRequest, tmpl *x509.Certificate, ca *x509.Certificate, caKey crypto.Signer) string {
+	t.Helper()
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Minute)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, csr.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func Test_localSigner(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := testCertificate(t, "local-ca", 1, time.Now().Add(24*time.Hour), caKey, nil, nil)
+	keyDER, err := x509.MarshalECPrivateKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	caCertFile := filepath.Join(dir, "ca.pem")
+	caKeyFile := filepath.Join(dir, "ca.key")
+	if err := os.WriteFile(caCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(caKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := New()
+	if _, err := a.newSigner(a.ctx); ExitCode(err) != ExitUsage {
+		t.Errorf("newSigner() without --ca-cert and --ca-key: err = %v, want a usage error", err)
+	}
+	a.Config.CertCACert = caCertFile
+	a.Config.CertCAKey = caKeyFile
+	s, err := a.newSigner(a.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.String() != signerLocal {
+		t.Errorf("String() = %q, want %q", s.String(), signerLocal)
+	}
+	if !s.ca().Equal(ca) {
+		t.Errorf("ca() = %q, want %q", s.ca().Subject, ca.Subject)
+	}
+	csr := testCSR(t)
+	tmpl, err := certificateFromCSR(csr, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.sign(a.ctx, tmpl, csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Issuer.String() != ca.Subject.String() {
+		t.Errorf("issuer = %q, want %q", c.Issuer, ca.Subject)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	chains, err := c.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	if err != nil {
+		t.Fatalf("certificate chain verification failed: %v", err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 2 || !chains[0][1].Equal(ca) {
+		t.Errorf("chains = %v, want the certificate and the local CA", chains)
+	}
+	if c.Subject.CommonName != "r1.example.net" || !reflect.DeepEqual(c.DNSNames, csr.DNSNames) {
+		t.Errorf("got certificate CN=%s DNS=%v, want CN=r1.example.net DNS=%v", c.Subject.CommonName, c.DNSNames, csr.DNSNames)
+	}
+}
+
+func Test_vaultSigner(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := testCertificate(t, "vault-ca", 1, time.Now().Add(24*time.Hour), caKey, nil, nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		var cert string
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/pki/cert/ca":
+			cert = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))
+		case "POST /v1/pki/sign/gnoic":
+			body := make(map[string]string)
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			block, _ := pem.Decode([]byte(body["csr"]))
+			csr, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ttl, _ := time.ParseDuration(body["ttl"])
+			tmpl := &x509.Certificate{
+				Subject:  pkix.Name{CommonName: body["common_name"]},
+				DNSNames: strings.Split(body["alt_names"], ","),
+				NotAfter: time.Now().Add(ttl),
+			}
+			for _, ip := range strings.Split(body["ip_sans"], ",") {
+				tmpl.IPAddresses = append(tmpl.IPAddresses, net.ParseIP(ip))
+			}
+			cert = testSignCSR(t, csr, tmpl, ca, caKey)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"certificate": cert},
+		})
+	}))
+	defer srv.Close()
+
+	a := New()
+	a.Config.CertSigner = signerVault
+	a.Config.CertVaultAddr = srv.URL
+	a.Config.CertVaultToken = "s.token"
+	a.Config.CertVaultMount = "pki"
+	a.Config.CertVaultRole = "gnoic"
+	s, err := a.newSigner(a.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.ca().Equal(ca) {
+		t.Errorf("ca() = %q, want %q", s.ca().Subject, ca.Subject)
+	}
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "r1"},
+		DNSNames:    []string{"r1.example.net", "r1"},
+		IPAddresses: []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+		NotAfter:    time.Now().Add(24 * time.Hour),
+	}
+	c, err := s.sign(a.ctx, tmpl, testCSR(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckSignatureFrom(ca); err != nil {
+		t.Errorf("certificate not signed by the vault CA: %v", err)
+	}
+	if c.Subject.CommonName != "r1" || !reflect.DeepEqual(c.DNSNames, tmpl.DNSNames) || len(c.IPAddresses) != 2 {
+		t.Errorf("got certificate CN=%s DNS=%v IP=%v, want CN=r1 DNS=%v IP=%v",
+			c.Subject.CommonName, c.DNSNames, c.IPAddresses, tmpl.DNSNames, tmpl.IPAddresses)
+	}
+	if d := c.NotAfter.Sub(tmpl.NotAfter); d < -time.Minute || d > time.Minute {
+		t.Errorf("NotAfter = %v, want %v", c.NotAfter, tmpl.NotAfter)
+	}
+
+	a.Config.CertVaultToken = "wrong"
+	_, err = a.newSigner(a.ctx)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("newSigner() with a wrong token: err = %v, want permission denied", err)
+	}
+}
+
+// acmeStandIn is a minimal RFC 8555 server issuing a single order.
+// It does not verify the JWS signatures.
+type acmeStandIn struct {
+	t           *testing.T
+	url         string
+	ca          *x509.Certificate
+	caKey       crypto.Signer
+	authzStatus string
+
+	mu    sync.Mutex
+	nonce int
+	ids   []map[string]string
+	cert  string
+}
+
+func (s *acmeStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+	var payload []byte
+	if r.Method == http.MethodPost {
+		jws := struct {
+			Payload string `json:"payload"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payload, _ = base64.RawURLEncoding.DecodeString(jws.Payload)
+	}
+	order := func() map[string]interface{} {
+		o := map[string]interface{}{
+			"status":         "pending",
+			"identifiers":    s.ids,
+			"authorizations": []string{s.url + "/authz/1"},
+			"finalize":       s.url + "/order/1/finalize",
+		}
+		switch {
+		case s.cert != "":
+			o["status"] = "valid"
+			o["certificate"] = s.url + "/cert/1"
+		case s.authzStatus == "valid":
+			o["status"] = "ready"
+		}
+		return o
+	}
+	reply := func(code int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(v)
+	}
+	switch r.URL.Path {
+	case "/directory":
+		reply(http.StatusOK, map[string]string{
+			"newNonce":   s.url + "/nonce",
+			"newAccount": s.url + "/account",
+			"newOrder":   s.url + "/order",
+		})
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+	case "/account":
+		w.Header().Set("Location", s.url+"/account/1")
+		reply(http.StatusCreated, map[string]string{"status": "valid"})
+	case "/order":
+		req := struct {
+			Identifiers []map[string]string `json:"identifiers"`
+		}{}
+		json.Unmarshal(payload, &req)
+		s.ids = req.Identifiers
+		w.Header().Set("Location", s.url+"/order/1")
+		reply(http.StatusCreated, order())
+	case "/authz/1":
+		reply(http.StatusOK, map[string]interface{}{
+			"status":     s.authzStatus,
+			"identifier": s.ids[0],
+		})
+	case "/order/1":
+		w.Header().Set("Location", s.url+"/order/1")
+		reply(http.StatusOK, order())
+	case "/order/1/finalize":
+		req := struct {
+			CSR string `json:"csr"`
+		}{}
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			reply(http.StatusBadRequest, map[string]string{"type": "urn:ietf:params:acme:error:badCSR"})
+			return
+		}
+		s.cert = testSignCSR(s.t, csr, &x509.Certificate{
+			Subject:     csr.Subject,
+			DNSNames:    csr.DNSNames,
+			IPAddresses: csr.IPAddresses,
+			NotAfter:    time.Now().Add(90 * 24 * time.Hour),
+		}, s.ca, s.caKey)
+		w.Header().Set("Location", s.url+"/order/1")
+		reply(http.StatusOK, order())
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		fmt.Fprint(w, s.cert)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func Test_acmeSigner(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := testCertificate(t, "acme-ca", 1, time.Now().Add(24*time.Hour), caKey, nil, nil)
+
+	tests := []struct {
+		name        string
+		authzStatus string
+		wantErr     string
+	}{
+		{name: "authorized", authzStatus: "valid"},
+		{name: "challenge_required", authzStatus: "pending", wantErr: "gnoic does not solve challenges"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &acmeStandIn{t: t, ca: ca, caKey: caKey, authzStatus: tt.authzStatus}
+			srv := httptest.NewServer(standIn)
+			defer srv.Close()
+			standIn.url = srv.URL
+
+			a := New()
+			a.Config.CertSigner = signerACME
+			a.Config.CertACMEDirectory = srv.URL + "/directory"
+			s, err := a.newSigner(a.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			csr := testCSR(t)
+			c, err := s.sign(a.ctx, &x509.Certificate{}, csr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("sign() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.CheckSignatureFrom(ca); err != nil {
+				t.Errorf("certificate not signed by the ACME CA: %v", err)
+			}
+			if !reflect.DeepEqual(c.DNSNames, csr.DNSNames) || !c.IPAddresses[0].Equal(csr.IPAddresses[0]) {
+				t.Errorf("got certificate DNS=%v IP=%v, want DNS=%v IP=%v", c.DNSNames, c.IPAddresses, csr.DNSNames, csr.IPAddresses)
+			}
+			wantIDs := []map[string]string{{"type": "dns", "value": "r1.example.net"}, {"type": "ip", "value": "192.0.2.1"}}
+			if !reflect.DeepEqual(standIn.ids, wantIDs) {
+				t.Errorf("ordered identifiers = %v, want %v", standIn.ids, wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// process exit codes
+const (
+	ExitOK = iota
+	// all the targets failed, or the command failed before reaching the targets
+	ExitFailure
+	// some of the targets failed or were skipped
+	ExitPartialFailure
+	// all the failed targets could not be reached
+	ExitConnectionFailure
+	// invalid command, flags or arguments
+	ExitUsage
+)
+
+// ExitError is an error carrying the process exit code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// UsageError marks err as a usage error, nil if err is nil.
+func UsageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: ExitUsage, Err: err}
+}
+
+// ExitCode returns the process exit code matching err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		return ee.Code
+	}
+	return ExitFailure
+}
+
+// exit codes severity, the exit codes are not ordered by severity
+var exitSeverity = map[int]int{
+	ExitOK:                0,
+	ExitPartialFailure:    1,
+	ExitConnectionFailure: 2,
+	ExitFailure:           3,
+	ExitUsage:             4,
+}
+
+// mostSevereExitCode returns the most severe of the exit codes.
+func mostSevereExitCode(codes ...int) int {
+	code := ExitOK
+	for _, c := range codes {
+		if exitSeverity[c] > exitSeverity[code] {
+			code = c
+		}
+	}
+	return code
+}
+
+// the status of gRPC errors wrapped using %v only survives in their message
+var rpcErrorCodeRegex = regexp.MustCompile(`rpc error: code = (\w+) desc`)
+
+// errorCode returns the gRPC status code of err.
+func errorCode(err error) codes.Code {
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	if m := rpcErrorCodeRegex.FindStringSubmatch(err.Error()); m != nil {
+		for c := codes.OK; c <= codes.Unauthenticated; c++ {
+			if c.String() == m[1] {
+				return c
+			}
+		}
+	}
+	return codes.Unknown
+}
+
+// exitCode returns the exit code of a command which returned errs,
+// based on the targets results.
+func exitCode(errs []error, rs []*result) int {
+	var succeeded, failed, unreachable, skipped int
+	for _, r := range rs {
+		switch r.Status {
+		case statusSuccess:
+			succeeded++
+		case statusFailed:
+			failed++
+			if r.connection {
+				unreachable++
+			}
+		case statusSkipped:
+			skipped++
+		}
+	}
+	switch {
+	case len(errs) == 0 && skipped == 0:
+		return ExitOK
+	case failed == 0 && skipped == 0:
+		// errors not related to a target
+		return ExitFailure
+	case failed > 0 && failed == unreachable:
+		return ExitConnectionFailure
+	case succeeded == 0:
+		return ExitFailure
+	default:
+		return ExitPartialFailure
+	}
+}
+
+type runSummary struct {
+	Command   string           `json:"command,omitempty"`
+	Start     time.Time        `json:"start,omitempty"`
+	Duration  string           `json:"duration,omitempty"`
+	ExitCode  int              `json:"exit-code"`
+	Succeeded []*summaryTarget `json:"succeeded"`
+	Failed    []*summaryTarget `json:"failed"`
+	Skipped   []*summaryTarget `json:"skipped"`
+}
+
+type summaryTarget struct {
+	Target   string `json:"target,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// writeSummary writes the run summary to the file set with --summary-file, if any.
+func (a *App) writeSummary(rs []*result, exitCode int) error {
+	if a.Config.SummaryFile == "" {
+		return nil
+	}
+	s := &runSummary{
+		Command:   a.Config.Command(),
+		Start:     a.start,
+		Duration:  time.Since(a.start).Round(time.Millisecond).String(),
+		ExitCode:  exitCode,
+		Succeeded: make([]*summaryTarget, 0),
+		Failed:    make([]*summaryTarget, 0),
+		Skipped:   make([]*summaryTarget, 0),
+	}
+	for _, r := range rs {
+		st := &summaryTarget{
+			Target:   r.Target,
+			Error:    r.Error,
+			Code:     r.Code,
+			Duration: r.Duration,
+		}
+		switch r.Status {
+		case statusSuccess:
+			s.Succeeded = append(s.Succeeded, st)
+		case statusFailed:
+			s.Failed = append(s.Failed, st)
+		case statusSkipped:
+			s.Skipped = append(s.Skipped, st)
+		}
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.Config.SummaryFile, append(b, '\n'), 0644)
+}
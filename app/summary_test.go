@@ -0,0 +1,98 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_errorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{
+			name: "status",
+			err:  status.Error(codes.PermissionDenied, "denied"),
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "wrapped_status",
+			err:  fmt.Errorf("%q failed creating Rotate gRPC stream: %v", "r1", status.Error(codes.Unavailable, "connection error")),
+			want: codes.Unavailable,
+		},
+		{
+			name: "other",
+			err:  errors.New("file not found"),
+			want: codes.Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(tt.err); got != tt.want {
+				t.Errorf("errorCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_exitCode(t *testing.T) {
+	errFailed := errors.New("failed")
+	success := &result{Status: statusSuccess}
+	failed := &result{Status: statusFailed}
+	unreachable := &result{Status: statusFailed, connection: true}
+	skipped := &result{Status: statusSkipped}
+	tests := []struct {
+		name string
+		errs []error
+		rs   []*result
+		want int
+	}{
+		{
+			name: "ok",
+			rs:   []*result{success, success},
+			want: ExitOK,
+		},
+		{
+			name: "total_failure",
+			errs: []error{errFailed, errFailed},
+			rs:   []*result{failed, unreachable},
+			want: ExitFailure,
+		},
+		{
+			name: "partial_failure",
+			errs: []error{errFailed},
+			rs:   []*result{success, failed},
+			want: ExitPartialFailure,
+		},
+		{
+			name: "skipped",
+			errs: []error{errFailed},
+			rs:   []*result{failed, skipped},
+			want: ExitFailure,
+		},
+		{
+			name: "connection_failure",
+			errs: []error{errFailed},
+			rs:   []*result{success, unreachable},
+			want: ExitConnectionFailure,
+		},
+		{
+			name: "non_target_error",
+			errs: []error{errFailed},
+			rs:   []*result{success},
+			want: ExitFailure,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.errs, tt.rs); got != tt.want {
+				t.Errorf("exitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
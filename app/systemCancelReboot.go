@@ -10,7 +10,6 @@ import (
 	"github.com/openconfig/gnoi/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -39,38 +38,32 @@ func (a *App) RunESystemCancelReboot(cmd *cobra.Command, args []string) error {
 	}
 	numTargets := len(targets)
 	responseChan := make(chan *TargetError, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target, subcomponents []*types.Path) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
-					TargetName: t.Config.Address,
-					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err := a.SystemCancelReboot(ctx, t, subcomponents)
-			responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t, subcomponents)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		err = a.SystemCancelReboot(ctx, t, subcomponents)
+		return sendResponse(responseChan, &TargetError{
+			TargetName: t.Config.Address,
+			Err:        err,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System CancelReboot failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -83,12 +76,12 @@ func (a *App) SystemCancelReboot(ctx context.Context, t *api.Target, subcomponen
 		Message:       a.Config.SystemCancelRebootMessage,
 		Subcomponents: subcomponents,
 	}
-	a.Logger.Debugf("%q System CancelReboot Request: %s", t.Config.Address, prototext.Format(req))
+	a.rpcLogger(t.Config.Name, system.System_CancelReboot_FullMethodName).Debugf("%q System CancelReboot Request: %s", t.Config.Address, prototext.Format(req))
 	a.printMsg(t.Config.Name, req)
 	_, err := t.SystemClient().CancelReboot(ctx, req)
 	if err != nil {
 		return err
 	}
-	a.Logger.Infof("%q System CancelReboot Request was successful", t.Config.Address)
+	a.rpcLogger(t.Config.Name, system.System_CancelReboot_FullMethodName).Infof("%q System CancelReboot Request was successful", t.Config.Address)
 	return nil
 }
@@ -7,10 +7,10 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 
 	"github.com/karimra/gnoic/api"
 	gsystem "github.com/karimra/gnoic/api/system"
+	"github.com/openconfig/gnoi/system"
 )
 
 func (a *App) InitSystemKillProcessFlags(cmd *cobra.Command) {
@@ -51,38 +51,32 @@ func (a *App) RunESystemKillProcess(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *TargetError, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
-					TargetName: t.Config.Address,
-					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err := a.SystemKillProcess(ctx, t)
-			responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		err = a.SystemKillProcess(ctx, t)
+		return sendResponse(responseChan, &TargetError{
+			TargetName: t.Config.Address,
+			Err:        err,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System KillProcess failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -91,12 +85,7 @@ func (a *App) RunESystemKillProcess(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) SystemKillProcess(ctx context.Context, t *api.Target) error {
-	req, err := gsystem.NewSystemKillProcessRequest(
-		gsystem.PID(a.Config.SystemKillProcessPID),
-		gsystem.ProcessName(a.Config.SystemKillProcessName),
-		gsystem.Signal(a.Config.SystemKillProcessSignal),
-		gsystem.ProcessRestart(a.Config.SystemKillProcessRestart),
-	)
+	req, err := a.systemKillProcessRequest()
 	if err != nil {
 		return err
 	}
@@ -104,6 +93,26 @@ func (a *App) SystemKillProcess(ctx context.Context, t *api.Target) error {
 	if err != nil {
 		return err
 	}
-	a.Logger.Infof("%q System KillProcess Request successful", t.Config.Address)
+	a.rpcLogger(t.Config.Name, system.System_KillProcess_FullMethodName).Infof("%q System KillProcess Request successful", t.Config.Address)
 	return nil
 }
+
+func (a *App) systemKillProcessRequest() (*system.KillProcessRequest, error) {
+	return gsystem.NewSystemKillProcessRequest(
+		gsystem.PID(a.Config.SystemKillProcessPID),
+		gsystem.ProcessName(a.Config.SystemKillProcessName),
+		gsystem.Signal(a.Config.SystemKillProcessSignal),
+		gsystem.ProcessRestart(a.Config.SystemKillProcessRestart),
+	)
+}
+
+func (a *App) systemKillProcessDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	req, err := a.systemKillProcessRequest()
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{{
+		RPC:     system.System_KillProcess_FullMethodName,
+		Request: req,
+	}}, nil
+}
@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,15 +14,16 @@ import (
 	"github.com/openconfig/gnoi/system"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
-type targetResponse struct {
-	Target   string      `json:"target,omitempty"`
-	Response interface{} `json:"response,omitempty"`
+type systemPingResponse struct {
+	TargetError
+	rsp []*system.PingResponse
 }
 
+func (r *systemPingResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitSystemPingFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -66,40 +66,39 @@ func (a *App) RunESystemPing(cmd *cobra.Command, args []string) error {
 	}
 
 	numTargets := len(targets)
-	responseChan := make(chan *TargetError, numTargets)
+	responseChan := make(chan *systemPingResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &systemPingResponse{
+				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err := a.SystemPing(ctx, t)
-			responseChan <- &TargetError{
+				},
+			})
+		}
+		defer t.Close()
+		rsps, err := a.SystemPing(ctx, t)
+		return sendResponse(responseChan, &systemPingResponse{
+			TargetError: TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			},
+			rsp: rsps,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System Ping failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -107,7 +106,7 @@ func (a *App) RunESystemPing(cmd *cobra.Command, args []string) error {
 	return a.handleErrs(errs)
 }
 
-func (a *App) SystemPing(ctx context.Context, t *api.Target) error {
+func (a *App) SystemPing(ctx context.Context, t *api.Target) ([]*system.PingResponse, error) {
 	req, err := gsystem.NewSystemPingRequest(
 		gsystem.Destination(a.Config.SystemPingDestination),
 		gsystem.Source(a.Config.SystemPingSource),
@@ -121,105 +120,100 @@ func (a *App) SystemPing(ctx context.Context, t *api.Target) error {
 		gsystem.NetworkInstance(a.Config.SystemPingNetworkInstance),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.Logger.Debugf("ping request:\n%s", prototext.Format(req))
+	return a.systemPing(ctx, t, req, a.textOutput())
+}
+
+// systemPing runs the Ping RPC, the responses are printed as they are received if print is true.
+func (a *App) systemPing(ctx context.Context, t *api.Target, req *system.PingRequest, print bool) ([]*system.PingResponse, error) {
+	a.targetLogger(t.Config.Name).Debugf("ping request:\n%s", prototext.Format(req))
 	a.printMsg(t.Config.Name, req)
 	stream, err := t.SystemClient().Ping(ctx, req)
 	if err != nil {
-		a.Logger.Errorf("%q creating System Ping stream failed: %v", t.Config.Address, err)
-		return err
+		a.rpcLogger(t.Config.Name, system.System_Ping_FullMethodName).Errorf("%q creating System Ping stream failed: %v", t.Config.Address, err)
+		return nil, err
 	}
+	rsps := make([]*system.PingResponse, 0)
 	for {
 		rsp, err := stream.Recv()
 		if err == io.EOF {
-			a.Logger.Debugf("%q sent EOF", t.Config.Address)
+			a.targetLogger(t.Config.Name).Debugf("%q sent EOF", t.Config.Address)
 			break
 		}
 		if err != nil && err != io.EOF {
-			a.Logger.Errorf("%q rcv Ping stream failed: %v", t.Config.Address, err)
-			return err
+			a.rpcLogger(t.Config.Name, system.System_Ping_FullMethodName).Errorf("%q rcv Ping stream failed: %v", t.Config.Address, err)
+			return rsps, err
 		}
-		a.Logger.Debugf("ping response %s:\n%s", t.Config.Name, prototext.Format(rsp))
+		a.targetLogger(t.Config.Name).Debugf("ping response %s:\n%s", t.Config.Name, prototext.Format(rsp))
 		a.printMsg(t.Config.Name, rsp)
-		a.printPingResponse(t.Config.Name, rsp)
+		rsps = append(rsps, rsp)
+		if print {
+			a.printPingResponse(t.Config.Name, rsp)
+		}
 	}
-	return nil
+	return rsps, nil
 }
 
 func (a *App) printPingResponse(name string, rsp *system.PingResponse) {
-	switch a.Config.Format {
-	case "json":
-		tRsp := targetResponse{
-			Target:   name,
-			Response: rsp,
-		}
-		b, err := json.MarshalIndent(tRsp, "", "  ")
-		if err != nil {
-			a.Logger.Errorf("failed to marshal ping response from %q: %v", name, err)
-			return
-		}
-		fmt.Println(string(b))
-	default:
-		sb := strings.Builder{}
-		numAddress := len(a.Config.Address)
-		if rsp.GetBytes() > 0 {
-			if numAddress > 1 {
-				sb.WriteString("[")
-				sb.WriteString(name)
-				sb.WriteString("] ")
-			}
-			sb.WriteString(strconv.Itoa(int(rsp.GetBytes())))
-			sb.WriteString(" bytes from ")
-			sb.WriteString(rsp.GetSource())
-			sb.WriteString(": icmp_seq=")
-			sb.WriteString(strconv.Itoa(int(rsp.GetSequence())))
-			sb.WriteString(" ttl=")
-			sb.WriteString(strconv.Itoa(int(rsp.GetTtl())))
-			sb.WriteString(" time=")
-			sb.WriteString(time.Duration(rsp.GetTime()).String())
-			fmt.Println(sb.String())
-			return
-		}
-		// summary
-		// line1
+	sb := strings.Builder{}
+	numAddress := len(a.Config.Address)
+	if rsp.GetBytes() > 0 {
 		if numAddress > 1 {
 			sb.WriteString("[")
 			sb.WriteString(name)
 			sb.WriteString("] ")
 		}
-		sb.WriteString("--- ")
+		sb.WriteString(strconv.Itoa(int(rsp.GetBytes())))
+		sb.WriteString(" bytes from ")
 		sb.WriteString(rsp.GetSource())
-		sb.WriteString(" ping statistics ---\n")
-		// line2
-		if numAddress > 1 {
-			sb.WriteString("[")
-			sb.WriteString(name)
-			sb.WriteString("] ")
-		}
-		sb.WriteString(strconv.Itoa(int(rsp.GetSent())))
-		sb.WriteString(" packets sent, ")
-		sb.WriteString(strconv.Itoa(int(rsp.GetReceived())))
-		sb.WriteString(" packets received, ")
-		sb.WriteString(fmt.Sprintf("%.2f%% packet loss\n", ((1 - (float32(rsp.GetReceived()) / float32(rsp.GetSent()))) * 100)))
-		// line3
-		if numAddress > 1 {
-			sb.WriteString("[")
-			sb.WriteString(name)
-			sb.WriteString("] ")
-		}
-		sb.WriteString("round-trip min/avg/max/stddev = ")
-		sb.WriteString(formatDurationMS(rsp.GetMinTime()))
-		sb.WriteString("/")
-		sb.WriteString(formatDurationMS(rsp.GetAvgTime()))
-		sb.WriteString("/")
-		sb.WriteString(formatDurationMS(rsp.GetMaxTime()))
-		sb.WriteString("/")
-		sb.WriteString(formatDurationMS(rsp.GetStdDev()))
-		sb.WriteString(" ms")
+		sb.WriteString(": icmp_seq=")
+		sb.WriteString(strconv.Itoa(int(rsp.GetSequence())))
+		sb.WriteString(" ttl=")
+		sb.WriteString(strconv.Itoa(int(rsp.GetTtl())))
+		sb.WriteString(" time=")
+		sb.WriteString(time.Duration(rsp.GetTime()).String())
 		fmt.Println(sb.String())
 		return
 	}
+	// summary
+	// line1
+	if numAddress > 1 {
+		sb.WriteString("[")
+		sb.WriteString(name)
+		sb.WriteString("] ")
+	}
+	sb.WriteString("--- ")
+	sb.WriteString(rsp.GetSource())
+	sb.WriteString(" ping statistics ---\n")
+	// line2
+	if numAddress > 1 {
+		sb.WriteString("[")
+		sb.WriteString(name)
+		sb.WriteString("] ")
+	}
+	sb.WriteString(strconv.Itoa(int(rsp.GetSent())))
+	sb.WriteString(" packets sent, ")
+	sb.WriteString(strconv.Itoa(int(rsp.GetReceived())))
+	sb.WriteString(" packets received, ")
+	sb.WriteString(fmt.Sprintf("%.2f%% packet loss\n", ((1 - (float32(rsp.GetReceived()) / float32(rsp.GetSent()))) * 100)))
+	// line3
+	if numAddress > 1 {
+		sb.WriteString("[")
+		sb.WriteString(name)
+		sb.WriteString("] ")
+	}
+	sb.WriteString("round-trip min/avg/max/stddev = ")
+	sb.WriteString(formatDurationMS(rsp.GetMinTime()))
+	sb.WriteString("/")
+	sb.WriteString(formatDurationMS(rsp.GetAvgTime()))
+	sb.WriteString("/")
+	sb.WriteString(formatDurationMS(rsp.GetMaxTime()))
+	sb.WriteString("/")
+	sb.WriteString(formatDurationMS(rsp.GetStdDev()))
+	sb.WriteString(" ms")
+	fmt.Println(sb.String())
+	return
 }
 
 func formatDurationMS(d int64) string {
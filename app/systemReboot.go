@@ -11,7 +11,6 @@ import (
 	"github.com/openconfig/gnoi/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 func (a *App) InitSystemRebootFlags(cmd *cobra.Command) {
@@ -58,38 +57,32 @@ func (a *App) RunESystemReboot(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *TargetError, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target, subcomponents []*types.Path) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
-					TargetName: t.Config.Address,
-					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err := a.SystemReboot(ctx, t, subcomponents)
-			responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t, subcomponents)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		err = a.SystemReboot(ctx, t, subcomponents)
+		return sendResponse(responseChan, &TargetError{
+			TargetName: t.Config.Address,
+			Err:        err,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System Reboot failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -98,17 +91,35 @@ func (a *App) RunESystemReboot(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) SystemReboot(ctx context.Context, t *api.Target, subcomponents []*types.Path) error {
-	req := &system.RebootRequest{
+	_, err := t.SystemClient().Reboot(ctx, a.systemRebootRequest(subcomponents))
+	if err != nil {
+		return err
+	}
+	a.rpcLogger(t.Config.Name, system.System_Reboot_FullMethodName).Infof("%q System Reboot Request successful", t.Config.Address)
+	return nil
+}
+
+func (a *App) systemRebootRequest(subcomponents []*types.Path) *system.RebootRequest {
+	return &system.RebootRequest{
 		Method:        system.RebootMethod(system.RebootMethod_value[a.Config.SystemRebootMethod]),
 		Delay:         uint64(a.Config.SystemRebootDelay.Nanoseconds()),
 		Message:       a.Config.SystemRebootMessage,
 		Subcomponents: subcomponents,
 		Force:         a.Config.SystemRebootForce,
 	}
-	_, err := t.SystemClient().Reboot(ctx, req)
-	if err != nil {
-		return err
+}
+
+func (a *App) systemRebootDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	subcomponents := make([]*types.Path, len(a.Config.SystemRebootSubcomponents))
+	for i, p := range a.Config.SystemRebootSubcomponents {
+		var err error
+		subcomponents[i], err = utils.ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
 	}
-	a.Logger.Infof("%q System Reboot Request successful", t.Config.Address)
-	return nil
+	return []*dryRunRequest{{
+		RPC:     system.System_Reboot_FullMethodName,
+		Request: a.systemRebootRequest(subcomponents),
+	}}, nil
 }
@@ -15,7 +15,6 @@ import (
 	"github.com/openconfig/gnoi/types"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -24,6 +23,8 @@ type systemRebootStatusResponse struct {
 	rsp *system.RebootStatusResponse
 }
 
+func (r *systemRebootStatusResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitSystemRebootStatusFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -48,55 +49,50 @@ func (a *App) RunESystemRebootStatus(cmd *cobra.Command, args []string) error {
 	}
 	numTargets := len(targets)
 	responseChan := make(chan *systemRebootStatusResponse, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target, subcomponents []*types.Path) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &systemRebootStatusResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.SystemRebootStatus(ctx, t, subcomponents)
-			responseChan <- &systemRebootStatusResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &systemRebootStatusResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t, subcomponents)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.SystemRebootStatus(ctx, t, subcomponents)
+		return sendResponse(responseChan, &systemRebootStatusResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*systemRebootStatusResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System Reboot Status failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 		result = append(result, rsp)
 	}
-
-	s, err := SystemRebootStatusTable(result)
-	if err != nil {
-		return err
+	if a.textOutput() {
+		s, err := SystemRebootStatusTable(result)
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
 	}
-	fmt.Print(s)
 	return a.handleErrs(errs)
 }
 
@@ -109,8 +105,8 @@ func (a *App) SystemRebootStatus(ctx context.Context, t *api.Target, subcomponen
 	if err != nil {
 		return nil, err
 	}
-	a.Logger.Debugf("%q response: %s", t.Config.Address, prototext.Format(resp))
-	a.Logger.Infof("%q rebootStatus active=%v, timeTillReboot=%s, rebootTime=%s, rebootCount=%d",
+	a.targetLogger(t.Config.Name).Debugf("%q response: %s", t.Config.Address, prototext.Format(resp))
+	a.targetLogger(t.Config.Name).Infof("%q rebootStatus active=%v, timeTillReboot=%s, rebootTime=%s, rebootCount=%d",
 		t.Config.Address, resp.Active,
 		time.Duration(resp.Wait), time.Unix(0, int64(resp.When)).String(),
 		resp.Count,
@@ -14,7 +14,6 @@ import (
 	"github.com/karimra/gnoic/api"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type setPackageResponse struct {
@@ -67,54 +66,46 @@ func (a *App) RunESystemSetPackage(cmd *cobra.Command, args []string) error {
 	numTargets := len(targets)
 	responseChan := make(chan *setPackageResponse, numTargets)
 
-	a.wg.Add(numTargets)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
-
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &setPackageResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Name,
-						Err:        err,
-					},
-				}
-				return
-			}
-
-			defer t.Close()
-
-			err = a.SystemSetPackage(ctx, t)
-			responseChan <- &setPackageResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &setPackageResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Name,
 					Err:        err,
 				},
-			}
-		}(t)
-	}
+			})
+		}
 
-	a.wg.Wait()
+		defer t.Close()
+
+		err = t.Retry(ctx, gnoisystem.System_SetPackage_FullMethodName, func(ctx context.Context) error {
+			return a.SystemSetPackage(ctx, t)
+		})
+		return sendResponse(responseChan, &setPackageResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Name,
+				Err:        err,
+			},
+		})
+	})
 
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q SetPackage failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
-		a.Logger.Infof("%q package %s sent successfully", rsp.TargetName, a.Config.SystemSetPackageFile)
+		a.targetLogger(rsp.TargetName).Infof("%q package %s sent successfully", rsp.TargetName, a.Config.SystemSetPackageFile)
 	}
 
 	return a.handleErrs(errs)
@@ -127,7 +118,7 @@ func (a *App) SystemSetPackage(ctx context.Context, t *api.Target) error {
 		return err
 	}
 
-	a.Logger.Infof("target %q: starting SetPackage stream", t.Config.Name)
+	a.rpcLogger(t.Config.Name, gnoisystem.System_SetPackage_FullMethodName).Infof("target %q: starting SetPackage stream", t.Config.Name)
 
 	filename := a.Config.SystemSetPackageFile
 	_, err = os.Stat(filename)
@@ -146,20 +137,16 @@ func (a *App) SystemSetPackage(ctx context.Context, t *api.Target) error {
 func (a *App) sendSysPackageFile(fileName, remoteFile string, sysClient gnoisystem.System_SetPackageClient, t *api.Target) error {
 	f, err := os.Open(fileName)
 	if err != nil {
-		a.Logger.Errorf("failed opening file %q: %v", fileName, err)
+		a.targetLogger(t.Config.Name).Errorf("failed opening file %q: %v", fileName, err)
 
 		return err
 	}
 
 	defer f.Close()
 
-	a.Logger.Infof("%q sending file=%q", t.Config.Address, fileName)
+	a.targetLogger(t.Config.Name).Infof("%q sending file=%q", t.Config.Address, fileName)
 
-	req, err := gsystem.NewSetPackagePackageRequest(
-		gsystem.PackageFile(remoteFile),
-		gsystem.Version(a.Config.SystemSetPackageVersion),
-		gsystem.Activate(a.Config.SystemSetPackageActivate),
-	)
+	req, err := a.systemSetPackageRequest(remoteFile)
 	if err != nil {
 		return err
 	}
@@ -185,7 +172,7 @@ func (a *App) sendSysPackageFile(fileName, remoteFile string, sysClient gnoisyst
 
 		h.Write(b[:n])
 
-		a.Logger.Debugf("%q file=%q, writing %d byte(s)", t.Config.Address, fileName, n)
+		a.targetLogger(t.Config.Name).Debugf("%q file=%q, writing %d byte(s)", t.Config.Address, fileName, n)
 
 		reqContents := &gnoisystem.SetPackageRequest{
 			Request: &gnoisystem.SetPackageRequest_Contents{
@@ -200,7 +187,7 @@ func (a *App) sendSysPackageFile(fileName, remoteFile string, sysClient gnoisyst
 	}
 
 	// send hash
-	a.Logger.Infof("%q sending file=%q hash", t.Config.Address, fileName)
+	a.targetLogger(t.Config.Name).Infof("%q sending file=%q hash", t.Config.Address, fileName)
 
 	reqHash, err := gsystem.NewSetPackageHashRequest(
 		gsystem.Hash("SHA512", h.Sum(nil)),
@@ -220,3 +207,36 @@ func (a *App) sendSysPackageFile(fileName, remoteFile string, sysClient gnoisyst
 	a.printMsg(t.Config.Name, rsp)
 	return err
 }
+
+func (a *App) systemSetPackageRequest(remoteFile string) (*gnoisystem.SetPackageRequest, error) {
+	return gsystem.NewSetPackagePackageRequest(
+		gsystem.PackageFile(remoteFile),
+		gsystem.Version(a.Config.SystemSetPackageVersion),
+		gsystem.Activate(a.Config.SystemSetPackageActivate),
+	)
+}
+
+func (a *App) systemSetPackageDryRun(ctx context.Context, t *api.Target) ([]*dryRunRequest, error) {
+	req, err := a.systemSetPackageRequest(a.Config.SystemSetPackageDstFile)
+	if err != nil {
+		return nil, err
+	}
+	n, sum, err := dryRunContent(a.Config.SystemSetPackageFile, a.Config.SystemSetPackageChunkSize, "SHA512")
+	if err != nil {
+		return nil, err
+	}
+	reqHash, err := gsystem.NewSetPackageHashRequest(
+		gsystem.Hash("SHA512", sum),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return []*dryRunRequest{
+		{RPC: gnoisystem.System_SetPackage_FullMethodName, Request: req},
+		{
+			RPC:  gnoisystem.System_SetPackage_FullMethodName,
+			Note: fmt.Sprintf("%d contents request(s) of up to %d byte(s) from %q", n, a.Config.SystemSetPackageChunkSize, a.Config.SystemSetPackageFile),
+		},
+		{RPC: gnoisystem.System_SetPackage_FullMethodName, Request: reqHash},
+	}, nil
+}
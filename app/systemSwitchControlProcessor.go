@@ -14,7 +14,6 @@ import (
 	"github.com/openconfig/gnoi/system"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type systemSwitchControlProcessorResponse struct {
@@ -22,6 +21,8 @@ type systemSwitchControlProcessorResponse struct {
 	rsp *system.SwitchControlProcessorResponse
 }
 
+func (r *systemSwitchControlProcessorResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitSystemSwitchControlProcessorFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -43,55 +44,50 @@ func (a *App) RunESystemSwitchControlProcessor(cmd *cobra.Command, args []string
 
 	numTargets := len(targets)
 	responseChan := make(chan *systemSwitchControlProcessorResponse, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &systemSwitchControlProcessorResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := a.SystemSwitchControlProcessor(ctx, t)
-			responseChan <- &systemSwitchControlProcessorResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &systemSwitchControlProcessorResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := a.SystemSwitchControlProcessor(ctx, t)
+		return sendResponse(responseChan, &systemSwitchControlProcessorResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*systemSwitchControlProcessorResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System SwitchControlProcessor failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 		result = append(result, rsp)
 	}
-
-	s, err := systemSwitchControlProcessorTable(result)
-	if err != nil {
-		return err
+	if a.textOutput() {
+		s, err := systemSwitchControlProcessorTable(result)
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
 	}
-	fmt.Print(s)
 	return a.handleErrs(errs)
 }
 
@@ -109,7 +105,7 @@ func (a *App) SystemSwitchControlProcessor(ctx context.Context, t *api.Target) (
 		return nil, err
 	}
 	a.printMsg(t.Config.Name, rsp)
-	a.Logger.Infof("%q System SwitchControlProcessor Request successful", t.Config.Address)
+	a.rpcLogger(t.Config.Name, system.System_SwitchControlProcessor_FullMethodName).Infof("%q System SwitchControlProcessor Request successful", t.Config.Address)
 	return rsp, nil
 }
 
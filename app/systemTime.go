@@ -14,7 +14,6 @@ import (
 	"github.com/openconfig/gnoi/system"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 )
 
 type systemTimeResponse struct {
@@ -22,6 +21,8 @@ type systemTimeResponse struct {
 	rsp *system.TimeResponse
 }
 
+func (r *systemTimeResponse) response() interface{} { return r.rsp }
+
 func (a *App) InitSystemTimeFlags(cmd *cobra.Command) {
 	cmd.ResetFlags()
 	//
@@ -39,54 +40,50 @@ func (a *App) RunESystemTime(cmd *cobra.Command, args []string) error {
 
 	numTargets := len(targets)
 	responseChan := make(chan *systemTimeResponse, numTargets)
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &systemTimeResponse{
-					TargetError: TargetError{
-						TargetName: t.Config.Address,
-						Err:        err,
-					},
-				}
-				return
-			}
-			defer t.Close()
-			rsp, err := t.SystemClient().Time(ctx, gsystem.NewSystemTimeRequest())
-			responseChan <- &systemTimeResponse{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &systemTimeResponse{
 				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
 				},
-				rsp: rsp,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			})
+		}
+		defer t.Close()
+		rsp, err := t.SystemClient().Time(ctx, gsystem.NewSystemTimeRequest())
+		return sendResponse(responseChan, &systemTimeResponse{
+			TargetError: TargetError{
+				TargetName: t.Config.Address,
+				Err:        err,
+			},
+			rsp: rsp,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	result := make([]*systemTimeResponse, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System Time failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
 		result = append(result, rsp)
 	}
-	s, err := systemTimeTable(result)
-	if err != nil {
-		return err
+	if a.textOutput() {
+		s, err := systemTimeTable(result)
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
 	}
-	fmt.Print(s)
 	return a.handleErrs(errs)
 }
 
@@ -2,7 +2,6 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,7 +14,6 @@ import (
 	"github.com/openconfig/gnoi/system"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/encoding/prototext"
 )
 
@@ -60,6 +58,13 @@ func (a *App) PreRunESystemTraceRoute(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+type systemTracerouteResponse struct {
+	TargetError
+	rsp []*system.TracerouteResponse
+}
+
+func (r *systemTracerouteResponse) response() interface{} { return r.rsp }
+
 func (a *App) RunESystemTraceRoute(cmd *cobra.Command, args []string) error {
 	targets, err := a.GetTargets()
 	if err != nil {
@@ -67,40 +72,39 @@ func (a *App) RunESystemTraceRoute(cmd *cobra.Command, args []string) error {
 	}
 
 	numTargets := len(targets)
-	responseChan := make(chan *TargetError, numTargets)
+	responseChan := make(chan *systemTracerouteResponse, numTargets)
 
-	a.wg.Add(numTargets)
-	for _, t := range targets {
-		go func(t *api.Target) {
-			defer a.wg.Done()
-			ctx, cancel := context.WithCancel(a.ctx)
-			defer cancel()
-			ctx = metadata.AppendToOutgoingContext(ctx, "username", *t.Config.Username, "password", *t.Config.Password)
+	a.runTargets(targets, func(t *api.Target) error {
+		ctx, cancel := context.WithCancel(a.commandContext(cmd))
+		defer cancel()
 
-			err = t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
-			if err != nil {
-				responseChan <- &TargetError{
+		err := t.CreateGrpcClient(ctx, a.createBaseDialOpts()...)
+		if err != nil {
+			return sendResponse(responseChan, &systemTracerouteResponse{
+				TargetError: TargetError{
 					TargetName: t.Config.Address,
 					Err:        err,
-				}
-				return
-			}
-			defer t.Close()
-			err := a.SystemTraceRoute(ctx, t)
-			responseChan <- &TargetError{
+				},
+			})
+		}
+		defer t.Close()
+		rsps, err := a.SystemTraceRoute(ctx, t)
+		return sendResponse(responseChan, &systemTracerouteResponse{
+			TargetError: TargetError{
 				TargetName: t.Config.Address,
 				Err:        err,
-			}
-		}(t)
-	}
-	a.wg.Wait()
+			},
+			rsp: rsps,
+		})
+	})
 	close(responseChan)
 
 	errs := make([]error, 0, numTargets)
 	for rsp := range responseChan {
+		a.addResult(rsp)
 		if rsp.Err != nil {
 			wErr := fmt.Errorf("%q System Traceroute failed: %v", rsp.TargetName, rsp.Err)
-			a.Logger.Error(wErr)
+			a.targetLogger(rsp.TargetName).Error(wErr)
 			errs = append(errs, wErr)
 			continue
 		}
@@ -108,7 +112,7 @@ func (a *App) RunESystemTraceRoute(cmd *cobra.Command, args []string) error {
 	return a.handleErrs(errs)
 }
 
-func (a *App) SystemTraceRoute(ctx context.Context, t *api.Target) error {
+func (a *App) SystemTraceRoute(ctx context.Context, t *api.Target) ([]*system.TracerouteResponse, error) {
 	req, err := gsystem.NewSystemTracerouteRequest(
 		gsystem.Destination(a.Config.SystemTracerouteDestination),
 		gsystem.Source(a.Config.SystemTracerouteSource),
@@ -123,83 +127,73 @@ func (a *App) SystemTraceRoute(ctx context.Context, t *api.Target) error {
 		gsystem.NetworkInstance(a.Config.SystemTracerouteNetworkInstance),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	a.Logger.Debug(prototext.Format(req))
+	a.targetLogger(t.Config.Name).Debug(prototext.Format(req))
 	a.printMsg(t.Config.Name, req)
 	stream, err := t.SystemClient().Traceroute(ctx, req)
 	if err != nil {
-		a.Logger.Errorf("creating System Traceroute stream failed: %v", err)
-		return err
+		a.rpcLogger(t.Config.Name, system.System_Traceroute_FullMethodName).Errorf("creating System Traceroute stream failed: %v", err)
+		return nil, err
 	}
+	rsps := make([]*system.TracerouteResponse, 0)
 	for {
 		rsp, err := stream.Recv()
 		if err == io.EOF {
-			a.Logger.Debugf("%q sent EOF", t.Config.Address)
+			a.targetLogger(t.Config.Name).Debugf("%q sent EOF", t.Config.Address)
 			break
 		}
 		if err != nil && err != io.EOF {
-			a.Logger.Errorf("rcv System Traceroute stream failed: %v", err)
-			return err
+			a.rpcLogger(t.Config.Name, system.System_Traceroute_FullMethodName).Errorf("rcv System Traceroute stream failed: %v", err)
+			return rsps, err
 		}
 		a.printMsg(t.Config.Name, rsp)
-		a.printTracerouteResponse(t.Config.Name, rsp)
+		rsps = append(rsps, rsp)
+		if a.textOutput() {
+			a.printTracerouteResponse(t.Config.Name, rsp)
+		}
 	}
-	return nil
+	return rsps, nil
 }
 
 func (a *App) printTracerouteResponse(name string, rsp *system.TracerouteResponse) {
 	a.pm.Lock()
 	defer a.pm.Unlock()
 
-	switch a.Config.Format {
-	case "json":
-		tRsp := targetResponse{
-			Target:   name,
-			Response: rsp,
-		}
-		b, err := json.MarshalIndent(tRsp, "", "  ")
-		if err != nil {
-			a.Logger.Errorf("failed to marshal traceroute response from %q: %v", name, err)
-			return
-		}
-		fmt.Fprintln(os.Stdout, string(b))
-	default:
-		sb := &strings.Builder{}
-		if len(a.Config.Address) > 1 {
-			sb.WriteString("[")
-			sb.WriteString(name)
-			sb.WriteString("] ")
-		}
-		// Fist msg
-		if rsp.DestinationAddress != "" {
-			fmt.Fprint(sb, "traceroute to ")
-			tracerouteHostnameIPString(sb, rsp.DestinationName, rsp.DestinationAddress)
-			fmt.Fprintf(sb, ", %d max hops, %d byte packets",
-				rsp.GetHops(),
-				rsp.GetPacketSize(),
-			)
-			fmt.Fprintln(os.Stdout, sb.String())
-			return
-		}
-		// rest of messages
-		// Hop index
-		fmt.Fprintf(sb, "%d ", rsp.GetHop())
-		// hostname (IP)
-		tracerouteHostnameIPString(sb, rsp.Name, rsp.Address)
-		// AS path
-		tracerouteASPathString(sb, rsp.GetAsPath())
-		// MPLS
-		if len(rsp.Mpls) > 0 {
-			fmt.Fprintf(sb, "<MPLS:L=%s,E=%s,S=%s,T=%s> ", rsp.Mpls["Label"], rsp.Mpls["E"], rsp.Mpls["S"], rsp.Mpls["TTL"])
-		}
-		// RTT
-		if rsp.Rtt != 0 {
-			fmt.Fprintf(sb, " %s", time.Duration(rsp.Rtt).String())
-		}
-		tracerouteStateString(sb, rsp)
+	sb := &strings.Builder{}
+	if len(a.Config.Address) > 1 {
+		sb.WriteString("[")
+		sb.WriteString(name)
+		sb.WriteString("] ")
+	}
+	// Fist msg
+	if rsp.DestinationAddress != "" {
+		fmt.Fprint(sb, "traceroute to ")
+		tracerouteHostnameIPString(sb, rsp.DestinationName, rsp.DestinationAddress)
+		fmt.Fprintf(sb, ", %d max hops, %d byte packets",
+			rsp.GetHops(),
+			rsp.GetPacketSize(),
+		)
 		fmt.Fprintln(os.Stdout, sb.String())
+		return
+	}
+	// rest of messages
+	// Hop index
+	fmt.Fprintf(sb, "%d ", rsp.GetHop())
+	// hostname (IP)
+	tracerouteHostnameIPString(sb, rsp.Name, rsp.Address)
+	// AS path
+	tracerouteASPathString(sb, rsp.GetAsPath())
+	// MPLS
+	if len(rsp.Mpls) > 0 {
+		fmt.Fprintf(sb, "<MPLS:L=%s,E=%s,S=%s,T=%s> ", rsp.Mpls["Label"], rsp.Mpls["E"], rsp.Mpls["S"], rsp.Mpls["TTL"])
+	}
+	// RTT
+	if rsp.Rtt != 0 {
+		fmt.Fprintf(sb, " %s", time.Duration(rsp.Rtt).String())
 	}
+	tracerouteStateString(sb, rsp)
+	fmt.Fprintln(os.Stdout, sb.String())
 }
 
 func tracerouteHostnameIPString(sb *strings.Builder, name, addr string) {
@@ -4,14 +4,25 @@ import (
 	"github.com/karimra/gnoic/api"
 )
 
+// GetTargets returns the targets the command runs against,
+// those checked by Guard if the command is destructive.
 func (a *App) GetTargets() (map[string]*api.Target, error) {
-	targetsConfigs, err := a.Config.GetTargets()
-	if err != nil {
-		return nil, err
+	targetsConfigs := a.guardedTargets
+	if targetsConfigs == nil {
+		var err error
+		targetsConfigs, err = a.Config.GetTargets()
+		if err != nil {
+			return nil, err
+		}
 	}
 	targets := make(map[string]*api.Target)
 	for n, tc := range targetsConfigs {
 		targets[n] = api.NewTargetFromConfig(tc)
+		a.targetNames.Store(tc.Address, tc.Name)
+		a.journalTarget(tc.Name, tc.Address)
+		if a.pool != nil {
+			targets[n].SetPool(a.pool)
+		}
 	}
 	return targets, nil
 }
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+type targetInfo struct {
+	Name       string            `json:"name,omitempty"`
+	Address    string            `json:"address,omitempty"`
+	ResolvedIP string            `json:"resolved-ip,omitempty"`
+	CommonName string            `json:"common-name,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+func (a *App) RunETargetsList(cmd *cobra.Command, args []string) error {
+	targetsConfigs, err := a.Config.GetTargets()
+	if err != nil {
+		return err
+	}
+	infos := make([]*targetInfo, 0, len(targetsConfigs))
+	for _, tc := range targetsConfigs {
+		infos = append(infos, &targetInfo{
+			Name:       tc.Name,
+			Address:    tc.Address,
+			ResolvedIP: tc.ResolvedIP,
+			CommonName: tc.CommonName,
+			Tags:       tc.Tags,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	switch a.Config.Format {
+	default:
+		fmt.Print(targetsTable(infos))
+	case "json":
+		b, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	}
+	return nil
+}
+
+func targetsTable(infos []*targetInfo) string {
+	tabData := make([][]string, 0, len(infos))
+	for _, ti := range infos {
+		tabData = append(tabData, []string{
+			ti.Name,
+			ti.Address,
+			ti.ResolvedIP,
+			ti.CommonName,
+			formatTags(ti.Tags),
+		})
+	}
+	b := new(bytes.Buffer)
+	table := tablewriter.NewWriter(b)
+	table.SetHeader([]string{"Target Name", "Address", "Resolved IP", "Common Name", "Tags"})
+	formatTable(table)
+	table.AppendBulk(tabData)
+	table.Render()
+	return b.String()
+}
+
+func formatTags(tags map[string]string) string {
+	kvs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return strings.Join(kvs, ",")
+}
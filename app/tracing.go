@@ -0,0 +1,296 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/karimra/gnoic/api"
+)
+
+const tracerName = "github.com/karimra/gnoic"
+
+// commands not traced
+var noTraceCommands = []string{"completion", "exporter", "help", "history", "server", "shell"}
+
+// tracing holds the spans of the running command.
+type tracing struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	// --trace-file
+	file *os.File
+	// context of the command span
+	ctx context.Context
+	// contexts of the targets spans indexed by address
+	targets sync.Map
+}
+
+func (a *App) tracingEnabled() bool {
+	return a.Config.OTLPEndpoint != "" || a.Config.TraceFile != ""
+}
+
+// Trace runs the command within a span, with a child span per target
+// and per RPC, exported over OTLP or to the --trace-file.
+func (a *App) Trace(cmd *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	if !a.tracingEnabled() || sInList(cmd.Name(), noTraceCommands) {
+		return runE(cmd, args)
+	}
+	tr, err := a.newTracing(a.commandContext(cmd))
+	if err != nil {
+		return err
+	}
+	var span trace.Span
+	tr.ctx, span = tr.tracer.Start(a.commandContext(cmd), a.Config.Command(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("gnoic.command", a.Config.Command()),
+			attribute.StringSlice("gnoic.args", args),
+		))
+
+	a.tracing = tr
+	err = runE(cmd, args)
+	a.tracing = nil
+
+	span.SetAttributes(
+		attribute.Int("gnoic.targets", len(a.targetResults())),
+		attribute.Int("gnoic.exit_code", ExitCode(err)),
+	)
+	endSpan(span, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if tErr := tr.provider.Shutdown(ctx); tErr != nil {
+		a.Logger.Warnf("failed to export traces: %v", tErr)
+	}
+	if tr.file != nil {
+		tr.file.Close()
+	}
+	return err
+}
+
+func (a *App) newTracing(ctx context.Context) (*tracing, error) {
+	tr := new(tracing)
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "gnoic"),
+			attribute.String("service.version", version),
+		)),
+	}
+	if a.Config.OTLPEndpoint != "" {
+		eOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(a.Config.OTLPEndpoint)}
+		if a.Config.OTLPInsecure {
+			eOpts = append(eOpts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, eOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	if a.Config.TraceFile != "" {
+		f, err := os.OpenFile(a.Config.TraceFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		tr.file = f
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	}
+	tr.provider = sdktrace.NewTracerProvider(opts...)
+	tr.tracer = tr.provider.Tracer(tracerName, trace.WithInstrumentationVersion(version))
+	return tr, nil
+}
+
+// traceTarget runs fn within the span of target t.
+func (a *App) traceTarget(t *api.Target, fn func(t *api.Target) error) error {
+	tr := a.tracing
+	if tr == nil {
+		return fn(t)
+	}
+	ctx, span := tr.tracer.Start(tr.ctx, "target "+t.Config.Name,
+		trace.WithAttributes(
+			attribute.String("gnoic.target", t.Config.Name),
+			attribute.String("gnoic.target.address", t.Config.Address),
+		))
+	tr.targets.Store(t.Config.Address, ctx)
+	err := fn(t)
+	endSpan(span, err)
+	return err
+}
+
+// rpcSpan starts the span of an RPC sent to address, as a child of its target span.
+func (tr *tracing) rpcSpan(address, method string) trace.Span {
+	ctx := tr.ctx
+	if tctx, ok := tr.targets.Load(address); ok {
+		ctx = tctx.(context.Context)
+	}
+	service, rpc := splitMethod(method)
+	_, span := tr.tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", rpc),
+			attribute.String("server.address", address),
+		))
+	return span
+}
+
+// splitMethod splits a full method name such as /gnoi.system.System/Reboot
+// into its service and method names.
+func splitMethod(method string) (string, string) {
+	method = strings.TrimPrefix(method, "/")
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		return method[:i], method[i+1:]
+	}
+	return "", method
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statusCode returns the gRPC status code of an RPC or context error.
+func statusCode(err error) codes.Code {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Code()
+	}
+	return status.Code(err)
+}
+
+// protoSize returns the encoded size of m, if it is a proto message.
+func protoSize(m interface{}) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return 0
+}
+
+// traceUnaryInterceptor creates a span per unary RPC.
+func (a *App) traceUnaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		tr := a.tracing
+		if tr == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		span := tr.rpcSpan(cc.Target(), method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		span.SetAttributes(
+			attribute.Int("rpc.grpc.status_code", int(statusCode(err))),
+			attribute.Int("gnoic.rpc.bytes_sent", protoSize(req)),
+		)
+		if err == nil {
+			span.SetAttributes(attribute.Int("gnoic.rpc.bytes_received", protoSize(reply)))
+		}
+		endSpan(span, err)
+		return err
+	}
+}
+
+// traceStreamInterceptor creates a span per streaming RPC, ended once the stream is done.
+func (a *App) traceStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		tr := a.tracing
+		if tr == nil {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		span := tr.rpcSpan(cc.Target(), method)
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(statusCode(err))))
+			endSpan(span, err)
+			return s, err
+		}
+		ts := &tracedStream{ClientStream: s, span: span, serverStreams: desc.ServerStreams}
+		go func() {
+			<-ctx.Done()
+			ts.finish(ctx.Err())
+		}()
+		return ts, nil
+	}
+}
+
+type tracedStream struct {
+	grpc.ClientStream
+	span          trace.Span
+	serverStreams bool
+
+	once          sync.Once
+	m             sync.Mutex
+	sent, recv    int
+	bytesSent     int
+	bytesReceived int
+}
+
+func (s *tracedStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.m.Lock()
+		s.sent++
+		s.bytesSent += protoSize(m)
+		s.m.Unlock()
+	}
+	return err
+}
+
+func (s *tracedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.m.Lock()
+		s.recv++
+		s.bytesReceived += protoSize(m)
+		s.m.Unlock()
+		// a client streaming RPC is done once its single response is received
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+// finish ends the stream span, only the first call has an effect.
+func (s *tracedStream) finish(err error) {
+	s.once.Do(func() {
+		s.m.Lock()
+		s.span.SetAttributes(
+			attribute.Int("rpc.grpc.status_code", int(statusCode(err))),
+			attribute.Int("gnoic.rpc.messages_sent", s.sent),
+			attribute.Int("gnoic.rpc.messages_received", s.recv),
+			attribute.Int("gnoic.rpc.bytes_sent", s.bytesSent),
+			attribute.Int("gnoic.rpc.bytes_received", s.bytesReceived),
+		)
+		s.m.Unlock()
+		endSpan(s.span, err)
+	})
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/openconfig/gnoi/file"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+)
+
+// recvStream returns the queued errors on RecvMsg.
+type recvStream struct {
+	grpc.ClientStream
+	errs []error
+}
+
+func (s *recvStream) SendMsg(m interface{}) error { return nil }
+
+func (s *recvStream) RecvMsg(m interface{}) error {
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func Test_tracedStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverStreams bool
+		errs          []error
+		wantRecv      int64
+	}{
+		{
+			name:          "server_streaming",
+			serverStreams: true,
+			errs:          []error{nil, nil, io.EOF},
+			wantRecv:      2,
+		},
+		{
+			name:     "client_streaming",
+			errs:     []error{nil},
+			wantRecv: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			_, span := tp.Tracer("test").Start(context.Background(), "rpc")
+			s := &tracedStream{
+				ClientStream:  &recvStream{errs: tt.errs},
+				span:          span,
+				serverStreams: tt.serverStreams,
+			}
+			req := &file.PutRequest{Request: &file.PutRequest_Contents{Contents: []byte("data")}}
+			if err := s.SendMsg(req); err != nil {
+				t.Fatal(err)
+			}
+			for range tt.errs {
+				s.RecvMsg(new(file.PutResponse))
+			}
+			s.finish(context.Canceled)
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d ended span(s), want 1", len(spans))
+			}
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range spans[0].Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			if got := attrs["gnoic.rpc.messages_sent"].AsInt64(); got != 1 {
+				t.Errorf("messages_sent = %d, want 1", got)
+			}
+			if got := attrs["gnoic.rpc.messages_received"].AsInt64(); got != tt.wantRecv {
+				t.Errorf("messages_received = %d, want %d", got, tt.wantRecv)
+			}
+			if got := attrs["gnoic.rpc.bytes_sent"].AsInt64(); got != 6 {
+				t.Errorf("bytes_sent = %d, want 6", got)
+			}
+			if got := attrs["rpc.grpc.status_code"].AsInt64(); got != 0 {
+				t.Errorf("status_code = %d, want 0", got)
+			}
+		})
+	}
+}
@@ -1,11 +1,8 @@
 package app
 
-import "time"
-
 const (
-	defaultGrpcPort   = "57400"
-	msgSize           = 512 * 1024 * 1024
-	defaultRetryTimer = 10 * time.Second
+	defaultGrpcPort = "57400"
+	msgSize         = 512 * 1024 * 1024
 )
 
 var tlsVersions = []string{"1.3", "1.2", "1.1", "1.0", "1"}
@@ -14,3 +11,12 @@ type TargetError struct {
 	TargetName string
 	Err        error
 }
+
+// targetErr returns the error reported for the target.
+func (te *TargetError) targetErr() error { return te.Err }
+
+// targetName returns the name the target is reported under.
+func (te *TargetError) targetName() string { return te.TargetName }
+
+// response returns the target response, nil if the command has none.
+func (te *TargetError) response() interface{} { return nil }
@@ -19,12 +19,32 @@ func sInListNotEmpty(s string, l []string) bool {
 }
 
 func (a *App) handleErrs(errs []error) error {
+	err := a.printResults()
+	if err != nil {
+		a.Logger.Errorf("failed to print results: %v", err)
+	}
+	rs := a.targetResults()
+	code := exitCode(errs, rs)
+	err = a.writeSummary(rs, code)
+	if err != nil {
+		a.Logger.Errorf("failed to write summary file: %v", err)
+	}
 	numErrors := len(errs)
+	numSkipped := len(a.skipped)
 	if numErrors > 0 {
 		for _, e := range errs {
 			a.Logger.Debug(e)
 		}
-		return fmt.Errorf("there was %d error(s)", numErrors)
+		if numSkipped > 0 {
+			return &ExitError{
+				Code: code,
+				Err:  fmt.Errorf("there was %d error(s), %d target(s) skipped", numErrors, numSkipped),
+			}
+		}
+		return &ExitError{Code: code, Err: fmt.Errorf("there was %d error(s)", numErrors)}
+	}
+	if numSkipped > 0 {
+		return &ExitError{Code: code, Err: fmt.Errorf("%d target(s) skipped", numSkipped)}
 	}
 	return nil
 }
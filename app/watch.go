@@ -0,0 +1,203 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// read-only commands supporting --watch
+var watchCommands = []string{
+	"cert-get-certs",
+	"file-stat",
+	"healthz-get",
+	"healthz-list",
+	"os-verify",
+	"system-reboot-status",
+	"system-time",
+}
+
+// watchCondition is a comma separated list of key==value or key!=value requirements,
+// all of which must match a target response.
+type watchCondition []watchRequirement
+
+type watchRequirement struct {
+	key    string
+	value  string
+	negate bool
+}
+
+func parseWatchCondition(s string) (watchCondition, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	wc := make(watchCondition, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		r := watchRequirement{}
+		var k, v string
+		var ok bool
+		if k, v, ok = strings.Cut(part, "!="); ok {
+			r.negate = true
+		} else if k, v, ok = strings.Cut(part, "=="); !ok {
+			k, v, ok = strings.Cut(part, "=")
+		}
+		r.key, r.value = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || r.key == "" || r.value == "" {
+			return nil, fmt.Errorf("invalid until condition %q, expected key==value or key!=value", part)
+		}
+		wc = append(wc, r)
+	}
+	return wc, nil
+}
+
+func (wc watchCondition) matches(flat map[string]string) bool {
+	for _, r := range wc {
+		if !r.matches(flat) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches checks if one of the response fields named key, at any depth, has the requirement value.
+// The value can be a glob pattern, enum values also match without their type prefix,
+// e.g: HEALTHY matches STATUS_HEALTHY.
+func (r watchRequirement) matches(flat map[string]string) bool {
+	found := false
+	for p, v := range flat {
+		if p != r.key && !strings.HasSuffix(p, "."+r.key) {
+			continue
+		}
+		if ok, _ := path.Match(r.value, v); ok ||
+			strings.EqualFold(v, r.value) ||
+			strings.HasSuffix(strings.ToUpper(v), "_"+strings.ToUpper(r.value)) {
+			found = true
+			break
+		}
+	}
+	return found != r.negate
+}
+
+// Watch runs the command once, or at the --watch interval until the --until condition is met.
+func (a *App) Watch(cmd *cobra.Command, args []string, runE func(*cobra.Command, []string) error) error {
+	if a.Config.Watch <= 0 {
+		return runE(cmd, args)
+	}
+	if !sInList(a.Config.Command(), watchCommands) {
+		return UsageError(fmt.Errorf("%q does not support --watch", cmd.CommandPath()))
+	}
+	cond, err := parseWatchCondition(a.Config.Until)
+	if err != nil {
+		return UsageError(err)
+	}
+	ticker := time.NewTicker(a.Config.Watch)
+	defer ticker.Stop()
+	// previous response of each target
+	prev := make(map[string]map[string]string)
+	for {
+		a.start = time.Now()
+		a.m.Lock()
+		a.results = nil
+		a.durations = nil
+		a.skipped = nil
+		a.m.Unlock()
+		if a.textOutput() {
+			fmt.Printf("Every %s: %s\t%s\n", a.Config.Watch, cmd.CommandPath(), a.start.Format(time.RFC3339))
+		}
+		err = runE(cmd, args)
+		rs := a.targetResults()
+		done := len(cond) > 0 && len(rs) > 0
+		for _, r := range rs {
+			if r.Status != statusSuccess {
+				done = false
+				continue
+			}
+			flat := flattenResponse(r.Response)
+			if p, ok := prev[r.Target]; ok && a.textOutput() {
+				if changes := diffResponses(p, flat); len(changes) > 0 {
+					fmt.Printf("%q changes:\n  %s\n", r.Target, strings.Join(changes, "\n  "))
+				}
+			}
+			prev[r.Target] = flat
+			if !cond.matches(flat) {
+				done = false
+			}
+		}
+		if done {
+			a.Logger.Infof("all targets match %q", a.Config.Until)
+			return nil
+		}
+		select {
+		case <-a.commandContext(cmd).Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// flattenResponse flattens a normalized response into a map of dotted paths to values,
+// list items are indexed by their position, e.g: component.children.0.status
+func flattenResponse(v interface{}) map[string]string {
+	flat := make(map[string]string)
+	var walk func(p string, v interface{})
+	walk = func(p string, v interface{}) {
+		join := func(k string) string {
+			if p == "" {
+				return k
+			}
+			return p + "." + k
+		}
+		switch v := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			for k, e := range v {
+				walk(join(k), e)
+			}
+		case []interface{}:
+			for i, e := range v {
+				walk(join(strconv.Itoa(i)), e)
+			}
+		case float64:
+			flat[p] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			flat[p] = fmt.Sprint(v)
+		}
+	}
+	walk("", v)
+	return flat
+}
+
+// diffResponses lists the fields added (+), removed (-) and changed (~) between two flattened responses.
+func diffResponses(prev, cur map[string]string) []string {
+	keys := make([]string, 0, len(cur))
+	for k := range cur {
+		keys = append(keys, k)
+	}
+	for k := range prev {
+		if _, ok := cur[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	changes := make([]string, 0)
+	for _, k := range keys {
+		pv, inPrev := prev[k]
+		cv, inCur := cur[k]
+		switch {
+		case !inPrev:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", k, cv))
+		case !inCur:
+			changes = append(changes, fmt.Sprintf("- %s: %s", k, pv))
+		case pv != cv:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", k, pv, cv))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_watchCondition(t *testing.T) {
+	rsp := map[string]interface{}{
+		"component": map[string]interface{}{
+			"path":   "/components/component[name=cpu0]",
+			"status": "STATUS_HEALTHY",
+		},
+		"active": false,
+	}
+	flat := flattenResponse(rsp)
+	tests := []struct {
+		name    string
+		cond    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "enum_suffix", cond: "status==HEALTHY", want: true},
+		{name: "full_path", cond: "component.status==STATUS_HEALTHY", want: true},
+		{name: "bool", cond: "active=false", want: true},
+		{name: "negate", cond: "status!=UNHEALTHY", want: true},
+		{name: "glob", cond: "status==STATUS_*", want: true},
+		{name: "all_requirements", cond: "status==HEALTHY,active==true", want: false},
+		{name: "missing_key", cond: "state==UP", want: false},
+		{name: "invalid", cond: "status", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc, err := parseWatchCondition(tt.cond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWatchCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := wc.matches(flat); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffResponses(t *testing.T) {
+	prev := map[string]string{"active": "true", "wait": "10", "reason": "upgrade"}
+	cur := map[string]string{"active": "false", "wait": "10", "status.status": "STATUS_SUCCESS"}
+	want := []string{
+		"~ active: true -> false",
+		"- reason: upgrade",
+		"+ status.status: STATUS_SUCCESS",
+	}
+	if got := diffResponses(prev, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffResponses() = %v, want %v", got, want)
+	}
+}
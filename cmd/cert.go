@@ -40,6 +40,8 @@ func newCertCmd() *cobra.Command {
 		newCertRevokeCertificatesCmd(),
 		newCertCanGenerateCSRCmd(),
 		newCertCreateCaCmd(),
+		newCertAuditCmd(),
+		newCertRenewCmd(),
 	)
 	return cmd
 }
@@ -184,3 +186,29 @@ func newCertCreateCaCmd() *cobra.Command {
 	gApp.InitCertCreateCaFlags(cmd)
 	return cmd
 }
+
+// newCertAuditCmd represents the cert audit command
+func newCertAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "audit",
+		Short:        "audit the targets certificates expiry, keys, signature and issuer",
+		PreRunE:      gApp.PreRunECertAudit,
+		RunE:         gApp.RunECertAudit,
+		SilenceUsage: true,
+	}
+	gApp.InitCertAuditFlags(cmd)
+	return cmd
+}
+
+// newCertRenewCmd represents the cert renew command
+func newCertRenewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "renew",
+		Short:        "rotate the targets certificates expiring soon or failing the audit checks",
+		PreRunE:      gApp.PreRunECertRenew,
+		RunE:         gApp.RunECertRenew,
+		SilenceUsage: true,
+	}
+	gApp.InitCertRenewFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// newExporterCmd represents the exporter command
+func newExporterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "exporter",
+		Short:        "periodically probe the targets and expose the results as Prometheus metrics",
+		Args:         cobra.NoArgs,
+		PreRunE:      gApp.PreRunEExporter,
+		RunE:         gApp.RunEExporter,
+		SilenceUsage: true,
+	}
+	gApp.InitExporterFlags(cmd)
+	return cmd
+}
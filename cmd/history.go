@@ -0,0 +1,17 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// newHistoryCmd represents the history command
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "history",
+		Short:        "query the journal of the commands run from this host",
+		Args:         cobra.NoArgs,
+		PreRunE:      gApp.PreRunEHistory,
+		RunE:         gApp.RunEHistory,
+		SilenceUsage: true,
+	}
+	gApp.InitHistoryFlags(cmd)
+	return cmd
+}
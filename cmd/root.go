@@ -18,6 +18,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/karimra/gnoic/app"
 	"github.com/spf13/cobra"
@@ -28,9 +29,10 @@ var gApp = app.New()
 
 func newRootCmd() *cobra.Command {
 	gApp.RootCmd = &cobra.Command{
-		Use:               "gnoic",
-		Short:             "run gNOI RPCs from the terminal",
-		PersistentPreRunE: gApp.PreRun,
+		Use:                "gnoic",
+		Short:              "run gNOI RPCs from the terminal",
+		PersistentPreRunE:  gApp.PreRun,
+		PersistentPostRunE: gApp.PostRun,
 	}
 	gApp.InitGlobalFlags()
 
@@ -46,17 +48,69 @@ func newRootCmd() *cobra.Command {
 		newServerCmd(),
 		newFactoryResetCmd(),
 		newServicesCmd(),
+		newTargetsCmd(),
+		newSecretsCmd(),
+		newShellCmd(),
+		newHistoryCmd(),
+		newExporterCmd(),
 	)
+	gApp.RootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return app.UsageError(err)
+	})
+	markUsageErrors(gApp.RootCmd)
+	wrapRunE(gApp.RootCmd)
 
 	return gApp.RootCmd
 }
 
+// markUsageErrors makes the errors returned by the commands arguments validation
+// and pre run functions usage errors.
+func markUsageErrors(cmd *cobra.Command) {
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			return app.UsageError(args(cmd, a))
+		}
+	}
+	if preRunE := cmd.PreRunE; preRunE != nil {
+		cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+			return app.UsageError(preRunE(cmd, args))
+		}
+	}
+	for _, c := range cmd.Commands() {
+		markUsageErrors(c)
+	}
+}
+
+// wrapRunE records the commands in the journal, traces them, adds the destructive
+// commands safety checks and handles the --dry-run and --watch flags of the commands.
+func wrapRunE(cmd *cobra.Command) {
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return gApp.Journal(cmd, args, func(cmd *cobra.Command, args []string) error {
+				return gApp.Trace(cmd, args, func(cmd *cobra.Command, args []string) error {
+					return gApp.Guard(cmd, args, func(cmd *cobra.Command, args []string) error {
+						return gApp.DryRun(cmd, args, func(cmd *cobra.Command, args []string) error {
+							return gApp.Watch(cmd, args, runE)
+						})
+					})
+				})
+			})
+		}
+	}
+	for _, c := range cmd.Commands() {
+		wrapRunE(c)
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := newRootCmd().Execute()
 	if err != nil {
-		os.Exit(1)
+		if strings.HasPrefix(err.Error(), "unknown command") {
+			os.Exit(app.ExitUsage)
+		}
+		os.Exit(app.ExitCode(err))
 	}
 }
 
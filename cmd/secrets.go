@@ -0,0 +1,55 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// newSecretsCmd represents the secrets command
+func newSecretsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "secrets",
+		Short:        "manage the secrets stored in the local encrypted secrets file",
+		SilenceUsage: true,
+	}
+	cmd.AddCommand(
+		newSecretsSetCmd(),
+		newSecretsListCmd(),
+		newSecretsRemoveCmd(),
+	)
+	return cmd
+}
+
+// newSecretsSetCmd represents the secrets set command
+func newSecretsSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "set <name>",
+		Short:        "store a secret, the value is prompted for or read from stdin",
+		Args:         cobra.ExactArgs(1),
+		RunE:         gApp.RunESecretsSet,
+		SilenceUsage: true,
+	}
+	return cmd
+}
+
+// newSecretsListCmd represents the secrets list command
+func newSecretsListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "list",
+		Aliases:      []string{"ls"},
+		Short:        "list the stored secrets names",
+		RunE:         gApp.RunESecretsList,
+		SilenceUsage: true,
+	}
+	return cmd
+}
+
+// newSecretsRemoveCmd represents the secrets remove command
+func newSecretsRemoveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "remove <name>",
+		Aliases:      []string{"rm"},
+		Short:        "remove a stored secret",
+		Args:         cobra.ExactArgs(1),
+		RunE:         gApp.RunESecretsRemove,
+		SilenceUsage: true,
+	}
+	return cmd
+}
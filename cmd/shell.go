@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// newShellCmd represents the shell command
+func newShellCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "shell",
+		Short:        "run gNOIc commands from an interactive shell",
+		Args:         cobra.NoArgs,
+		RunE:         gApp.RunEShell,
+		SilenceUsage: true,
+	}
+	return cmd
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+// newTargetsCmd represents the targets command
+func newTargetsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "targets",
+		Short:        "manage the targets resolved from the flags, the inventory and the config file",
+		SilenceUsage: true,
+	}
+	cmd.AddCommand(newTargetsListCmd())
+	return cmd
+}
+
+// newTargetsListCmd represents the targets list command
+func newTargetsListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "list",
+		Aliases:      []string{"ls"},
+		Short:        "list the expanded and resolved targets",
+		RunE:         gApp.RunETargetsList,
+		SilenceUsage: true,
+	}
+	return cmd
+}
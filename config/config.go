@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/adrg/xdg"
@@ -25,6 +26,14 @@ type Config struct {
 	FileConfig  *viper.Viper `mapstructure:"-" json:"-" yaml:"-" `
 
 	logger *log.Entry
+	// full name of the command being run
+	command string
+
+	// resolved secrets, indexed by reference
+	secretsMu     sync.Mutex
+	secretsCache  map[string]string
+	secretsStore  *SecretsStore
+	passwordAsked bool
 }
 
 type GlobalFlags struct {
@@ -32,6 +41,8 @@ type GlobalFlags struct {
 	Address       []string      `mapstructure:"address,omitempty" json:"address,omitempty" yaml:"address,omitempty"`
 	Username      string        `mapstructure:"username,omitempty" json:"username,omitempty" yaml:"username,omitempty"`
 	Password      string        `mapstructure:"password,omitempty" json:"password,omitempty" yaml:"password,omitempty"`
+	AskPassword   bool          `mapstructure:"ask-password,omitempty" json:"ask-password,omitempty" yaml:"ask-password,omitempty"`
+	SecretsFile   string        `mapstructure:"secrets-file,omitempty" json:"secrets-file,omitempty" yaml:"secrets-file,omitempty"`
 	Port          string        `mapstructure:"port,omitempty" json:"port,omitempty" yaml:"port,omitempty"`
 	Encoding      string        `mapstructure:"encoding,omitempty" json:"encoding,omitempty" yaml:"encoding,omitempty"`
 	Insecure      bool          `mapstructure:"insecure,omitempty" json:"insecure,omitempty" yaml:"insecure,omitempty"`
@@ -48,6 +59,63 @@ type GlobalFlags struct {
 	PrintProto    bool          `mapstructure:"print-proto,omitempty" json:"print-proto,omitempty" yaml:"print-proto,omitempty"`
 	Gzip          bool          `mapstructure:"gzip,omitempty" json:"gzip,omitempty" yaml:"gzip,omitempty"`
 	Format        string        `mapstructure:"format,omitempty" json:"format,omitempty" yaml:"format,omitempty"`
+	Select        string        `mapstructure:"select,omitempty" json:"select,omitempty" yaml:"select,omitempty"`
+	// Rolling execution
+	MaxConcurrency int    `mapstructure:"max-concurrency,omitempty" json:"max-concurrency,omitempty" yaml:"max-concurrency,omitempty"`
+	BatchSize      int    `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty" yaml:"batch-size,omitempty"`
+	Canary         int    `mapstructure:"canary,omitempty" json:"canary,omitempty" yaml:"canary,omitempty"`
+	MaxFailures    string `mapstructure:"max-failures,omitempty" json:"max-failures,omitempty" yaml:"max-failures,omitempty"`
+	// Inventory
+	Inventory        string   `mapstructure:"inventory,omitempty" json:"inventory,omitempty" yaml:"inventory,omitempty"`
+	InventoryHeaders []string `mapstructure:"inventory-header,omitempty" json:"inventory-header,omitempty" yaml:"inventory-header,omitempty"`
+	// Retry
+	RetryMaxAttempts   int           `mapstructure:"retry-max-attempts,omitempty" json:"retry-max-attempts,omitempty" yaml:"retry-max-attempts,omitempty"`
+	RetryBackoff       time.Duration `mapstructure:"retry-backoff,omitempty" json:"retry-backoff,omitempty" yaml:"retry-backoff,omitempty"`
+	RetryMaxBackoff    time.Duration `mapstructure:"retry-max-backoff,omitempty" json:"retry-max-backoff,omitempty" yaml:"retry-max-backoff,omitempty"`
+	RetryJitter        float64       `mapstructure:"retry-jitter,omitempty" json:"retry-jitter,omitempty" yaml:"retry-jitter,omitempty"`
+	RetryCodes         []string      `mapstructure:"retry-codes,omitempty" json:"retry-codes,omitempty" yaml:"retry-codes,omitempty"`
+	RetryNonIdempotent bool          `mapstructure:"retry-non-idempotent,omitempty" json:"retry-non-idempotent,omitempty" yaml:"retry-non-idempotent,omitempty"`
+	// Connections
+	KeepaliveTime    time.Duration `mapstructure:"keepalive-time,omitempty" json:"keepalive-time,omitempty" yaml:"keepalive-time,omitempty"`
+	KeepaliveTimeout time.Duration `mapstructure:"keepalive-timeout,omitempty" json:"keepalive-timeout,omitempty" yaml:"keepalive-timeout,omitempty"`
+	MaxMsgSize       int           `mapstructure:"max-msg-size,omitempty" json:"max-msg-size,omitempty" yaml:"max-msg-size,omitempty"`
+	ConnIdleTimeout  time.Duration `mapstructure:"conn-idle-timeout,omitempty" json:"conn-idle-timeout,omitempty" yaml:"conn-idle-timeout,omitempty"`
+	// Proxy
+	Proxy   string   `mapstructure:"proxy,omitempty" json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	NoProxy []string `mapstructure:"no-proxy,omitempty" json:"no-proxy,omitempty" yaml:"no-proxy,omitempty"`
+	// SSH jump hosts
+	SSHProxy      []string `mapstructure:"ssh-proxy,omitempty" json:"ssh-proxy,omitempty" yaml:"ssh-proxy,omitempty"`
+	SSHKeyFile    string   `mapstructure:"ssh-key-file,omitempty" json:"ssh-key-file,omitempty" yaml:"ssh-key-file,omitempty"`
+	SSHKnownHosts string   `mapstructure:"ssh-known-hosts,omitempty" json:"ssh-known-hosts,omitempty" yaml:"ssh-known-hosts,omitempty"`
+	// Per RPC credentials
+	Token              string   `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
+	Metadata           []string `mapstructure:"metadata,omitempty" json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	OAuth2TokenURL     string   `mapstructure:"oauth2-token-url,omitempty" json:"oauth2-token-url,omitempty" yaml:"oauth2-token-url,omitempty"`
+	OAuth2ClientID     string   `mapstructure:"oauth2-client-id,omitempty" json:"oauth2-client-id,omitempty" yaml:"oauth2-client-id,omitempty"`
+	OAuth2ClientSecret string   `mapstructure:"oauth2-client-secret,omitempty" json:"oauth2-client-secret,omitempty" yaml:"oauth2-client-secret,omitempty"`
+	OAuth2Scopes       []string `mapstructure:"oauth2-scopes,omitempty" json:"oauth2-scopes,omitempty" yaml:"oauth2-scopes,omitempty"`
+	// Run summary
+	SummaryFile string `mapstructure:"summary-file,omitempty" json:"summary-file,omitempty" yaml:"summary-file,omitempty"`
+	// Watch
+	Watch time.Duration `mapstructure:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+	Until string        `mapstructure:"until,omitempty" json:"until,omitempty" yaml:"until,omitempty"`
+	// Dry run
+	DryRun bool `mapstructure:"dry-run,omitempty" json:"dry-run,omitempty" yaml:"dry-run,omitempty"`
+	// Safety
+	Yes        bool   `mapstructure:"yes,omitempty" json:"yes,omitempty" yaml:"yes,omitempty"`
+	MaxTargets int    `mapstructure:"max-targets,omitempty" json:"max-targets,omitempty" yaml:"max-targets,omitempty"`
+	PolicyFile string `mapstructure:"policy-file,omitempty" json:"policy-file,omitempty" yaml:"policy-file,omitempty"`
+	// Journal
+	JournalFile string `mapstructure:"journal-file,omitempty" json:"journal-file,omitempty" yaml:"journal-file,omitempty"`
+	NoJournal   bool   `mapstructure:"no-journal,omitempty" json:"no-journal,omitempty" yaml:"no-journal,omitempty"`
+	// Logging
+	LogFile   string `mapstructure:"log-file,omitempty" json:"log-file,omitempty" yaml:"log-file,omitempty"`
+	LogFormat string `mapstructure:"log-format,omitempty" json:"log-format,omitempty" yaml:"log-format,omitempty"`
+	LogLevel  string `mapstructure:"log-level,omitempty" json:"log-level,omitempty" yaml:"log-level,omitempty"`
+	// Tracing
+	OTLPEndpoint string `mapstructure:"otlp-endpoint,omitempty" json:"otlp-endpoint,omitempty" yaml:"otlp-endpoint,omitempty"`
+	OTLPInsecure bool   `mapstructure:"otlp-insecure,omitempty" json:"otlp-insecure,omitempty" yaml:"otlp-insecure,omitempty"`
+	TraceFile    string `mapstructure:"trace-file,omitempty" json:"trace-file,omitempty" yaml:"trace-file,omitempty"`
 }
 
 type LocalFlags struct {
@@ -57,8 +125,19 @@ type LocalFlags struct {
 	// VersionUpgrade
 	UpgradeUsePkg bool `mapstructure:"upgrade-use-pkg" json:"upgrade-use-pkg,omitempty" yaml:"upgrade-use-pkg,omitempty"`
 	// Cert
-	CertCACert string `json:"cert-ca-cert,omitempty" mapstructure:"cert-ca-cert,omitempty" yaml:"cert-ca-cert,omitempty"`
-	CertCAKey  string `json:"cert-ca-key,omitempty" mapstructure:"cert-ca-key,omitempty" yaml:"cert-ca-key,omitempty"`
+	CertCACert         string `json:"cert-ca-cert,omitempty" mapstructure:"cert-ca-cert,omitempty" yaml:"cert-ca-cert,omitempty"`
+	CertCAKey          string `json:"cert-ca-key,omitempty" mapstructure:"cert-ca-key,omitempty" yaml:"cert-ca-key,omitempty"`
+	CertSigner         string `json:"cert-signer,omitempty" mapstructure:"cert-signer,omitempty" yaml:"cert-signer,omitempty"`
+	CertSignerTLSCA    string `json:"cert-signer-tls-ca,omitempty" mapstructure:"cert-signer-tls-ca,omitempty" yaml:"cert-signer-tls-ca,omitempty"`
+	CertVaultAddr      string `json:"cert-vault-addr,omitempty" mapstructure:"cert-vault-addr,omitempty" yaml:"cert-vault-addr,omitempty"`
+	CertVaultToken     string `json:"cert-vault-token,omitempty" mapstructure:"cert-vault-token,omitempty" yaml:"cert-vault-token,omitempty"`
+	CertVaultNamespace string `json:"cert-vault-namespace,omitempty" mapstructure:"cert-vault-namespace,omitempty" yaml:"cert-vault-namespace,omitempty"`
+	CertVaultMount     string `json:"cert-vault-mount,omitempty" mapstructure:"cert-vault-mount,omitempty" yaml:"cert-vault-mount,omitempty"`
+	CertVaultRole      string `json:"cert-vault-role,omitempty" mapstructure:"cert-vault-role,omitempty" yaml:"cert-vault-role,omitempty"`
+	CertACMEDirectory  string `json:"cert-acme-directory,omitempty" mapstructure:"cert-acme-directory,omitempty" yaml:"cert-acme-directory,omitempty"`
+	CertACMEAccountKey string `json:"cert-acme-account-key,omitempty" mapstructure:"cert-acme-account-key,omitempty" yaml:"cert-acme-account-key,omitempty"`
+	CertACMEEABKID     string `json:"cert-acme-eab-kid,omitempty" mapstructure:"cert-acme-eab-kid,omitempty" yaml:"cert-acme-eab-kid,omitempty"`
+	CertACMEEABKey     string `json:"cert-acme-eab-key,omitempty" mapstructure:"cert-acme-eab-key,omitempty" yaml:"cert-acme-eab-key,omitempty"`
 	// Cert CreateCA
 	CertCreateCaOrg           string        `json:"cert-create-ca-org,omitempty" mapstructure:"cert-create-ca-org,omitempty" yaml:"cert-create-ca-org,omitempty"`
 	CertCreateCaOrgUnit       string        `json:"cert-create-ca-org-unit,omitempty" mapstructure:"cert-create-ca-org-unit,omitempty" yaml:"cert-create-ca-org-unit,omitempty"`
@@ -74,21 +153,26 @@ type LocalFlags struct {
 	CertCreateCaKeyOut        string        `json:"cert-create-ca-key-out,omitempty" mapstructure:"cert-create-ca-key-out,omitempty" yaml:"cert-create-ca-key-out,omitempty"`
 	CertCreateCaCertOut       string        `json:"cert-create-ca-cert-out,omitempty" mapstructure:"cert-create-ca-cert-out,omitempty" yaml:"cert-create-ca-cert-out,omitempty"`
 	// Cert Rotate
-	CertRotateCertificateID   string        `json:"cert-rotate-certificate-id,omitempty" mapstructure:"cert-rotate-certificate-id,omitempty" yaml:"cert-rotate-certificate-id,omitempty"`
-	CertRotateKeyType         string        `json:"cert-rotate-key-type,omitempty" mapstructure:"cert-rotate-key-type,omitempty" yaml:"cert-rotate-key-type,omitempty"`
-	CertRotateCertificateType string        `json:"cert-rotate-certificate-type,omitempty" mapstructure:"cert-rotate-certificate-type,omitempty" yaml:"cert-rotate-certificate-type,omitempty"`
-	CertRotateMinKeySize      uint32        `json:"cert-rotate-min-key-size,omitempty" mapstructure:"cert-rotate-min-key-size,omitempty" yaml:"cert-rotate-min-key-size,omitempty"`
-	CertRotateCommonName      string        `json:"cert-rotate-common-name,omitempty" mapstructure:"cert-rotate-common-name,omitempty" yaml:"cert-rotate-common-name,omitempty"`
-	CertRotateCountry         string        `json:"cert-rotate-country,omitempty" mapstructure:"cert-rotate-country,omitempty" yaml:"cert-rotate-country,omitempty"`
-	CertRotateState           string        `json:"cert-rotate-state,omitempty" mapstructure:"cert-rotate-state,omitempty" yaml:"cert-rotate-state,omitempty"`
-	CertRotateCity            string        `json:"cert-rotate-city,omitempty" mapstructure:"cert-rotate-city,omitempty" yaml:"cert-rotate-city,omitempty"`
-	CertRotateOrg             string        `json:"cert-rotate-org,omitempty" mapstructure:"cert-rotate-org,omitempty" yaml:"cert-rotate-org,omitempty"`
-	CertRotateOrgUnit         string        `json:"cert-rotate-org-unit,omitempty" mapstructure:"cert-rotate-org-unit,omitempty" yaml:"cert-rotate-org-unit,omitempty"`
-	CertRotateIPAddress       string        `json:"cert-rotate-ip-address,omitempty" mapstructure:"cert-rotate-ip-address,omitempty" yaml:"cert-rotate-ip-address,omitempty"`
-	CertRotateEmailID         string        `json:"cert-rotate-email-id,omitempty" mapstructure:"cert-rotate-email-id,omitempty" yaml:"cert-rotate-email-id,omitempty"`
-	CertRotateValidity        time.Duration `json:"cert-rotate-validity,omitempty" mapstructure:"cert-rotate-validity,omitempty" yaml:"cert-rotate-validity,omitempty"`
-	CertRotatePrintCSR        bool          `json:"cert-rotate-print-csr,omitempty" mapstructure:"cert-rotate-print-csr,omitempty" yaml:"cert-rotate-print-csr,omitempty"`
-	CertRotateGenCSR          bool          `json:"cert-rotate-gen-csr,omitempty" mapstructure:"cert-rotate-gen-csr,omitempty" yaml:"cert-rotate-gen-csr,omitempty"`
+	CertRotateCertificateID     string        `json:"cert-rotate-certificate-id,omitempty" mapstructure:"cert-rotate-certificate-id,omitempty" yaml:"cert-rotate-certificate-id,omitempty"`
+	CertRotateKeyType           string        `json:"cert-rotate-key-type,omitempty" mapstructure:"cert-rotate-key-type,omitempty" yaml:"cert-rotate-key-type,omitempty"`
+	CertRotateCertificateType   string        `json:"cert-rotate-certificate-type,omitempty" mapstructure:"cert-rotate-certificate-type,omitempty" yaml:"cert-rotate-certificate-type,omitempty"`
+	CertRotateMinKeySize        uint32        `json:"cert-rotate-min-key-size,omitempty" mapstructure:"cert-rotate-min-key-size,omitempty" yaml:"cert-rotate-min-key-size,omitempty"`
+	CertRotateCommonName        string        `json:"cert-rotate-common-name,omitempty" mapstructure:"cert-rotate-common-name,omitempty" yaml:"cert-rotate-common-name,omitempty"`
+	CertRotateCountry           string        `json:"cert-rotate-country,omitempty" mapstructure:"cert-rotate-country,omitempty" yaml:"cert-rotate-country,omitempty"`
+	CertRotateState             string        `json:"cert-rotate-state,omitempty" mapstructure:"cert-rotate-state,omitempty" yaml:"cert-rotate-state,omitempty"`
+	CertRotateCity              string        `json:"cert-rotate-city,omitempty" mapstructure:"cert-rotate-city,omitempty" yaml:"cert-rotate-city,omitempty"`
+	CertRotateOrg               string        `json:"cert-rotate-org,omitempty" mapstructure:"cert-rotate-org,omitempty" yaml:"cert-rotate-org,omitempty"`
+	CertRotateOrgUnit           string        `json:"cert-rotate-org-unit,omitempty" mapstructure:"cert-rotate-org-unit,omitempty" yaml:"cert-rotate-org-unit,omitempty"`
+	CertRotateIPAddress         []string      `json:"cert-rotate-ip-address,omitempty" mapstructure:"cert-rotate-ip-address,omitempty" yaml:"cert-rotate-ip-address,omitempty"`
+	CertRotateDNSName           []string      `json:"cert-rotate-dns-name,omitempty" mapstructure:"cert-rotate-dns-name,omitempty" yaml:"cert-rotate-dns-name,omitempty"`
+	CertRotateURI               []string      `json:"cert-rotate-uri,omitempty" mapstructure:"cert-rotate-uri,omitempty" yaml:"cert-rotate-uri,omitempty"`
+	CertRotateExtKeyUsage       []string      `json:"cert-rotate-ext-key-usage,omitempty" mapstructure:"cert-rotate-ext-key-usage,omitempty" yaml:"cert-rotate-ext-key-usage,omitempty"`
+	CertRotateEmailID           string        `json:"cert-rotate-email-id,omitempty" mapstructure:"cert-rotate-email-id,omitempty" yaml:"cert-rotate-email-id,omitempty"`
+	CertRotateValidity          time.Duration `json:"cert-rotate-validity,omitempty" mapstructure:"cert-rotate-validity,omitempty" yaml:"cert-rotate-validity,omitempty"`
+	CertRotatePrintCSR          bool          `json:"cert-rotate-print-csr,omitempty" mapstructure:"cert-rotate-print-csr,omitempty" yaml:"cert-rotate-print-csr,omitempty"`
+	CertRotateGenCSR            bool          `json:"cert-rotate-gen-csr,omitempty" mapstructure:"cert-rotate-gen-csr,omitempty" yaml:"cert-rotate-gen-csr,omitempty"`
+	CertRotateSkipValidation    bool          `json:"cert-rotate-skip-validation,omitempty" mapstructure:"cert-rotate-skip-validation,omitempty" yaml:"cert-rotate-skip-validation,omitempty"`
+	CertRotateValidationTimeout time.Duration `json:"cert-rotate-validation-timeout,omitempty" mapstructure:"cert-rotate-validation-timeout,omitempty" yaml:"cert-rotate-validation-timeout,omitempty"`
 	// Cert Install
 	CertInstallCertificateID   string        `json:"cert-install-certificate-id,omitempty" mapstructure:"cert-install-certificate-id,omitempty" yaml:"cert-install-certificate-id,omitempty"`
 	CertInstallKeyType         string        `json:"cert-install-key-type,omitempty" mapstructure:"cert-install-key-type,omitempty" yaml:"cert-install-key-type,omitempty"`
@@ -100,24 +184,30 @@ type LocalFlags struct {
 	CertInstallCity            string        `json:"cert-install-city,omitempty" mapstructure:"cert-install-city,omitempty" yaml:"cert-install-city,omitempty"`
 	CertInstallOrg             string        `json:"cert-install-org,omitempty" mapstructure:"cert-install-org,omitempty" yaml:"cert-install-org,omitempty"`
 	CertInstallOrgUnit         string        `json:"cert-install-org-unit,omitempty" mapstructure:"cert-install-org-unit,omitempty" yaml:"cert-install-org-unit,omitempty"`
-	CertInstallIPAddress       string        `json:"cert-install-ip-address,omitempty" mapstructure:"cert-install-ip-address,omitempty" yaml:"cert-install-ip-address,omitempty"`
+	CertInstallIPAddress       []string      `json:"cert-install-ip-address,omitempty" mapstructure:"cert-install-ip-address,omitempty" yaml:"cert-install-ip-address,omitempty"`
+	CertInstallDNSName         []string      `json:"cert-install-dns-name,omitempty" mapstructure:"cert-install-dns-name,omitempty" yaml:"cert-install-dns-name,omitempty"`
+	CertInstallURI             []string      `json:"cert-install-uri,omitempty" mapstructure:"cert-install-uri,omitempty" yaml:"cert-install-uri,omitempty"`
+	CertInstallExtKeyUsage     []string      `json:"cert-install-ext-key-usage,omitempty" mapstructure:"cert-install-ext-key-usage,omitempty" yaml:"cert-install-ext-key-usage,omitempty"`
 	CertInstallEmailID         string        `json:"cert-install-email-id,omitempty" mapstructure:"cert-install-email-id,omitempty" yaml:"cert-install-email-id,omitempty"`
 	CertInstallValidity        time.Duration `json:"cert-install-validity,omitempty" mapstructure:"cert-install-validity,omitempty" yaml:"cert-install-validity,omitempty"`
 	CertInstallPrintCSR        bool          `json:"cert-install-print-csr,omitempty" mapstructure:"cert-install-print-csr,omitempty" yaml:"cert-install-print-csr,omitempty"`
 	CertInstallGenCSR          bool          `json:"cert-install-gen-csr,omitempty" mapstructure:"cert-install-gen-csr,omitempty" yaml:"cert-install-gen-csr,omitempty"`
 	// Cert GenerateCSR
-	CertGenerateCSRCertificateID   string `json:"cert-generate-csr-certificate-id,omitempty" mapstructure:"cert-generate-csr-certificate-id,omitempty" yaml:"cert-generate-csr-certificate-id,omitempty"`
-	CertGenerateCSRKeyType         string `json:"cert-generate-csr-key-type,omitempty" mapstructure:"cert-generate-csr-key-type,omitempty" yaml:"cert-generate-csr-key-type,omitempty"`
-	CertGenerateCSRCertificateType string `json:"cert-generate-csr-certificate-type,omitempty" mapstructure:"cert-generate-csr-certificate-type,omitempty" yaml:"cert-generate-csr-certificate-type,omitempty"`
-	CertGenerateCSRMinKeySize      uint32 `json:"cert-generate-csr-min-key-size,omitempty" mapstructure:"cert-generate-csr-min-key-size,omitempty" yaml:"cert-generate-csr-min-key-size,omitempty"`
-	CertGenerateCSRCommonName      string `json:"cert-generate-csr-common-name,omitempty" mapstructure:"cert-generate-csr-common-name,omitempty" yaml:"cert-generate-csr-common-name,omitempty"`
-	CertGenerateCSRCountry         string `json:"cert-generate-csr-country,omitempty" mapstructure:"cert-generate-csr-country,omitempty" yaml:"cert-generate-csr-country,omitempty"`
-	CertGenerateCSRState           string `json:"cert-generate-csr-state,omitempty" mapstructure:"cert-generate-csr-state,omitempty" yaml:"cert-generate-csr-state,omitempty"`
-	CertGenerateCSRCity            string `json:"cert-generate-csr-city,omitempty" mapstructure:"cert-generate-csr-city,omitempty" yaml:"cert-generate-csr-city,omitempty"`
-	CertGenerateCSROrg             string `json:"cert-generate-csr-org,omitempty" mapstructure:"cert-generate-csr-org,omitempty" yaml:"cert-generate-csr-org,omitempty"`
-	CertGenerateCSROrgUnit         string `json:"cert-generate-csr-org-unit,omitempty" mapstructure:"cert-generate-csr-org-unit,omitempty" yaml:"cert-generate-csr-org-unit,omitempty"`
-	CertGenerateCSRIPAddress       string `json:"cert-generate-csr-ip-address,omitempty" mapstructure:"cert-generate-csr-ip-address,omitempty" yaml:"cert-generate-cs-rip-address,omitempty"`
-	CertGenerateCSREmailID         string `json:"cert-generate-csr-email-id,omitempty" mapstructure:"cert-generate-csr-email-id,omitempty" yaml:"cert-generate-csr-email-id,omitempty"`
+	CertGenerateCSRCertificateID   string   `json:"cert-generate-csr-certificate-id,omitempty" mapstructure:"cert-generate-csr-certificate-id,omitempty" yaml:"cert-generate-csr-certificate-id,omitempty"`
+	CertGenerateCSRKeyType         string   `json:"cert-generate-csr-key-type,omitempty" mapstructure:"cert-generate-csr-key-type,omitempty" yaml:"cert-generate-csr-key-type,omitempty"`
+	CertGenerateCSRCertificateType string   `json:"cert-generate-csr-certificate-type,omitempty" mapstructure:"cert-generate-csr-certificate-type,omitempty" yaml:"cert-generate-csr-certificate-type,omitempty"`
+	CertGenerateCSRMinKeySize      uint32   `json:"cert-generate-csr-min-key-size,omitempty" mapstructure:"cert-generate-csr-min-key-size,omitempty" yaml:"cert-generate-csr-min-key-size,omitempty"`
+	CertGenerateCSRCommonName      string   `json:"cert-generate-csr-common-name,omitempty" mapstructure:"cert-generate-csr-common-name,omitempty" yaml:"cert-generate-csr-common-name,omitempty"`
+	CertGenerateCSRCountry         string   `json:"cert-generate-csr-country,omitempty" mapstructure:"cert-generate-csr-country,omitempty" yaml:"cert-generate-csr-country,omitempty"`
+	CertGenerateCSRState           string   `json:"cert-generate-csr-state,omitempty" mapstructure:"cert-generate-csr-state,omitempty" yaml:"cert-generate-csr-state,omitempty"`
+	CertGenerateCSRCity            string   `json:"cert-generate-csr-city,omitempty" mapstructure:"cert-generate-csr-city,omitempty" yaml:"cert-generate-csr-city,omitempty"`
+	CertGenerateCSROrg             string   `json:"cert-generate-csr-org,omitempty" mapstructure:"cert-generate-csr-org,omitempty" yaml:"cert-generate-csr-org,omitempty"`
+	CertGenerateCSROrgUnit         string   `json:"cert-generate-csr-org-unit,omitempty" mapstructure:"cert-generate-csr-org-unit,omitempty" yaml:"cert-generate-csr-org-unit,omitempty"`
+	CertGenerateCSRIPAddress       []string `json:"cert-generate-csr-ip-address,omitempty" mapstructure:"cert-generate-csr-ip-address,omitempty" yaml:"cert-generate-csr-ip-address,omitempty"`
+	CertGenerateCSRDNSName         []string `json:"cert-generate-csr-dns-name,omitempty" mapstructure:"cert-generate-csr-dns-name,omitempty" yaml:"cert-generate-csr-dns-name,omitempty"`
+	CertGenerateCSRURI             []string `json:"cert-generate-csr-uri,omitempty" mapstructure:"cert-generate-csr-uri,omitempty" yaml:"cert-generate-csr-uri,omitempty"`
+	CertGenerateCSRExtKeyUsage     []string `json:"cert-generate-csr-ext-key-usage,omitempty" mapstructure:"cert-generate-csr-ext-key-usage,omitempty" yaml:"cert-generate-csr-ext-key-usage,omitempty"`
+	CertGenerateCSREmailID         string   `json:"cert-generate-csr-email-id,omitempty" mapstructure:"cert-generate-csr-email-id,omitempty" yaml:"cert-generate-csr-email-id,omitempty"`
 	// Cert CanGenerateCSR
 	CertCanGenerateCSRKeyType         string `json:"cert-can-generate-csr-key-type,omitempty" mapstructure:"cert-can-generate-csr-key-type,omitempty" yaml:"cert-can-generate-csr-key-type,omitempty"`
 	CertCanGenerateCSRCertificateType string `json:"cert-can-generate-csr-certificate-type,omitempty" mapstructure:"cert-can-generate-csr-certificate-type,omitempty" yaml:"cert-can-generate-csr-certificate-type,omitempty"`
@@ -138,6 +228,21 @@ type LocalFlags struct {
 	CertGetCertificatesDetails bool     `json:"cert-get-certificates-details,omitempty" mapstructure:"cert-get-certificates-details,omitempty" yaml:"cert-get-certificates-details,omitempty"`
 	CertGetCertificatesID      []string `json:"cert-get-certificates-id,omitempty" mapstructure:"cert-get-certificates-id,omitempty" yaml:"cert-get-certificates-id,omitempty"`
 	CertGetCertificatesSave    bool     `json:"cert-get-certificates-save,omitempty" mapstructure:"cert-get-certificates-save,omitempty" yaml:"cert-get-certificates-save,omitempty"`
+	// Cert Audit
+	CertAuditWarn       string   `json:"cert-audit-warn,omitempty" mapstructure:"cert-audit-warn,omitempty" yaml:"cert-audit-warn,omitempty"`
+	CertAuditCrit       string   `json:"cert-audit-crit,omitempty" mapstructure:"cert-audit-crit,omitempty" yaml:"cert-audit-crit,omitempty"`
+	CertAuditID         []string `json:"cert-audit-id,omitempty" mapstructure:"cert-audit-id,omitempty" yaml:"cert-audit-id,omitempty"`
+	CertAuditMinKeySize int      `json:"cert-audit-min-key-size,omitempty" mapstructure:"cert-audit-min-key-size,omitempty" yaml:"cert-audit-min-key-size,omitempty"`
+	// Cert Renew
+	CertRenewID                []string      `json:"cert-renew-id,omitempty" mapstructure:"cert-renew-id,omitempty" yaml:"cert-renew-id,omitempty"`
+	CertRenewWindow            string        `json:"cert-renew-window,omitempty" mapstructure:"cert-renew-window,omitempty" yaml:"cert-renew-window,omitempty"`
+	CertRenewMinKeySize        int           `json:"cert-renew-min-key-size,omitempty" mapstructure:"cert-renew-min-key-size,omitempty" yaml:"cert-renew-min-key-size,omitempty"`
+	CertRenewKeyType           string        `json:"cert-renew-key-type,omitempty" mapstructure:"cert-renew-key-type,omitempty" yaml:"cert-renew-key-type,omitempty"`
+	CertRenewValidity          time.Duration `json:"cert-renew-validity,omitempty" mapstructure:"cert-renew-validity,omitempty" yaml:"cert-renew-validity,omitempty"`
+	CertRenewGenCSR            bool          `json:"cert-renew-gen-csr,omitempty" mapstructure:"cert-renew-gen-csr,omitempty" yaml:"cert-renew-gen-csr,omitempty"`
+	CertRenewPrintCSR          bool          `json:"cert-renew-print-csr,omitempty" mapstructure:"cert-renew-print-csr,omitempty" yaml:"cert-renew-print-csr,omitempty"`
+	CertRenewSkipValidation    bool          `json:"cert-renew-skip-validation,omitempty" mapstructure:"cert-renew-skip-validation,omitempty" yaml:"cert-renew-skip-validation,omitempty"`
+	CertRenewValidationTimeout time.Duration `json:"cert-renew-validation-timeout,omitempty" mapstructure:"cert-renew-validation-timeout,omitempty" yaml:"cert-renew-validation-timeout,omitempty"`
 	// File
 	// File Get
 	FileGetFile         []string `json:"file-get-file,omitempty" mapstructure:"file-get-file,omitempty" yaml:"file-get-file,omitempty"`
@@ -241,14 +346,26 @@ type LocalFlags struct {
 	// FactoryReset
 	FactoryResetStartFactoryOS bool `json:"factory-reset-start-factory-os,omitempty" mapstructure:"factory-reset-start-factory-os,omitempty" yaml:"factory-reset-start-factory-os,omitempty"`
 	FactoryResetStartZeroFill  bool `json:"factory-reset-start-zero-fill,omitempty" mapstructure:"factory-reset-start-zero-fill,omitempty" yaml:"factory-reset-start-zero-fill,omitempty"`
+	// Exporter
+	ExporterListenAddress   string        `json:"exporter-listen-address,omitempty" mapstructure:"exporter-listen-address,omitempty" yaml:"exporter-listen-address,omitempty"`
+	ExporterMetricsPath     string        `json:"exporter-metrics-path,omitempty" mapstructure:"exporter-metrics-path,omitempty" yaml:"exporter-metrics-path,omitempty"`
+	ExporterInterval        time.Duration `json:"exporter-interval,omitempty" mapstructure:"exporter-interval,omitempty" yaml:"exporter-interval,omitempty"`
+	ExporterProbes          []string      `json:"exporter-probes,omitempty" mapstructure:"exporter-probes,omitempty" yaml:"exporter-probes,omitempty"`
+	ExporterPingDestination []string      `json:"exporter-ping-destination,omitempty" mapstructure:"exporter-ping-destination,omitempty" yaml:"exporter-ping-destination,omitempty"`
+	ExporterPingCount       int32         `json:"exporter-ping-count,omitempty" mapstructure:"exporter-ping-count,omitempty" yaml:"exporter-ping-count,omitempty"`
+	// History
+	HistoryTarget  string `json:"history-target,omitempty" mapstructure:"history-target,omitempty" yaml:"history-target,omitempty"`
+	HistoryCommand string `json:"history-command,omitempty" mapstructure:"history-command,omitempty" yaml:"history-command,omitempty"`
+	HistoryFrom    string `json:"history-from,omitempty" mapstructure:"history-from,omitempty" yaml:"history-from,omitempty"`
+	HistoryTo      string `json:"history-to,omitempty" mapstructure:"history-to,omitempty" yaml:"history-to,omitempty"`
+	HistoryLast    int    `json:"history-last,omitempty" mapstructure:"history-last,omitempty" yaml:"history-last,omitempty"`
 }
 
 func New() *Config {
 	return &Config{
-		GlobalFlags{},
-		LocalFlags{},
-		viper.NewWithOptions(viper.KeyDelimiter("/")),
-		nil,
+		GlobalFlags: GlobalFlags{},
+		LocalFlags:  LocalFlags{},
+		FileConfig:  viper.NewWithOptions(viper.KeyDelimiter("/")),
 	}
 }
 
@@ -284,12 +401,8 @@ func (c *Config) Load() error {
 	return nil
 }
 
-func (c *Config) SetLogger() {
-	logger := log.StandardLogger()
-	if c.Debug {
-		logger.SetLevel(log.DebugLevel)
-	}
-	c.logger = log.NewEntry(logger)
+func (c *Config) SetLogger(l *log.Entry) {
+	c.logger = l
 }
 
 func (c *Config) LogOutput() io.Writer {
@@ -340,7 +453,16 @@ func flagFullName(cmd *cobra.Command, fName string) string {
 	if cmd.Name() == "gnoic" {
 		return fName
 	}
-	ls := []string{cmd.Name(), fName}
+	return strings.Join([]string{commandFullName(cmd), fName}, "-")
+}
+
+// commandFullName returns the command name prefixed with its parents names,
+// excluding the root command, e.g: "system-reboot"
+func commandFullName(cmd *cobra.Command) string {
+	if cmd.Name() == "gnoic" {
+		return ""
+	}
+	ls := []string{cmd.Name()}
 	for cmd.Parent() != nil && cmd.Parent().Name() != "gnoic" {
 		ls = append([]string{cmd.Parent().Name()}, ls...)
 		cmd = cmd.Parent()
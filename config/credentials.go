@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OAuth2Config holds the OAuth2 client credentials used to fetch
+// the bearer token sent to a target.
+type OAuth2Config struct {
+	TokenURL     string   `json:"token-url,omitempty" mapstructure:"token-url,omitempty"`
+	ClientID     string   `json:"client-id,omitempty" mapstructure:"client-id,omitempty"`
+	ClientSecret string   `json:"client-secret,omitempty" mapstructure:"client-secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty" mapstructure:"scopes,omitempty"`
+}
+
+// validateMetadata checks the --metadata flag values format.
+func validateMetadata(md []string) error {
+	for _, kv := range md {
+		k, _, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return fmt.Errorf("malformed metadata %q, expected key=value", kv)
+		}
+	}
+	return nil
+}
+
+// validateCredentials checks that a target does not use more than one bearer token source.
+func (tc *TargetConfig) validateCredentials() error {
+	if tc.Token != nil && *tc.Token != "" && tc.OAuth2 != nil {
+		return fmt.Errorf("target %q: token and oauth2 are mutually exclusive", tc.Name)
+	}
+	if tc.OAuth2 != nil && tc.OAuth2.TokenURL == "" {
+		return fmt.Errorf("target %q: missing oauth2 token-url", tc.Name)
+	}
+	return nil
+}
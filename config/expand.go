@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxExpandedAddresses limits the number of addresses a single pattern expands to.
+const maxExpandedAddresses = 65536
+
+var numRangeRegex = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// ExpandAddress expands an address pattern into the list of addresses it represents.
+// Supported patterns are:
+//   - numeric ranges and lists within brackets: leaf[01-48].dc1, leaf[1,3,5-7]
+//   - alternatives within braces: spine{1,2}.dc1
+//   - IPv4 and IPv6 prefixes: 10.1.0.0/28, 10.1.0.0/28:57400
+//
+// An address without pattern is returned as is.
+func ExpandAddress(addr string) ([]string, error) {
+	if host, port, ok := splitCIDR(addr); ok {
+		return expandCIDR(host, port)
+	}
+	result, err := expandPattern(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand address %q: %v", addr, err)
+	}
+	return result, nil
+}
+
+// splitCIDR checks if addr is a prefix, optionally followed by a port.
+func splitCIDR(addr string) (string, string, bool) {
+	idx := strings.LastIndex(addr, "/")
+	if idx < 0 {
+		return "", "", false
+	}
+	prefix, mask := addr[:idx], addr[idx+1:]
+	var port string
+	if i := strings.Index(mask, ":"); i >= 0 {
+		mask, port = mask[:i], mask[i+1:]
+	}
+	if _, err := strconv.Atoi(mask); err != nil {
+		return "", "", false
+	}
+	if net.ParseIP(prefix) == nil {
+		return "", "", false
+	}
+	return prefix + "/" + mask, port, true
+}
+
+func expandCIDR(cidr, port string) ([]string, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, err
+	}
+	p = p.Masked()
+	hostBits := p.Addr().BitLen() - p.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("prefix %q is too large, maximum is %d addresses", cidr, maxExpandedAddresses)
+	}
+	addrs := make([]string, 0, 1<<hostBits)
+	first := p.Addr()
+	for a := first; p.Contains(a); a = a.Next() {
+		// skip the network and broadcast addresses of IPv4 prefixes
+		if a.Is4() && hostBits > 1 && (a == first || !p.Contains(a.Next())) {
+			continue
+		}
+		if port != "" {
+			addrs = append(addrs, net.JoinHostPort(a.String(), port))
+		} else {
+			addrs = append(addrs, a.String())
+		}
+		if !a.Next().IsValid() {
+			break
+		}
+	}
+	return addrs, nil
+}
+
+// expandPattern expands the first bracket or brace group found in s,
+// then recursively expands the results.
+func expandPattern(s string) ([]string, error) {
+	start, end, alternatives, err := firstGroup(s)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return []string{s}, nil
+	}
+	result := make([]string, 0, len(alternatives))
+	for _, alt := range alternatives {
+		expanded, err := expandPattern(s[:start] + alt + s[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+		if len(result) > maxExpandedAddresses {
+			return nil, fmt.Errorf("pattern expands to more than %d addresses", maxExpandedAddresses)
+		}
+	}
+	return result, nil
+}
+
+// firstGroup finds the first expandable group in s and returns
+// its start and end indexes along with its alternatives.
+// Brackets that do not hold a numeric range, such as IPv6 addresses, are left as is.
+func firstGroup(s string) (int, int, []string, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			j := strings.IndexByte(s[i:], ']')
+			if j < 0 {
+				return -1, -1, nil, fmt.Errorf("unbalanced bracket in %q", s)
+			}
+			content := s[i+1 : i+j]
+			if !numRangeRegex.MatchString(content) {
+				i += j
+				continue
+			}
+			alts, err := expandRanges(content)
+			if err != nil {
+				return -1, -1, nil, err
+			}
+			return i, i + j, alts, nil
+		case '{':
+			j := strings.IndexByte(s[i:], '}')
+			if j < 0 {
+				return -1, -1, nil, fmt.Errorf("unbalanced brace in %q", s)
+			}
+			return i, i + j, strings.Split(s[i+1:i+j], ","), nil
+		}
+	}
+	return -1, -1, nil, nil
+}
+
+// expandRanges expands a list of numeric ranges such as "01-03,7".
+// Leading zeros in a range start set the width of the generated numbers.
+func expandRanges(s string) ([]string, error) {
+	result := make([]string, 0)
+	for _, r := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(r, "-")
+		if !isRange {
+			result = append(result, from)
+			continue
+		}
+		lo, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("invalid range %q", r)
+		}
+		if hi-lo >= maxExpandedAddresses {
+			return nil, fmt.Errorf("range %q is too large", r)
+		}
+		width := 0
+		if len(from) > 1 && from[0] == '0' {
+			width = len(from)
+		}
+		for n := lo; n <= hi; n++ {
+			result = append(result, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+	return result, nil
+}
+
+// joinAddressPatterns rejoins the address patterns that were split
+// on their commas by the --address flag parsing, e.g: "spine{1" and "2}"
+func joinAddressPatterns(addrs []string) []string {
+	result := make([]string, 0, len(addrs))
+	current := ""
+	depth := 0
+	for _, a := range addrs {
+		if depth > 0 {
+			current += "," + a
+		} else {
+			current = a
+		}
+		depth += strings.Count(a, "{") + strings.Count(a, "[") -
+			strings.Count(a, "}") - strings.Count(a, "]")
+		if depth <= 0 {
+			result = append(result, current)
+			depth = 0
+		}
+	}
+	if depth > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// expandTargetConfig expands the address pattern addr into individual
+// target configs that inherit the settings of tc.
+func (c *Config) expandTargetConfig(tc *TargetConfig, addr string, retryPolicy *RetryPolicy) ([]*TargetConfig, error) {
+	addrs, err := ExpandAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	tcs := make([]*TargetConfig, 0, len(addrs))
+	for _, expAddr := range addrs {
+		ntc := tc
+		if len(addrs) > 1 {
+			ntc = tc.copy()
+			// the configured name only applies to a single address
+			ntc.Name = ""
+		}
+		err = c.parseAddress(ntc, expAddr)
+		if err != nil {
+			return nil, fmt.Errorf("%q failed to parse address: %v", expAddr, err)
+		}
+		c.setTargetConfigDefaults(ntc, retryPolicy)
+		c.logger.Debugf("%q target-config: %s", expAddr, ntc)
+		// resolved after logging the config to keep the secrets out of the logs
+		err = c.resolveTargetSecrets(ntc)
+		if err != nil {
+			return nil, err
+		}
+		err = ntc.validateCredentials()
+		if err != nil {
+			return nil, err
+		}
+		err = ntc.validateProxy()
+		if err != nil {
+			return nil, err
+		}
+		tcs = append(tcs, ntc)
+	}
+	return tcs, nil
+}
+
+func (tc *TargetConfig) copy() *TargetConfig {
+	ntc := *tc
+	if tc.Retry != nil {
+		rp := *tc.Retry
+		ntc.Retry = &rp
+	}
+	if tc.OAuth2 != nil {
+		oc := *tc.OAuth2
+		ntc.OAuth2 = &oc
+	}
+	ntc.Tags = copyStringMap(tc.Tags)
+	ntc.Metadata = copyStringMap(tc.Metadata)
+	return &ntc
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	nm := make(map[string]string, len(m))
+	for k, v := range m {
+		nm[k] = v
+	}
+	return nm
+}
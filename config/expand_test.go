@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "plain",
+			addr: "router1:57400",
+			want: []string{"router1:57400"},
+		},
+		{
+			name: "range_zero_padded",
+			addr: "leaf[08-10].dc1",
+			want: []string{"leaf08.dc1", "leaf09.dc1", "leaf10.dc1"},
+		},
+		{
+			name: "range_list",
+			addr: "leaf[1,3-4]",
+			want: []string{"leaf1", "leaf3", "leaf4"},
+		},
+		{
+			name: "braces",
+			addr: "spine{1,2}.dc{1,2}",
+			want: []string{"spine1.dc1", "spine1.dc2", "spine2.dc1", "spine2.dc2"},
+		},
+		{
+			name: "cidr",
+			addr: "10.1.0.0/30",
+			want: []string{"10.1.0.1", "10.1.0.2"},
+		},
+		{
+			name: "cidr_port",
+			addr: "10.1.0.0/31:6030",
+			want: []string{"10.1.0.0:6030", "10.1.0.1:6030"},
+		},
+		{
+			name: "ipv6_address",
+			addr: "[2001:db8::1]:57400",
+			want: []string{"[2001:db8::1]:57400"},
+		},
+		{
+			name:    "invalid_range",
+			addr:    "leaf[10-1]",
+			wantErr: true,
+		},
+		{
+			name:    "too_large",
+			addr:    "10.0.0.0/8",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) && !tt.wantErr {
+				t.Errorf("ExpandAddress() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinAddressPatterns(t *testing.T) {
+	got := joinAddressPatterns([]string{"spine{1", "2}.dc1", "leaf[1", "3-4]", "10.0.0.1"})
+	want := []string{"spine{1,2}.dc1", "leaf[1,3-4]", "10.0.0.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("joinAddressPatterns() = %v, want %v", got, want)
+	}
+}
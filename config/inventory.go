@@ -0,0 +1,308 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultInventoryTimeout = 30 * time.Second
+
+// InventoryLoader loads target configurations from an external source of truth.
+// Each returned entry is decoded into a TargetConfig, the entry keys
+// are the TargetConfig field names, e.g: "address", "username", "skip-verify".
+type InventoryLoader interface {
+	Load(ctx context.Context) ([]map[string]interface{}, error)
+}
+
+// NewInventoryLoader creates an InventoryLoader from an inventory specification:
+//   - file:/path/to/inventory.{csv,yaml,yml,json}, or a path without prefix
+//   - http://host/path or https://host/path, returning a JSON targets list
+//   - srv:_service._proto.domain, DNS SRV records
+//   - exec:command args, a command printing a JSON or YAML targets list on its stdout
+func NewInventoryLoader(spec string, headers []string) (InventoryLoader, error) {
+	switch {
+	case spec == "":
+		return nil, errors.New("empty inventory")
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		hdrs := make(http.Header)
+		for _, h := range headers {
+			k, v, ok := strings.Cut(h, ":")
+			if !ok {
+				return nil, fmt.Errorf("malformed inventory header %q, expected 'Key: Value'", h)
+			}
+			hdrs.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+		return &httpInventory{url: spec, headers: hdrs}, nil
+	case strings.HasPrefix(spec, "srv:"):
+		return &srvInventory{name: strings.TrimPrefix(spec, "srv:")}, nil
+	case strings.HasPrefix(spec, "exec:"):
+		args := strings.Fields(strings.TrimPrefix(spec, "exec:"))
+		if len(args) == 0 {
+			return nil, errors.New("missing inventory exec command")
+		}
+		return &execInventory{cmd: args[0], args: args[1:]}, nil
+	default:
+		return &fileInventory{path: strings.TrimPrefix(spec, "file:")}, nil
+	}
+}
+
+// fileInventory reads targets from a CSV, YAML or JSON file.
+type fileInventory struct {
+	path string
+}
+
+func (fi *fileInventory) Load(ctx context.Context) ([]map[string]interface{}, error) {
+	b, err := os.ReadFile(fi.path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(fi.path)) {
+	case ".csv":
+		return parseCSVInventory(b)
+	case ".yaml", ".yml":
+		return parseYAMLInventory(b)
+	case ".json":
+		return parseJSONInventory(b)
+	default:
+		return nil, fmt.Errorf("unsupported inventory file format %q", filepath.Ext(fi.path))
+	}
+}
+
+// httpInventory fetches a JSON targets list from an HTTP endpoint.
+type httpInventory struct {
+	url     string
+	headers http.Header
+}
+
+func (hi *httpInventory) Load(ctx context.Context) ([]map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hi.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = hi.headers.Clone()
+	req.Header.Set("Accept", "application/json")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("inventory %q returned status %q", hi.url, rsp.Status)
+	}
+	return parseJSONInventory(b)
+}
+
+// lookupSRV resolves the SRV records of the srv inventory, replaced in tests.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// srvInventory builds targets from DNS SRV records.
+type srvInventory struct {
+	name string
+}
+
+func (si *srvInventory) Load(ctx context.Context) ([]map[string]interface{}, error) {
+	_, srvs, err := lookupSRV(ctx, "", "", si.name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]map[string]interface{}, 0, len(srvs))
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		entries = append(entries, map[string]interface{}{
+			"name":    host,
+			"address": net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+		})
+	}
+	return entries, nil
+}
+
+// execInventory runs a command and reads a JSON or YAML targets list from its stdout.
+type execInventory struct {
+	cmd  string
+	args []string
+}
+
+func (ei *execInventory) Load(ctx context.Context) ([]map[string]interface{}, error) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, ei.cmd, ei.args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("inventory command %q failed: %v: %s", ei.cmd, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseYAMLInventory(stdout.Bytes())
+}
+
+func parseJSONInventory(b []byte) ([]map[string]interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	return inventoryEntries(v)
+}
+
+// parseYAMLInventory parses a YAML inventory, JSON being valid YAML it parses JSON as well.
+func parseYAMLInventory(b []byte) ([]map[string]interface{}, error) {
+	var v interface{}
+	err := yaml.Unmarshal(b, &v)
+	if err != nil {
+		return nil, err
+	}
+	return inventoryEntries(v)
+}
+
+// parseCSVInventory parses a CSV inventory, the first line is a header
+// holding the TargetConfig field names.
+func parseCSVInventory(b []byte) ([]map[string]interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	entries := make([]map[string]interface{}, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		e := make(map[string]interface{}, len(header))
+		for i, col := range header {
+			if i >= len(rec) || rec[i] == "" {
+				continue
+			}
+			e[strings.TrimSpace(col)] = rec[i]
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// inventoryEntries normalizes a decoded inventory into a list of entries.
+// It accepts a list of targets, a map of targets keyed by address
+// or an object with the list under "targets" or "results" (NetBox style).
+func inventoryEntries(v interface{}) ([]map[string]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		entries := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			e, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected inventory entry format, got a %T", item)
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	case map[string]interface{}:
+		for _, k := range []string{"targets", "results"} {
+			if l, ok := v[k].([]interface{}); ok {
+				return inventoryEntries(l)
+			}
+		}
+		entries := make([]map[string]interface{}, 0, len(v))
+		for addr, item := range v {
+			e := make(map[string]interface{})
+			switch item := item.(type) {
+			case map[string]interface{}:
+				for k, iv := range item {
+					e[k] = iv
+				}
+			case nil:
+			default:
+				return nil, fmt.Errorf("unexpected inventory entry format, got a %T", item)
+			}
+			if _, ok := e["address"]; !ok {
+				e["address"] = addr
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected inventory format, got a %T", v)
+	}
+}
+
+// getInventoryTargets loads the targets from the configured inventory.
+func (c *Config) getInventoryTargets(retryPolicy *RetryPolicy) (map[string]*TargetConfig, error) {
+	loader, err := NewInventoryLoader(c.Inventory, c.InventoryHeaders)
+	if err != nil {
+		return nil, err
+	}
+	timeout := c.Timeout
+	if timeout < defaultInventoryTimeout {
+		timeout = defaultInventoryTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	entries, err := loader.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading inventory %q: %v", c.Inventory, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no targets found in inventory %q", c.Inventory)
+	}
+	targetsConfigs := make(map[string]*TargetConfig, len(entries))
+	for _, e := range entries {
+		tc := new(TargetConfig)
+		err = decodeTargetConfig(e, tc)
+		if err != nil {
+			return nil, err
+		}
+		if tc.Address == "" {
+			return nil, fmt.Errorf("inventory entry %v has no address", e)
+		}
+		tcs, err := c.expandTargetConfig(tc, tc.Address, retryPolicy)
+		if err != nil {
+			return nil, err
+		}
+		for _, tc := range tcs {
+			targetsConfigs[tc.Name] = tc
+		}
+	}
+	return targetsConfigs, nil
+}
+
+func decodeTargetConfig(in map[string]interface{}, tc *TargetConfig) error {
+	decoder, err := mapstructure.NewDecoder(
+		&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+				stringToTagsHookFunc(),
+				boolTagsHookFunc(),
+			),
+			WeaklyTypedInput: true,
+			Result:           tc,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(in)
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPInventory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"count": 2, "results": [
+			{"name": "leaf1", "address": "10.0.0.1:57400", "username": "admin"},
+			{"name": "leaf2", "address": "10.0.0.2:57400", "skip-verify": true}
+		]}`))
+	}))
+	defer srv.Close()
+
+	loader, err := NewInventoryLoader(srv.URL, []string{"Authorization: Token secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	tc := new(TargetConfig)
+	err = decodeTargetConfig(entries[1], tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.Name != "leaf2" || tc.SkipVerify == nil || !*tc.SkipVerify {
+		t.Errorf("unexpected target config: %s", tc)
+	}
+}
+
+func TestCSVInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.csv")
+	err := os.WriteFile(path, []byte("name,address,insecure,timeout\n"+
+		"# comment\n"+
+		"spine1,10.0.1.1:57400,true,30s\n"+
+		"spine2,10.0.1.2:57400,,\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader, err := NewInventoryLoader("file:"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	tc := new(TargetConfig)
+	err = decodeTargetConfig(entries[0], tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.Insecure == nil || !*tc.Insecure || tc.Timeout != 30*time.Second {
+		t.Errorf("unexpected target config: %s", tc)
+	}
+	if _, ok := entries[1]["insecure"]; ok {
+		t.Errorf("empty CSV cells should not be set: %v", entries[1])
+	}
+}
+
+func TestSRVInventory(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) {
+		lookupSRV = f
+	}(lookupSRV)
+
+	tests := []struct {
+		name    string
+		srvs    []*net.SRV
+		err     error
+		want    []map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "records",
+			srvs: []*net.SRV{
+				{Target: "leaf1.dc1.example.net.", Port: 57400},
+				{Target: "leaf2.dc1.example.net.", Port: 9339},
+			},
+			want: []map[string]interface{}{
+				{"name": "leaf1.dc1.example.net", "address": "leaf1.dc1.example.net:57400"},
+				{"name": "leaf2.dc1.example.net", "address": "leaf2.dc1.example.net:9339"},
+			},
+		},
+		{
+			name:    "lookup_error",
+			err:     errors.New("no such host"),
+			wantErr: "no such host",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				if name != "_gnoi._tcp.dc1.example.net" {
+					t.Errorf("unexpected SRV lookup name %q", name)
+				}
+				return "", tt.srvs, tt.err
+			}
+			loader, err := NewInventoryLoader("srv:_gnoi._tcp.dc1.example.net", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := loader.Load(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("expected %d entries, got %d", len(tt.want), len(entries))
+			}
+			for i, e := range entries {
+				if e["name"] != tt.want[i]["name"] || e["address"] != tt.want[i]["address"] {
+					t.Errorf("entry %d = %v, want %v", i, e, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExecInventory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	tests := []struct {
+		name     string
+		script   string
+		wantName string
+		wantErr  string
+	}{
+		{
+			name:     "json",
+			script:   `echo '[{"name": "leaf1", "address": "10.0.0.1:57400", "insecure": true}]'`,
+			wantName: "leaf1",
+		},
+		{
+			name: "yaml",
+			script: `cat <<EOF
+targets:
+  - name: leaf1
+    address: 10.0.0.1:57400
+    insecure: true
+EOF`,
+			wantName: "leaf1",
+		},
+		{
+			name:    "non_zero_exit",
+			script:  "echo 'inventory unreachable' >&2; exit 3",
+			wantErr: "inventory unreachable",
+		},
+		{
+			name:    "malformed_output",
+			script:  `echo '[{"name": "leaf1",'`,
+			wantErr: "yaml",
+		},
+		{
+			name:    "unexpected_format",
+			script:  "echo leaf1",
+			wantErr: "unexpected inventory format",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "inventory.sh")
+			err := os.WriteFile(path, []byte("#!/bin/sh\n"+tt.script+"\n"), 0700)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loader, err := NewInventoryLoader("exec:"+path+" --site par1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := loader.Load(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 entry, got %d", len(entries))
+			}
+			tc := new(TargetConfig)
+			err = decodeTargetConfig(entries[0], tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.Name != tt.wantName || tc.Address != "10.0.0.1:57400" || tc.Insecure == nil || !*tc.Insecure {
+				t.Errorf("unexpected target config: %s", tc)
+			}
+		})
+	}
+}
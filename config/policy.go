@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy restricts where and when destructive commands can run.
+// It is loaded from the file set with --policy-file.
+type Policy struct {
+	// MaxTargets is the maximum number of targets a destructive command
+	// can run against, used if --max-targets is not set.
+	MaxTargets int           `yaml:"max-targets,omitempty"`
+	Rules      []*PolicyRule `yaml:"rules,omitempty"`
+}
+
+// PolicyRule applies to the commands matching Commands,
+// run against the targets matching Selector.
+type PolicyRule struct {
+	Name string `yaml:"name,omitempty"`
+	// Commands is a list of command names or glob patterns, e.g: system-reboot, cert-*
+	// all the destructive commands if empty.
+	Commands []string `yaml:"commands,omitempty"`
+	// Selector is a target selector, all the targets if empty.
+	Selector string `yaml:"selector,omitempty"`
+	// Deny forbids the commands on the selected targets.
+	Deny bool `yaml:"deny,omitempty"`
+	// ChangeWindows allows the commands on the selected targets
+	// only within one of these windows.
+	ChangeWindows []*ChangeWindow `yaml:"change-windows,omitempty"`
+
+	selector Selector
+}
+
+// ChangeWindow is a recurring time window, e.g: saturday and sunday, from 22:00 to 06:00.
+// A window ending before its start spans midnight.
+type ChangeWindow struct {
+	// Days are the days the window starts on, e.g: sat, sunday.
+	// Every day if empty.
+	Days  []string `yaml:"days,omitempty"`
+	Start string   `yaml:"start,omitempty"`
+	End   string   `yaml:"end,omitempty"`
+	// Timezone is an IANA time zone name, defaults to the local time zone.
+	Timezone string `yaml:"timezone,omitempty"`
+
+	days       map[time.Weekday]struct{}
+	start, end time.Duration
+	location   *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// LoadPolicy reads and validates a policy file.
+func LoadPolicy(filename string) (*Policy, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := new(Policy)
+	err = yaml.Unmarshal(b, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %q: %v", filename, err)
+	}
+	err = p.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %q: %v", filename, err)
+	}
+	return p, nil
+}
+
+func (p *Policy) validate() error {
+	if p.MaxTargets < 0 {
+		return fmt.Errorf("max-targets must be positive")
+	}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if !r.Deny && len(r.ChangeWindows) == 0 {
+			return fmt.Errorf("%s: set deny or change-windows", r.Name)
+		}
+		var err error
+		if strings.TrimSpace(r.Selector) != "" {
+			r.selector, err = ParseSelector(r.Selector)
+			if err != nil {
+				return fmt.Errorf("%s: %v", r.Name, err)
+			}
+		}
+		for _, w := range r.ChangeWindows {
+			err = w.parse()
+			if err != nil {
+				return fmt.Errorf("%s: %v", r.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *ChangeWindow) parse() error {
+	w.days = make(map[time.Weekday]struct{}, len(w.Days))
+	for _, d := range w.Days {
+		k := strings.ToLower(d)
+		if len(k) > 3 {
+			k = k[:3]
+		}
+		wd, ok := weekdays[k]
+		if !ok {
+			return fmt.Errorf("unknown change window day %q", d)
+		}
+		w.days[wd] = struct{}{}
+	}
+	var err error
+	w.start, err = parseTimeOfDay(w.Start)
+	if err != nil {
+		return err
+	}
+	w.end, err = parseTimeOfDay(w.End)
+	if err != nil {
+		return err
+	}
+	w.location = time.Local
+	if w.Timezone != "" {
+		w.location, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid change window time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains returns true if t is within the window.
+func (w *ChangeWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	startsOn := func(d time.Weekday) bool {
+		if len(w.days) == 0 {
+			return true
+		}
+		_, ok := w.days[d]
+		return ok
+	}
+	if w.start < w.end {
+		return startsOn(t.Weekday()) && tod >= w.start && tod < w.end
+	}
+	// spans midnight
+	if tod >= w.start {
+		return startsOn(t.Weekday())
+	}
+	return tod < w.end && startsOn((t.Weekday()+6)%7)
+}
+
+func (r *PolicyRule) appliesTo(command string) bool {
+	if len(r.Commands) == 0 {
+		return true
+	}
+	for _, c := range r.Commands {
+		if ok, _ := path.Match(c, command); ok || c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPolicy returns the policy violations of a destructive command run
+// against targets at time now, one per target and rule.
+func (c *Config) CheckPolicy(p *Policy, command string, targets map[string]*TargetConfig, now time.Time) ([]string, error) {
+	groups, err := c.targetGroups(targets)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(targets))
+	for n := range targets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	violations := make([]string, 0)
+	for _, r := range p.Rules {
+		if !r.appliesTo(command) {
+			continue
+		}
+		for _, n := range names {
+			if !r.selector.Matches(targets[n], groups[n]) {
+				continue
+			}
+			if r.Deny {
+				violations = append(violations, fmt.Sprintf("%q: %s is denied by %s", n, command, r.Name))
+				continue
+			}
+			if !inChangeWindow(r.ChangeWindows, now) {
+				violations = append(violations, fmt.Sprintf("%q: %s is outside the change windows of %s", n, command, r.Name))
+			}
+		}
+	}
+	return violations, nil
+}
+
+func inChangeWindow(ws []*ChangeWindow, t time.Time) bool {
+	for _, w := range ws {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
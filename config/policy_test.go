@@ -0,0 +1,107 @@
+package config
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// yamlTargets returns the targets of the YAML config file content cfg.
+func yamlTargets(t *testing.T, cfg string) map[string]*TargetConfig {
+	t.Helper()
+	c := New()
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	c.SetLogger(log.NewEntry(logger))
+	c.FileConfig.SetConfigType("yaml")
+	if err := c.FileConfig.ReadConfig(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := c.getTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return targets
+}
+
+func TestChangeWindowContains(t *testing.T) {
+	w := &ChangeWindow{Days: []string{"sat", "Sunday"}, Start: "22:00", End: "06:00", Timezone: "UTC"}
+	if err := w.parse(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		t    string
+		want bool
+	}{
+		{name: "saturday_night", t: "2026-10-17T23:30:00Z", want: true},
+		{name: "sunday_early_morning", t: "2026-10-18T05:59:00Z", want: true},
+		{name: "monday_early_morning", t: "2026-10-19T01:00:00Z", want: true},
+		{name: "saturday_early_morning", t: "2026-10-17T01:00:00Z", want: false},
+		{name: "saturday_afternoon", t: "2026-10-17T15:00:00Z", want: false},
+		{name: "friday_night", t: "2026-10-16T23:00:00Z", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := time.Parse(time.RFC3339, tt.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.contains(ts); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", ts.Weekday(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	p := &Policy{
+		Rules: []*PolicyRule{
+			{Selector: "protected=true", Deny: true},
+			{
+				Commands:      []string{"system-*"},
+				Selector:      "role=spine",
+				ChangeWindows: []*ChangeWindow{{Start: "22:00", End: "23:00", Timezone: "UTC"}},
+			},
+		},
+	}
+	if err := p.validate(); err != nil {
+		t.Fatal(err)
+	}
+	targets := yamlTargets(t, `
+targets:
+  r1:57400:
+    tags:
+      protected: true
+  r2:57400:
+    tags:
+      role: spine
+  r3:57400:
+    tags:
+      role: leaf
+      protected: false
+`)
+	c := New()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		command string
+		want    int
+	}{
+		{command: "system-reboot", want: 2},
+		{command: "file-remove", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := c.CheckPolicy(p, tt.command, targets, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("CheckPolicy() = %v, want %d violation(s)", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ProxyURL returns the parsed proxy URL used to reach addr,
+// nil if the target has no proxy or addr matches the target no-proxy list.
+func (tc *TargetConfig) ProxyURL(addr string) (*url.URL, error) {
+	if tc.Proxy == nil || *tc.Proxy == "" {
+		return nil, nil
+	}
+	u, err := parseProxyURL(*tc.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if matchNoProxy(tc.NoProxy, host) {
+		return nil, nil
+	}
+	return u, nil
+}
+
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %v", err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected socks5, http or https", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", u.Redacted())
+	}
+	return u, nil
+}
+
+// matchNoProxy checks if host matches one of the no-proxy entries.
+// An entry is a prefix (10.0.0.0/8), an IP address or a domain name,
+// a domain name also matches its sub domains.
+func matchNoProxy(noProxy []string, host string) bool {
+	ip := net.ParseIP(host)
+	for _, np := range noProxy {
+		np = strings.TrimSpace(np)
+		if np == "" {
+			continue
+		}
+		if np == "*" {
+			return true
+		}
+		if _, ipNet, err := net.ParseCIDR(np); err == nil {
+			if ip != nil && ipNet.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if npIP := net.ParseIP(np); npIP != nil {
+			if ip != nil && npIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if ip != nil {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimPrefix(np, "."))
+		host := strings.ToLower(host)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateProxy checks the target proxy settings.
+func (tc *TargetConfig) validateProxy() error {
+	if tc.Proxy == nil || *tc.Proxy == "" {
+		return nil
+	}
+	_, err := parseProxyURL(*tc.Proxy)
+	if err != nil {
+		return fmt.Errorf("target %q: %v", tc.Name, err)
+	}
+	return nil
+}
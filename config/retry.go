@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy defines how failed RPCs towards a target are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int `json:"max-attempts,omitempty" mapstructure:"max-attempts,omitempty"`
+	// Backoff is the wait time before the first retry,
+	// it doubles with each subsequent attempt.
+	// A policy setting it to 0 retries without waiting.
+	Backoff    *time.Duration `json:"backoff,omitempty" mapstructure:"backoff,omitempty"`
+	MaxBackoff time.Duration  `json:"max-backoff,omitempty" mapstructure:"max-backoff,omitempty"`
+	// Jitter is the fraction of the backoff randomly added or removed,
+	// a policy setting it to 0 disables the jitter.
+	Jitter *float64 `json:"jitter,omitempty" mapstructure:"jitter,omitempty"`
+	// Codes is the list of retryable gRPC status codes.
+	Codes []string `json:"codes,omitempty" mapstructure:"codes,omitempty"`
+	// NonIdempotent allows retrying RPCs that change the target state
+	// such as File Put, System Reboot or System SetPackage.
+	NonIdempotent *bool `json:"non-idempotent,omitempty" mapstructure:"non-idempotent,omitempty"`
+}
+
+// RetryableCodes returns the set of retryable gRPC codes.
+func (rp *RetryPolicy) RetryableCodes() (map[codes.Code]struct{}, error) {
+	rc := make(map[codes.Code]struct{}, len(rp.Codes))
+	for _, c := range rp.Codes {
+		code, ok := parseCode(c)
+		if !ok {
+			return nil, fmt.Errorf("unknown gRPC code %q", c)
+		}
+		rc[code] = struct{}{}
+	}
+	return rc, nil
+}
+
+// BackoffFor returns the wait time before the given retry attempt, without jitter.
+func (rp *RetryPolicy) BackoffFor(attempt int) time.Duration {
+	d := defaultRetryBackoff
+	if rp.Backoff != nil {
+		d = *rp.Backoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	max := rp.MaxBackoff
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// parseCode parses a gRPC code name, e.g: "Unavailable", "UNAVAILABLE" or "resource-exhausted"
+func parseCode(s string) (codes.Code, bool) {
+	norm := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	s = norm(s)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if norm(c.String()) == s {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// SetCommand records the command being run, it is used to
+// lookup command specific settings in the config file.
+func (c *Config) SetCommand(cmd *cobra.Command) {
+	c.command = commandFullName(cmd)
+}
+
+// Command returns the full name of the command being run, e.g: "system-reboot"
+func (c *Config) Command() string {
+	return c.command
+}
+
+// retryPolicy builds the retry policy of the command being run:
+// the global flags values overwritten by the command policy
+// found under `retry-commands` in the config file.
+func (c *Config) retryPolicy() (*RetryPolicy, error) {
+	rp := &RetryPolicy{
+		MaxAttempts:   c.RetryMaxAttempts,
+		Backoff:       pointer.ToDuration(c.RetryBackoff),
+		MaxBackoff:    c.RetryMaxBackoff,
+		Jitter:        pointer.ToFloat64(c.RetryJitter),
+		Codes:         c.RetryCodes,
+		NonIdempotent: &c.RetryNonIdempotent,
+	}
+	if c.command == "" {
+		return rp, nil
+	}
+	cmdPolicy, ok := c.FileConfig.Get("retry-commands/" + c.command).(map[string]interface{})
+	if !ok {
+		return rp, nil
+	}
+	crp := new(RetryPolicy)
+	err := decodeRetryPolicy(cmdPolicy, crp)
+	if err != nil {
+		return nil, fmt.Errorf("command %q retry policy: %v", c.command, err)
+	}
+	crp.merge(rp)
+	return crp, nil
+}
+
+// merge sets the unset fields of rp from the values in def.
+func (rp *RetryPolicy) merge(def *RetryPolicy) {
+	if def == nil {
+		return
+	}
+	if rp.MaxAttempts <= 0 {
+		rp.MaxAttempts = def.MaxAttempts
+	}
+	if rp.Backoff == nil {
+		rp.Backoff = def.Backoff
+	}
+	if rp.MaxBackoff <= 0 {
+		rp.MaxBackoff = def.MaxBackoff
+	}
+	if rp.Jitter == nil {
+		rp.Jitter = def.Jitter
+	}
+	if len(rp.Codes) == 0 {
+		rp.Codes = def.Codes
+	}
+	if rp.NonIdempotent == nil {
+		rp.NonIdempotent = def.NonIdempotent
+	}
+}
+
+func decodeRetryPolicy(in interface{}, rp *RetryPolicy) error {
+	decoder, err := mapstructure.NewDecoder(
+		&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           rp,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(in)
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRetryableCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		codes   []string
+		want    []codes.Code
+		wantErr string
+	}{
+		{name: "none", codes: nil},
+		{name: "camel_case", codes: []string{"Unavailable", "DeadlineExceeded"}, want: []codes.Code{codes.Unavailable, codes.DeadlineExceeded}},
+		{name: "upper_snake_case", codes: []string{"RESOURCE_EXHAUSTED"}, want: []codes.Code{codes.ResourceExhausted}},
+		{name: "kebab_case", codes: []string{"resource-exhausted", "aborted"}, want: []codes.Code{codes.ResourceExhausted, codes.Aborted}},
+		{name: "unknown_code", codes: []string{"Unavailable", "Flaky"}, wantErr: `unknown gRPC code "Flaky"`},
+		{name: "numeric_code", codes: []string{"14"}, wantErr: `unknown gRPC code "14"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, err := (&RetryPolicy{Codes: tt.codes}).RetryableCodes()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RetryableCodes() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rc) != len(tt.want) {
+				t.Errorf("RetryableCodes() = %v, want %v", rc, tt.want)
+			}
+			for _, c := range tt.want {
+				if _, ok := rc[c]; !ok {
+					t.Errorf("RetryableCodes() = %v, missing %s", rc, c)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{name: "defaults_first_retry", policy: &RetryPolicy{}, attempt: 1, want: defaultRetryBackoff},
+		{name: "defaults_capped", policy: &RetryPolicy{}, attempt: 10, want: defaultRetryMaxBackoff},
+		{name: "first_retry", policy: &RetryPolicy{Backoff: pointer.ToDuration(100 * time.Millisecond), MaxBackoff: time.Second}, attempt: 1, want: 100 * time.Millisecond},
+		{name: "doubles", policy: &RetryPolicy{Backoff: pointer.ToDuration(100 * time.Millisecond), MaxBackoff: time.Second}, attempt: 3, want: 400 * time.Millisecond},
+		{name: "capped", policy: &RetryPolicy{Backoff: pointer.ToDuration(100 * time.Millisecond), MaxBackoff: time.Second}, attempt: 5, want: time.Second},
+		{name: "no_wait", policy: &RetryPolicy{Backoff: pointer.ToDuration(0), MaxBackoff: time.Second}, attempt: 3, want: 0},
+		{name: "backoff_above_max", policy: &RetryPolicy{Backoff: pointer.ToDuration(2 * time.Second), MaxBackoff: time.Second}, attempt: 1, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.BackoffFor(tt.attempt); got != tt.want {
+				t.Errorf("BackoffFor(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyMerge(t *testing.T) {
+	def := &RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     pointer.ToDuration(time.Second),
+		MaxBackoff:  10 * time.Second,
+		Jitter:      pointer.ToFloat64(0.5),
+		Codes:       []string{"Unavailable"},
+	}
+	tests := []struct {
+		name        string
+		in          map[string]interface{}
+		wantBackoff time.Duration
+		wantJitter  float64
+	}{
+		{name: "unset", in: map[string]interface{}{"max-attempts": 5}, wantBackoff: time.Second, wantJitter: 0.5},
+		{name: "set", in: map[string]interface{}{"backoff": "2s", "jitter": 0.1}, wantBackoff: 2 * time.Second, wantJitter: 0.1},
+		{name: "zero", in: map[string]interface{}{"backoff": "0s", "jitter": 0}, wantBackoff: 0, wantJitter: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := new(RetryPolicy)
+			if err := decodeRetryPolicy(tt.in, rp); err != nil {
+				t.Fatal(err)
+			}
+			rp.merge(def)
+			if got := pointer.GetDuration(rp.Backoff); rp.Backoff == nil || got != tt.wantBackoff {
+				t.Errorf("backoff = %v, want %v", got, tt.wantBackoff)
+			}
+			if got := pointer.GetFloat64(rp.Jitter); rp.Jitter == nil || got != tt.wantJitter {
+				t.Errorf("jitter = %v, want %v", got, tt.wantJitter)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/adrg/xdg"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	secretsPassphraseEnv = "GNOIC_SECRETS_PASSPHRASE"
+	secretsFileName      = "secrets.enc"
+)
+
+// secret reference prefixes
+const (
+	secretRefEnv    = "env:"
+	secretRefFile   = "file:"
+	secretRefExec   = "exec:"
+	secretRefSecret = "secret:"
+)
+
+// secretPlaceholderRegex matches secret references embedded in a string, e.g: ${env:VAR}
+var secretPlaceholderRegex = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// IsSecretRef returns true if s is a secret reference.
+func IsSecretRef(s string) bool {
+	for _, p := range []string{secretRefEnv, secretRefFile, secretRefExec, secretRefSecret} {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecret returns the secret value referenced by ref:
+//   - env:VAR, the value of the environment variable VAR
+//   - file:/path, the content of the file, trailing new lines removed
+//   - exec:cmd args, the stdout of the command, trailing new lines removed
+//   - secret:name, the secret stored under name in the encrypted secrets file
+//
+// Any other value is returned as is.
+// Resolved values are cached so that a command runs only once per gnoic run.
+func (c *Config) ResolveSecret(ref string) (string, error) {
+	if !IsSecretRef(ref) {
+		return ref, nil
+	}
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	if v, ok := c.secretsCache[ref]; ok {
+		return v, nil
+	}
+	v, err := c.resolveSecret(ref)
+	if err != nil {
+		return "", err
+	}
+	if c.secretsCache == nil {
+		c.secretsCache = make(map[string]string)
+	}
+	c.secretsCache[ref] = v
+	return v, nil
+}
+
+func (c *Config) resolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, secretRefEnv):
+		name := strings.TrimPrefix(ref, secretRefEnv)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret env variable %q is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(ref, secretRefFile):
+		b, err := os.ReadFile(strings.TrimPrefix(ref, secretRefFile))
+		if err != nil {
+			return "", fmt.Errorf("failed reading secret file: %v", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case strings.HasPrefix(ref, secretRefExec):
+		args := strings.Fields(strings.TrimPrefix(ref, secretRefExec))
+		if len(args) == 0 {
+			return "", errors.New("missing secret command")
+		}
+		stdout := new(bytes.Buffer)
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdout = stdout
+		cmd.Stderr = os.Stderr
+		cmd.Stdin = os.Stdin
+		err := cmd.Run()
+		if err != nil {
+			return "", fmt.Errorf("secret command %q failed: %v", args[0], err)
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), nil
+	case strings.HasPrefix(ref, secretRefSecret):
+		name := strings.TrimPrefix(ref, secretRefSecret)
+		store, err := c.SecretsStore()
+		if err != nil {
+			return "", err
+		}
+		secrets, err := store.Load()
+		if err != nil {
+			return "", err
+		}
+		v, ok := secrets[name]
+		if !ok {
+			return "", fmt.Errorf("secret %q not found in %q", name, store.path)
+		}
+		return v, nil
+	}
+	return ref, nil
+}
+
+// ResolveSecretPlaceholders replaces the ${ref} placeholders found in s
+// with the referenced secret values, e.g: scp://admin:${env:SCP_PASSWORD}@server:/file
+func (c *Config) ResolveSecretPlaceholders(s string) (string, error) {
+	var rErr error
+	result := secretPlaceholderRegex.ReplaceAllStringFunc(s, func(m string) string {
+		ref := secretPlaceholderRegex.FindStringSubmatch(m)[1]
+		if !IsSecretRef(ref) {
+			return m
+		}
+		v, err := c.ResolveSecret(ref)
+		if err != nil && rErr == nil {
+			rErr = err
+		}
+		return v
+	})
+	return result, rErr
+}
+
+// resolveTargetSecrets resolves the secret references used in the target credentials.
+func (c *Config) resolveTargetSecrets(tc *TargetConfig) error {
+	for _, field := range []**string{&tc.Username, &tc.Password, &tc.Token} {
+		if *field == nil || !IsSecretRef(**field) {
+			continue
+		}
+		v, err := c.ResolveSecret(**field)
+		if err != nil {
+			return fmt.Errorf("target %q: %v", tc.Name, err)
+		}
+		// do not overwrite the value of a pointer shared with the global flags
+		*field = &v
+	}
+	if tc.Proxy != nil && secretPlaceholderRegex.MatchString(*tc.Proxy) {
+		v, err := c.ResolveSecretPlaceholders(*tc.Proxy)
+		if err != nil {
+			return fmt.Errorf("target %q: %v", tc.Name, err)
+		}
+		tc.Proxy = &v
+	}
+	if tc.OAuth2 != nil && IsSecretRef(tc.OAuth2.ClientSecret) {
+		v, err := c.ResolveSecret(tc.OAuth2.ClientSecret)
+		if err != nil {
+			return fmt.Errorf("target %q: %v", tc.Name, err)
+		}
+		oc := *tc.OAuth2
+		oc.ClientSecret = v
+		tc.OAuth2 = &oc
+	}
+	return nil
+}
+
+// askPassword prompts for the global password once, without echo.
+func (c *Config) askPassword() error {
+	if !c.AskPassword || c.passwordAsked {
+		return nil
+	}
+	p, err := ReadSecret("Password: ")
+	if err != nil {
+		return err
+	}
+	c.Password = p
+	c.passwordAsked = true
+	return nil
+}
+
+// ReadSecret prompts for a secret on the terminal without echoing it.
+func ReadSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("cannot prompt for a secret: stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// SecretsStore is a local file holding named secrets,
+// encrypted with a key derived from a passphrase.
+type SecretsStore struct {
+	path       string
+	passphrase string
+}
+
+type secretsFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// SecretsStore returns the store located at the --secrets-file path.
+// The passphrase is read from the GNOIC_SECRETS_PASSPHRASE env variable
+// or prompted for.
+func (c *Config) SecretsStore() (*SecretsStore, error) {
+	if c.secretsStore != nil {
+		return c.secretsStore, nil
+	}
+	path := c.SecretsFile
+	if path == "" {
+		path = filepath.Join(xdg.ConfigHome, "gnoic", secretsFileName)
+	}
+	passphrase, ok := os.LookupEnv(secretsPassphraseEnv)
+	if !ok {
+		var err error
+		passphrase, err = ReadSecret(fmt.Sprintf("Passphrase for %s: ", path))
+		if err != nil {
+			return nil, err
+		}
+	}
+	c.secretsStore = &SecretsStore{path: path, passphrase: passphrase}
+	return c.secretsStore, nil
+}
+
+// Path returns the secrets file path.
+func (s *SecretsStore) Path() string { return s.path }
+
+// Load decrypts and returns the stored secrets.
+// A missing file is an empty store.
+func (s *SecretsStore) Load() (map[string]string, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	sf := new(secretsFile)
+	err = json.Unmarshal(b, sf)
+	if err != nil {
+		return nil, fmt.Errorf("malformed secrets file %q: %v", s.path, err)
+	}
+	gcm, err := s.cipher(sf.Salt)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, sf.Nonce, sf.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets file %q: wrong passphrase?", s.path)
+	}
+	secrets := make(map[string]string)
+	err = json.Unmarshal(data, &secrets)
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// Save encrypts and writes the secrets to the store file.
+func (s *SecretsStore) Save(secrets map[string]string) error {
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	sf := &secretsFile{Salt: make([]byte, 16)}
+	if _, err = rand.Read(sf.Salt); err != nil {
+		return err
+	}
+	gcm, err := s.cipher(sf.Salt)
+	if err != nil {
+		return err
+	}
+	sf.Nonce = make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(sf.Nonce); err != nil {
+		return err
+	}
+	sf.Data = gcm.Seal(nil, sf.Nonce, data, nil)
+	b, err := json.Marshal(sf)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0600)
+}
+
+// Names returns the sorted names of the stored secrets.
+func (s *SecretsStore) Names() ([]string, error) {
+	secrets, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for n := range secrets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *SecretsStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.passphrase == "" {
+		return nil, errors.New("empty secrets passphrase")
+	}
+	key, err := scrypt.Key([]byte(s.passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "pass")
+	err := os.WriteFile(secretFile, []byte("s3cr3t\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNOIC_TEST_SECRET", "from:env@")
+	t.Setenv(secretsPassphraseEnv, "passphrase")
+
+	c := New()
+	c.SecretsFile = filepath.Join(dir, "secrets.enc")
+	store, err := c.SecretsStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save(map[string]string{"admin": "stored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", ref: "admin", want: "admin"},
+		{name: "env", ref: "env:GNOIC_TEST_SECRET", want: "from:env@"},
+		{name: "env_unset", ref: "env:GNOIC_TEST_UNSET", wantErr: true},
+		{name: "file", ref: "file:" + secretFile, want: "s3cr3t"},
+		{name: "exec", ref: "exec:echo from exec", want: "from exec"},
+		{name: "secret", ref: "secret:admin", want: "stored"},
+		{name: "secret_missing", ref: "secret:other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.ResolveSecret(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSecret(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveSecret(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+	got, err := c.ResolveSecretPlaceholders("admin:${env:GNOIC_TEST_SECRET}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "admin:from:env@" {
+		t.Errorf("ResolveSecretPlaceholders() = %q", got)
+	}
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Selector filters targets based on their name, address, tags and groups.
+// It is a comma separated list of requirements, all of which must match:
+//   - key=value, key==value: the tag value matches
+//   - key!=value: the tag is not set or its value does not match
+//   - key in (v1,v2): the tag value matches one of the values
+//   - key notin (v1,v2): the tag is not set or its value matches none of the values
+//   - key: the tag is set
+//   - !key: the tag is not set
+//
+// A tag set to "false" (case insensitive) is considered not set by key and !key.
+// Values can be glob patterns, e.g: site=par*
+// The keys "name" and "address" match the target name and address,
+// the key "group" matches the groups the target belongs to.
+type Selector []requirement
+
+type operator int
+
+const (
+	opEquals operator = iota
+	opNotEquals
+	opIn
+	opNotIn
+	opExists
+	opNotExists
+)
+
+type requirement struct {
+	key    string
+	op     operator
+	values []string
+}
+
+// ParseSelector parses a target selection expression.
+func ParseSelector(s string) (Selector, error) {
+	terms, err := splitTerms(s)
+	if err != nil {
+		return nil, err
+	}
+	sel := make(Selector, 0, len(terms))
+	for _, term := range terms {
+		r, err := parseRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %v", term, err)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitTerms splits a selector on the commas that are not within parenthesis.
+func splitTerms(s string) ([]string, error) {
+	terms := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parenthesis in selector %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parenthesis in selector %q", s)
+	}
+	terms = append(terms, s[start:])
+	result := make([]string, 0, len(terms))
+	for _, t := range terms {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func parseRequirement(term string) (requirement, error) {
+	if strings.HasPrefix(term, "!") && !strings.Contains(term, "=") {
+		key := strings.TrimSpace(term[1:])
+		if key == "" {
+			return requirement{}, errors.New("missing key")
+		}
+		return requirement{key: key, op: opNotExists}, nil
+	}
+	for _, op := range []struct {
+		sep string
+		op  operator
+	}{
+		{sep: "!=", op: opNotEquals},
+		{sep: "==", op: opEquals},
+		{sep: "=", op: opEquals},
+	} {
+		if k, v, ok := strings.Cut(term, op.sep); ok {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				return requirement{}, errors.New("missing key")
+			}
+			return requirement{key: k, op: op.op, values: []string{strings.TrimSpace(v)}}, nil
+		}
+	}
+	fields := strings.Fields(term)
+	if len(fields) == 1 {
+		return requirement{key: fields[0], op: opExists}, nil
+	}
+	if len(fields) < 3 {
+		return requirement{}, errors.New("unknown operator")
+	}
+	var op operator
+	switch fields[1] {
+	case "in":
+		op = opIn
+	case "notin":
+		op = opNotIn
+	default:
+		return requirement{}, fmt.Errorf("unknown operator %q", fields[1])
+	}
+	list := strings.TrimSpace(strings.Join(fields[2:], " "))
+	if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+		return requirement{}, errors.New("values list must be within parenthesis")
+	}
+	values := make([]string, 0)
+	for _, v := range strings.Split(list[1:len(list)-1], ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return requirement{key: fields[0], op: op, values: values}, nil
+}
+
+// Matches returns true if the target config satisfies all the selector requirements.
+// groups is the list of groups the target belongs to.
+func (s Selector) Matches(tc *TargetConfig, groups []string) bool {
+	for _, r := range s {
+		if !r.matches(tc, groups) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r requirement) matches(tc *TargetConfig, groups []string) bool {
+	values, ok := r.targetValues(tc, groups)
+	switch r.op {
+	case opExists:
+		return isSet(values, ok)
+	case opNotExists:
+		return !isSet(values, ok)
+	case opEquals, opIn:
+		return ok && anyMatch(r.values, values)
+	case opNotEquals, opNotIn:
+		return !ok || !anyMatch(r.values, values)
+	}
+	return false
+}
+
+func (r requirement) targetValues(tc *TargetConfig, groups []string) ([]string, bool) {
+	switch r.key {
+	case "name":
+		return []string{tc.Name}, true
+	case "address":
+		return []string{tc.Address}, true
+	case "group":
+		return groups, len(groups) > 0
+	}
+	v, ok := tc.Tags[r.key]
+	return []string{v}, ok
+}
+
+// isSet returns true if the key is present and not set to "false".
+func isSet(values []string, ok bool) bool {
+	return ok && !(len(values) == 1 && strings.EqualFold(values[0], "false"))
+}
+
+func anyMatch(patterns, values []string) bool {
+	for _, p := range patterns {
+		for _, v := range values {
+			if ok, _ := path.Match(p, v); ok || p == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// targetGroups returns the groups defined in the config file, indexed by target name.
+// A group is either a list of target names or a selector.
+func (c *Config) targetGroups(targets map[string]*TargetConfig) (map[string][]string, error) {
+	membership := make(map[string][]string)
+	groups, ok := c.FileConfig.Get("groups").(map[string]interface{})
+	if !ok {
+		return membership, nil
+	}
+	for gn, g := range groups {
+		switch g := g.(type) {
+		case string:
+			sel, err := ParseSelector(g)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: %v", gn, err)
+			}
+			for n, tc := range targets {
+				if sel.Matches(tc, nil) {
+					membership[n] = append(membership[n], gn)
+				}
+			}
+		case []interface{}:
+			members := make(map[string]struct{}, len(g))
+			for _, m := range g {
+				members[fmt.Sprintf("%v", m)] = struct{}{}
+			}
+			for n, tc := range targets {
+				_, okName := members[tc.Name]
+				_, okAddr := members[tc.Address]
+				if okName || okAddr {
+					membership[n] = append(membership[n], gn)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unexpected group %q format, got a %T", gn, g)
+		}
+	}
+	for n := range membership {
+		sort.Strings(membership[n])
+	}
+	return membership, nil
+}
+
+// Groups returns the sorted names of the groups defined in the config file.
+func (c *Config) Groups() []string {
+	groups, ok := c.FileConfig.Get("groups").(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(groups))
+	for gn := range groups {
+		names = append(names, gn)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectTargets filters the targets using the global --select expression.
+func (c *Config) selectTargets(targets map[string]*TargetConfig) (map[string]*TargetConfig, error) {
+	if strings.TrimSpace(c.Select) == "" {
+		return targets, nil
+	}
+	sel, err := ParseSelector(c.Select)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := c.targetGroups(targets)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]*TargetConfig)
+	for n, tc := range targets {
+		if sel.Matches(tc, groups[n]) {
+			selected[n] = tc
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no targets match selector %q", c.Select)
+	}
+	c.logger.Debugf("selected %d/%d target(s) using %q", len(selected), len(targets), c.Select)
+	return selected, nil
+}
+
+// boolTagsHookFunc decodes the boolean tag values as "true" or "false",
+// e.g: a YAML "protected: true" tag, the weak decoding would turn them into "1" or "0".
+func boolTagsHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.Map || t != reflect.TypeOf(map[string]string{}) {
+			return data, nil
+		}
+		v := reflect.ValueOf(data)
+		tags := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			tv := iter.Value().Interface()
+			if b, ok := tv.(bool); ok {
+				tv = strconv.FormatBool(b)
+			}
+			tags[fmt.Sprint(iter.Key().Interface())] = tv
+		}
+		return tags, nil
+	}
+}
+
+// stringToTagsHookFunc decodes tags given as a "key=value,key2=value2"
+// or "key=value;key2=value2" string, as found in CSV inventories.
+func stringToTagsHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(map[string]string{}) {
+			return data, nil
+		}
+		tags := make(map[string]string)
+		for _, kv := range strings.FieldsFunc(data.(string), func(r rune) bool { return r == ',' || r == ';' }) {
+			kv = strings.TrimSpace(kv)
+			if kv == "" {
+				continue
+			}
+			k, v, _ := strings.Cut(kv, "=")
+			tags[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		return tags, nil
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	tc := &TargetConfig{
+		Name:    "spine1.par1",
+		Address: "10.0.0.1:57400",
+		Tags: map[string]string{
+			"role":        "spine",
+			"site":        "par1",
+			"maintenance": "false",
+			"drained":     "FALSE",
+			"upgraded":    "true",
+		},
+	}
+	tests := []struct {
+		name     string
+		selector string
+		groups   []string
+		want     bool
+		wantErr  bool
+	}{
+		{name: "equals", selector: "role=spine", want: true},
+		{name: "double_equals", selector: "role==spine", want: true},
+		{name: "not_equals", selector: "role!=spine", want: false},
+		{name: "in", selector: "role=spine,site in (par1,lon2)", want: true},
+		{name: "notin", selector: "site notin (par1, lon2)", want: false},
+		{name: "not_exists_false_value", selector: "role=spine,site in (par1,lon2),!maintenance", want: true},
+		{name: "exists_false_value", selector: "maintenance", want: false},
+		{name: "exists_false_value_case", selector: "drained", want: false},
+		{name: "not_exists_false_value_case", selector: "!drained", want: true},
+		{name: "exists", selector: "upgraded", want: true},
+		{name: "not_exists", selector: "!upgraded", want: false},
+		{name: "exists_missing_tag", selector: "rack", want: false},
+		{name: "not_exists_missing_tag", selector: "!rack", want: true},
+		{name: "equals_false_value", selector: "maintenance=false", want: true},
+		{name: "missing_tag", selector: "rack=r1", want: false},
+		{name: "missing_tag_not_equals", selector: "rack!=r1", want: true},
+		{name: "glob", selector: "name=spine*", want: true},
+		{name: "group", selector: "group=core", groups: []string{"core", "dc1"}, want: true},
+		{name: "no_group", selector: "group=core", want: false},
+		{name: "unbalanced", selector: "site in (par1", wantErr: true},
+		{name: "bad_operator", selector: "site like par1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := ParseSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := sel.Matches(tc, tt.groups); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorMatchesYAMLTags(t *testing.T) {
+	targets := yamlTargets(t, `
+targets:
+  r1:57400:
+    tags:
+      maintenance: false
+      upgraded: true
+`)
+	tc := targets["r1:57400"]
+	if tc == nil {
+		t.Fatalf("target r1:57400 not loaded: %v", targets)
+	}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "maintenance", want: false},
+		{selector: "!maintenance", want: true},
+		{selector: "maintenance=true", want: false},
+		{selector: "maintenance=false", want: true},
+		{selector: "upgraded", want: true},
+		{selector: "!upgraded", want: false},
+		{selector: "upgraded=true", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sel.Matches(tc, nil); got != tt.want {
+				t.Errorf("Matches() = %v, want %v, tags %v", got, tt.want, tc.Tags)
+			}
+		})
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/mitchellh/mapstructure"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -24,6 +23,8 @@ type TargetConfig struct {
 	SkipVerify    *bool         `json:"skip-verify,omitempty" mapstructure:"skip-verify,omitempty"`
 	Username      *string       `json:"username,omitempty" mapstructure:"username,omitempty"`
 	Password      *string       `json:"password,omitempty" mapstructure:"password,omitempty"`
+	Token         *string       `json:"token,omitempty" mapstructure:"token,omitempty"`
+	OAuth2        *OAuth2Config `json:"oauth2,omitempty" mapstructure:"oauth2,omitempty"`
 	Timeout       time.Duration `json:"timeout,omitempty" mapstructure:"timeout,omitempty"`
 	TLSCert       *string       `json:"tls-cert,omitempty" mapstructure:"tls-cert,omitempty"`
 	TLSKey        *string       `json:"tls-key,omitempty" mapstructure:"tls-key,omitempty"`
@@ -33,6 +34,18 @@ type TargetConfig struct {
 	TLSVersion    string        `json:"tls-version,omitempty" mapstructure:"tls-version,omitempty"`
 	Gzip          *bool         `json:"gzip,omitempty" mapstructure:"gzip,omitempty"`
 	TCPKeepalive  time.Duration `json:"tcp-keepalive,omitempty" mapstructure:"tcp-keepalive,omitempty"`
+	Retry         *RetryPolicy  `json:"retry,omitempty" mapstructure:"retry,omitempty"`
+	// Proxy is a socks5:// or http(s):// proxy URL used to reach the target
+	Proxy   *string  `json:"proxy,omitempty" mapstructure:"proxy,omitempty"`
+	NoProxy []string `json:"no-proxy,omitempty" mapstructure:"no-proxy,omitempty"`
+	// SSHProxy is the chain of SSH jump hosts used to reach the target, in [user@]host[:port] format
+	SSHProxy      []string `json:"ssh-proxy,omitempty" mapstructure:"ssh-proxy,omitempty"`
+	SSHKeyFile    *string  `json:"ssh-key-file,omitempty" mapstructure:"ssh-key-file,omitempty"`
+	SSHKnownHosts *string  `json:"ssh-known-hosts,omitempty" mapstructure:"ssh-known-hosts,omitempty"`
+	// Metadata are extra gRPC metadata sent with each RPC, e.g: x-tenant=blue
+	Metadata map[string]string `json:"metadata,omitempty" mapstructure:"metadata,omitempty"`
+	// Tags are free form key/value labels used to select targets, e.g: role=spine
+	Tags map[string]string `json:"tags,omitempty" mapstructure:"tags,omitempty"`
 	//
 	CommonName string `json:"common-name,omitempty"`
 	ResolvedIP string `json:"resolved-ip,omitempty"`
@@ -40,22 +53,45 @@ type TargetConfig struct {
 	tlsConfig *tls.Config
 }
 
+// GetTargets returns the targets configurations built from the --address flag,
+// the inventory or the config file, filtered by the --select expression.
 func (c *Config) GetTargets() (map[string]*TargetConfig, error) {
+	targetsConfigs, err := c.getTargets()
+	if err != nil {
+		return nil, err
+	}
+	return c.selectTargets(targetsConfigs)
+}
+
+func (c *Config) getTargets() (map[string]*TargetConfig, error) {
 	targetsConfigs := make(map[string]*TargetConfig)
+	retryPolicy, err := c.retryPolicy()
+	if err != nil {
+		return nil, err
+	}
+	err = c.askPassword()
+	if err != nil {
+		return nil, err
+	}
+	err = validateMetadata(c.Metadata)
+	if err != nil {
+		return nil, err
+	}
 	if len(c.Address) > 0 {
-		var err error
-		for _, addr := range c.Address {
-			tc := new(TargetConfig)
-			err = c.parseAddress(tc, addr)
+		for _, addr := range joinAddressPatterns(c.Address) {
+			tcs, err := c.expandTargetConfig(new(TargetConfig), addr, retryPolicy)
 			if err != nil {
-				return nil, fmt.Errorf("%q failed to parse address: %v", addr, err)
+				return nil, err
+			}
+			for _, tc := range tcs {
+				targetsConfigs[tc.Name] = tc
 			}
-			c.setTargetConfigDefaults(tc)
-			targetsConfigs[tc.Name] = tc
-			c.logger.Debugf("%q target-config: %s", addr, tc)
 		}
 		return targetsConfigs, nil
 	}
+	if c.Inventory != "" {
+		return c.getInventoryTargets(retryPolicy)
+	}
 	targetsMap := c.FileConfig.GetStringMap("targets")
 	if len(targetsMap) == 0 {
 		return nil, errors.New("no targets found")
@@ -64,16 +100,7 @@ func (c *Config) GetTargets() (map[string]*TargetConfig, error) {
 		tc := new(TargetConfig)
 		switch t := t.(type) {
 		case map[string]interface{}:
-			decoder, err := mapstructure.NewDecoder(
-				&mapstructure.DecoderConfig{
-					DecodeHook: mapstructure.StringToTimeDurationHookFunc(),
-					Result:     tc,
-				},
-			)
-			if err != nil {
-				return nil, err
-			}
-			err = decoder.Decode(t)
+			err := decodeTargetConfig(t, tc)
 			if err != nil {
 				return nil, err
 			}
@@ -81,13 +108,13 @@ func (c *Config) GetTargets() (map[string]*TargetConfig, error) {
 		default:
 			return nil, fmt.Errorf("unexpected targets format, got a %T", t)
 		}
-		err := c.parseAddress(tc, addr)
+		tcs, err := c.expandTargetConfig(tc, addr, retryPolicy)
 		if err != nil {
-			return nil, fmt.Errorf("%q failed to parse address: %v", addr, err)
+			return nil, err
+		}
+		for _, tc := range tcs {
+			targetsConfigs[tc.Name] = tc
 		}
-		c.setTargetConfigDefaults(tc)
-		targetsConfigs[tc.Name] = tc
-		c.logger.Debugf("%q target-config: %s", addr, tc)
 	}
 	return targetsConfigs, nil
 }
@@ -130,7 +157,7 @@ func (c *Config) parseAddress(tc *TargetConfig, addr string) error {
 	return nil
 }
 
-func (c *Config) setTargetConfigDefaults(tc *TargetConfig) {
+func (c *Config) setTargetConfigDefaults(tc *TargetConfig, retryPolicy *RetryPolicy) {
 	if tc.Name == "" {
 		tc.Name = tc.Address
 	}
@@ -146,6 +173,44 @@ func (c *Config) setTargetConfigDefaults(tc *TargetConfig) {
 	if tc.Password == nil {
 		tc.Password = &c.Password
 	}
+	if tc.Proxy == nil && c.Proxy != "" {
+		tc.Proxy = &c.Proxy
+	}
+	if tc.NoProxy == nil && len(c.NoProxy) > 0 {
+		tc.NoProxy = c.NoProxy
+	}
+	if tc.SSHProxy == nil && len(c.SSHProxy) > 0 {
+		tc.SSHProxy = c.SSHProxy
+	}
+	if tc.SSHKeyFile == nil {
+		tc.SSHKeyFile = &c.SSHKeyFile
+	}
+	if tc.SSHKnownHosts == nil {
+		tc.SSHKnownHosts = &c.SSHKnownHosts
+	}
+	if tc.Token == nil && c.Token != "" {
+		tc.Token = &c.Token
+	}
+	if tc.OAuth2 == nil && c.OAuth2TokenURL != "" {
+		tc.OAuth2 = &OAuth2Config{
+			TokenURL:     c.OAuth2TokenURL,
+			ClientID:     c.OAuth2ClientID,
+			ClientSecret: c.OAuth2ClientSecret,
+			Scopes:       c.OAuth2Scopes,
+		}
+	}
+	if len(c.Metadata) > 0 {
+		md := make(map[string]string, len(c.Metadata)+len(tc.Metadata))
+		for _, kv := range c.Metadata {
+			k, v, _ := strings.Cut(kv, "=")
+			md[strings.ToLower(strings.TrimSpace(k))] = v
+		}
+		// target metadata take precedence over the flags
+		for k, v := range tc.Metadata {
+			md[strings.ToLower(k)] = v
+		}
+		tc.Metadata = md
+	}
 	if tc.SkipVerify == nil {
 		tc.SkipVerify = &c.SkipVerify
 	}
@@ -174,6 +239,10 @@ func (c *Config) setTargetConfigDefaults(tc *TargetConfig) {
 	if tc.Gzip == nil {
 		tc.Gzip = &c.Gzip
 	}
+	if tc.Retry == nil {
+		tc.Retry = new(RetryPolicy)
+	}
+	tc.Retry.merge(retryPolicy)
 }
 
 func (tc *TargetConfig) DialOpts() ([]grpc.DialOption, error) {
@@ -272,6 +341,15 @@ func (tc *TargetConfig) String() string {
 	return string(b)
 }
 
+// NewTLSConfig returns a copy of the TLS config used to reach the target.
+func (tc *TargetConfig) NewTLSConfig() (*tls.Config, error) {
+	tlsConfig, err := tc.newTLS()
+	if err != nil {
+		return nil, err
+	}
+	return tlsConfig.Clone(), nil
+}
+
 func (tc *TargetConfig) SetTLSConfig(tlsConfig *tls.Config) {
 	tc.tlsConfig = tlsConfig
 }